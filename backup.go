@@ -0,0 +1,207 @@
+package wrapper
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/wlwanpan/minecraft-wrapper/events"
+)
+
+// ErrWorldNotFound is returned when backing up a server directory holding
+// no world.
+var ErrWorldNotFound = errors.New("world not found")
+
+// backupTimeLayout is the time suffix of the backup archives, sorting them
+// oldest first.
+const backupTimeLayout = "20060102-150405"
+
+// worldDimensions are the suffixes of the world directories, the nether
+// and the end being stored apart by Bukkit servers.
+var worldDimensions = []string{"", "_nether", "_the_end"}
+
+// Backup archives the world of the server run in dir to a gzipped tarball
+// in the backup directory, returning the archive path. While online, the
+// server saving is turned off until archived, after flushing the world to
+// the disk. The oldest archives above the count kept are removed.
+func (w *Wrapper) Backup(ctx context.Context, dir string, cfg BackupConfig) (string, error) {
+	level, err := levelName(dir)
+	if err != nil {
+		return "", err
+	}
+	worlds := []string{}
+	for _, dim := range worldDimensions {
+		if fi, err := os.Stat(filepath.Join(dir, level+dim)); err == nil && fi.IsDir() {
+			worlds = append(worlds, level+dim)
+		}
+	}
+	if len(worlds) == 0 {
+		return "", fmt.Errorf("%w: %s", ErrWorldNotFound, filepath.Join(dir, level))
+	}
+
+	if w.State() == WrapperOnline {
+		if err := w.flushWorld(ctx); err != nil {
+			return "", err
+		}
+		defer w.SaveOn()
+	}
+
+	backupDir := cfg.Dir
+	if !filepath.IsAbs(backupDir) {
+		backupDir = filepath.Join(dir, backupDir)
+	}
+	if err := os.MkdirAll(backupDir, 0755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(backupDir, fmt.Sprintf("%s-%s.tar.gz", level, time.Now().Format(backupTimeLayout)))
+	if err := archiveDirs(path, dir, worlds); err != nil {
+		return "", err
+	}
+	return path, pruneBackups(backupDir, level, cfg.Keep)
+}
+
+// flushWorld turns the server saving off and waits for the world to be
+// saved to the disk, logged as "Saved the game", or "Saved the world" prior
+// to 1.13.
+func (w *Wrapper) flushWorld(ctx context.Context) error {
+	states, cancel := w.states.subscribe()
+	defer cancel()
+	if err := w.SaveOff(); err != nil {
+		return err
+	}
+	if err := w.SaveAll(true); err != nil {
+		w.SaveOn()
+		return err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			w.SaveOn()
+			return ctx.Err()
+		case ev := <-states:
+			if ev.String() == events.Saved {
+				return nil
+			}
+		}
+	}
+}
+
+// levelName returns the world directory name set in the server properties,
+// "world" by default.
+func levelName(dir string) (string, error) {
+	f, err := os.Open(filepath.Join(dir, "server.properties"))
+	if os.IsNotExist(err) {
+		return "world", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "level-name=") {
+			if name := strings.TrimPrefix(line, "level-name="); name != "" {
+				return name, nil
+			}
+		}
+	}
+	return "world", scanner.Err()
+}
+
+// archiveDirs writes the directories, relative to root, to a gzipped
+// tarball written to a temporary file first.
+func archiveDirs(path, root string, dirs []string) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	gz := gzip.NewWriter(tmp)
+	tw := tar.NewWriter(gz)
+	for _, d := range dirs {
+		if err := archiveDir(tw, root, d); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func archiveDir(tw *tar.Writer, root, dir string) error {
+	return filepath.Walk(filepath.Join(root, dir), func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		// The lock of the running server is not part of the world.
+		if fi.Name() == "session.lock" || !(fi.IsDir() || fi.Mode().IsRegular()) {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		hdr, err := tar.FileInfoHeader(fi, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		if fi.IsDir() {
+			hdr.Name += "/"
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			return nil
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.CopyN(tw, f, hdr.Size)
+		return err
+	})
+}
+
+// pruneBackups removes the oldest archives of the world above keep, none
+// being removed if 0.
+func pruneBackups(dir, level string, keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+	archives, err := filepath.Glob(filepath.Join(dir, level+"-*.tar.gz"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(archives)
+	for len(archives) > keep {
+		if err := os.Remove(archives[0]); err != nil {
+			return err
+		}
+		archives = archives[1:]
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package wrapper
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// readArchive returns the names of the files of a gzipped tarball.
+func readArchive(t *testing.T, path string) []string {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+	names := []string{}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, hdr.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestWrapperBackup(t *testing.T) {
+	dir := testDir(t)
+	files := map[string]string{
+		"server.properties":                       "motd=A Minecraft Server\nlevel-name=survival\n",
+		"survival/level.dat":                      "level",
+		"survival/session.lock":                   "lock",
+		"survival_nether/DIM-1/region/r.0.0.mca":  "region",
+		"logs/latest.log":                         "log",
+		"backups/survival-20200101-000000.tar.gz": "old backup",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	wpr, tc := newCmdTestWrapper(t, "1.16.5", map[string][]string{
+		"save-all flush": {
+			"[12:01:00] [Server thread/INFO]: Saving the game (this may take a moment!)",
+			"[12:01:00] [Server thread/INFO]: Saved the game",
+		},
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	path, err := wpr.Backup(ctx, dir, BackupConfig{Dir: "backups", Keep: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"survival/",
+		"survival/level.dat",
+		"survival_nether/",
+		"survival_nether/DIM-1/",
+		"survival_nether/DIM-1/region/",
+		"survival_nether/DIM-1/region/r.0.0.mca",
+	}
+	if names := readArchive(t, path); !reflect.DeepEqual(names, expected) {
+		t.Errorf("wrong files archived:\nactual  : %v\nexpected: %v", names, expected)
+	}
+	if cmds := tc.written(); !reflect.DeepEqual(cmds, []string{"save-off", "save-all flush", "save-on"}) {
+		t.Errorf("saving should be turned off while archiving, got %v", cmds)
+	}
+	archives, _ := filepath.Glob(filepath.Join(dir, "backups", "*"))
+	if !reflect.DeepEqual(archives, []string{path}) {
+		t.Errorf("older backups should be pruned, got %v", archives)
+	}
+
+	if _, err := wpr.Backup(ctx, filepath.Join(dir, "logs"), BackupConfig{Dir: "backups"}); !errors.Is(err, ErrWorldNotFound) {
+		t.Errorf("backup with no world should fail, got %v", err)
+	}
+}
+
+func TestWrapperBackupLegacy(t *testing.T) {
+	dir := testDir(t)
+	if err := os.MkdirAll(filepath.Join(dir, "world"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "world", "level.dat"), []byte("level"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Prior to 1.13, the save is logged as "Saved the world".
+	wpr, _ := newCmdTestWrapper(t, "1.12.2", map[string][]string{
+		"save-all flush": {
+			"[12:01:00] [Server thread/INFO]: Saving...",
+			"[12:01:00] [Server thread/INFO]: Saved the world",
+		},
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	path, err := wpr.Backup(ctx, dir, BackupConfig{Dir: "backups"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := readArchive(t, path); !reflect.DeepEqual(names, []string{"world/", "world/level.dat"}) {
+		t.Errorf("wrong files archived: %v", names)
+	}
+}
@@ -0,0 +1,204 @@
+package wrapper
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// BanEntry is a ban listed by the 'banlist' command.
+type BanEntry struct {
+	// Target is the name of the banned player, or the banned IP address.
+	Target string
+	Type   BanListType
+	// Source is who banned the target, a player name or "Server" for the
+	// bans from the console.
+	Source string
+	Reason string
+}
+
+// banTimeLayout is the layout of the ban dates in 'banned-players.json' and
+// 'banned-ips.json', "forever" being the expiry date of permanent bans.
+const (
+	banTimeLayout = "2006-01-02 15:04:05 -0700"
+	banForever    = "forever"
+)
+
+// PlayerBan is a banned player, as saved in 'banned-players.json'.
+type PlayerBan struct {
+	UUID    string
+	Name    string
+	Created time.Time
+	Source  string
+	// Expires is the end of a temporary ban, the zero time for permanent
+	// bans.
+	Expires time.Time
+	Reason  string
+}
+
+type jsonPlayerBan struct {
+	UUID    string `json:"uuid"`
+	Name    string `json:"name"`
+	Created string `json:"created"`
+	Source  string `json:"source"`
+	Expires string `json:"expires"`
+	Reason  string `json:"reason"`
+}
+
+// IPBan is a banned IP address, as saved in 'banned-ips.json'.
+type IPBan struct {
+	IP      string
+	Created time.Time
+	Source  string
+	// Expires is the end of a temporary ban, the zero time for permanent
+	// bans.
+	Expires time.Time
+	Reason  string
+}
+
+type jsonIPBan struct {
+	IP      string `json:"ip"`
+	Created string `json:"created"`
+	Source  string `json:"source"`
+	Expires string `json:"expires"`
+	Reason  string `json:"reason"`
+}
+
+// MarshalJSON encodes the ban in the 'banned-players.json' format.
+func (b PlayerBan) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonPlayerBan{
+		UUID:    b.UUID,
+		Name:    b.Name,
+		Created: formatBanTime(b.Created),
+		Source:  b.Source,
+		Expires: formatBanTime(b.Expires),
+		Reason:  b.Reason,
+	})
+}
+
+// UnmarshalJSON decodes the ban from the 'banned-players.json' format.
+func (b *PlayerBan) UnmarshalJSON(data []byte) error {
+	jb := jsonPlayerBan{}
+	if err := json.Unmarshal(data, &jb); err != nil {
+		return err
+	}
+	created, expires, err := parseBanTimes(jb.Created, jb.Expires)
+	if err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrInvalidEntry, jb.Name, err)
+	}
+	*b = PlayerBan{
+		UUID:    jb.UUID,
+		Name:    jb.Name,
+		Created: created,
+		Source:  jb.Source,
+		Expires: expires,
+		Reason:  jb.Reason,
+	}
+	return nil
+}
+
+// MarshalJSON encodes the ban in the 'banned-ips.json' format.
+func (b IPBan) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonIPBan{
+		IP:      b.IP,
+		Created: formatBanTime(b.Created),
+		Source:  b.Source,
+		Expires: formatBanTime(b.Expires),
+		Reason:  b.Reason,
+	})
+}
+
+// UnmarshalJSON decodes the ban from the 'banned-ips.json' format.
+func (b *IPBan) UnmarshalJSON(data []byte) error {
+	jb := jsonIPBan{}
+	if err := json.Unmarshal(data, &jb); err != nil {
+		return err
+	}
+	created, expires, err := parseBanTimes(jb.Created, jb.Expires)
+	if err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrInvalidEntry, jb.IP, err)
+	}
+	*b = IPBan{
+		IP:      jb.IP,
+		Created: created,
+		Source:  jb.Source,
+		Expires: expires,
+		Reason:  jb.Reason,
+	}
+	return nil
+}
+
+// Permanent returns whether the ban never expires.
+func (b PlayerBan) Permanent() bool {
+	return b.Expires.IsZero()
+}
+
+// Permanent returns whether the ban never expires.
+func (b IPBan) Permanent() bool {
+	return b.Expires.IsZero()
+}
+
+func formatBanTime(t time.Time) string {
+	if t.IsZero() {
+		return banForever
+	}
+	return t.Format(banTimeLayout)
+}
+
+func parseBanTimes(created, expires string) (time.Time, time.Time, error) {
+	c, err := time.Parse(banTimeLayout, created)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid created date %q", created)
+	}
+	if expires == banForever || expires == "" {
+		return c, time.Time{}, nil
+	}
+	e, err := time.Parse(banTimeLayout, expires)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid expires date %q", expires)
+	}
+	return c, e, nil
+}
+
+// ReadBannedPlayersFile returns the bans of a 'banned-players.json' file,
+// or none if it does not exist.
+func ReadBannedPlayersFile(path string) ([]PlayerBan, error) {
+	bans := []PlayerBan{}
+	if err := readJSONFile(path, &bans); err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	for _, b := range bans {
+		if err := validateProfile(b.UUID, b.Name); err != nil {
+			return nil, err
+		}
+		uuid := strings.ToLower(b.UUID)
+		if seen[uuid] {
+			return nil, fmt.Errorf("%w: duplicated uuid %s", ErrInvalidEntry, b.UUID)
+		}
+		seen[uuid] = true
+	}
+	return bans, nil
+}
+
+// ReadBannedIPsFile returns the bans of a 'banned-ips.json' file, or none
+// if it does not exist.
+func ReadBannedIPsFile(path string) ([]IPBan, error) {
+	bans := []IPBan{}
+	if err := readJSONFile(path, &bans); err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	for _, b := range bans {
+		if net.ParseIP(b.IP) == nil {
+			return nil, fmt.Errorf("%w: invalid ip %q", ErrInvalidEntry, b.IP)
+		}
+		if seen[b.IP] {
+			return nil, fmt.Errorf("%w: duplicated ip %s", ErrInvalidEntry, b.IP)
+		}
+		seen[b.IP] = true
+	}
+	return bans, nil
+}
@@ -0,0 +1,141 @@
+package wrapper
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBannedPlayersFile(t *testing.T) {
+	bans, err := ReadBannedPlayersFile("testdata/server_files/banned-players.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bans) != 2 {
+		t.Fatalf("wrong bans count: %d", len(bans))
+	}
+	created := time.Date(2021, 3, 1, 11, 0, 0, 0, time.UTC)
+	if b := bans[0]; b.Name != "griefer1" || !b.Created.Equal(created) || !b.Permanent() || b.Source != "Server" {
+		t.Errorf("wrong permanent ban: %+v", b)
+	}
+	expires := time.Date(2021, 3, 9, 8, 30, 0, 0, time.UTC)
+	if b := bans[1]; b.Permanent() || !b.Expires.Equal(expires) || b.Source != "admin" || b.Reason != "Griefing: spawn" {
+		t.Errorf("wrong temporary ban: %+v", b)
+	}
+
+	b, err := json.Marshal(bans[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"uuid":"6a9e4b3c-2d5f-4e7a-9b0c-1d2e3f4a5b6c","name":"griefer2","created":"2021-03-02 08:30:00 +0000","source":"admin","expires":"2021-03-09 08:30:00 +0000","reason":"Griefing: spawn"}`
+	if string(b) != expected {
+		t.Errorf("wrong ban json:\nactual  : %s\nexpected: %s", b, expected)
+	}
+}
+
+func TestBannedIPsFile(t *testing.T) {
+	bans, err := ReadBannedIPsFile("testdata/server_files/banned-ips.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bans) != 1 || bans[0].IP != "203.0.113.7" || !bans[0].Permanent() {
+		t.Errorf("wrong ip bans: %+v", bans)
+	}
+}
+
+func TestInvalidBanFiles(t *testing.T) {
+	dir := testDir(t)
+	path := filepath.Join(dir, "bans.json")
+
+	if bans, err := ReadBannedPlayersFile(path); err != nil || len(bans) != 0 {
+		t.Errorf("missing file should have no bans, got %v, %v", bans, err)
+	}
+	players := []string{
+		`[{"uuid":"5f8d3a2b-1c4e-4d6f-8a9b-0c1d2e3f4a5b","name":"griefer1","created":"yesterday","source":"Server","expires":"forever","reason":""}]`,
+		`[{"uuid":"5f8d3a2b","name":"griefer1","created":"2021-03-01 12:00:00 +0000","source":"Server","expires":"forever","reason":""}]`,
+	}
+	for _, content := range players {
+		ioutil.WriteFile(path, []byte(content), 0644)
+		if _, err := ReadBannedPlayersFile(path); !errors.Is(err, ErrInvalidEntry) {
+			t.Errorf("%s should be invalid, got %v", content, err)
+		}
+	}
+	ioutil.WriteFile(path, []byte(`[{"ip":"203.0.113","created":"2021-03-01 12:00:00 +0000","source":"Server","expires":"forever","reason":""}]`), 0644)
+	if _, err := ReadBannedIPsFile(path); !errors.Is(err, ErrInvalidEntry) {
+		t.Errorf("invalid ip should be rejected, got %v", err)
+	}
+}
+
+func TestWrapperBanListEntries(t *testing.T) {
+	wpr, _ := newCmdTestWrapper(t, "1.16.5", map[string][]string{
+		"banlist players": {
+			"[12:01:00] [Server thread/INFO]: There are 2 bans:",
+			"[12:01:00] [Server thread/INFO]: griefer1 was banned by Server: Banned by an operator.",
+			"[12:01:00] [Server thread/INFO]: griefer2 was banned by admin: Griefing: spawn",
+		},
+	})
+	entries, err := wpr.BanListEntries(BanPlayers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []BanEntry{
+		{Target: "griefer1", Type: BanPlayers, Source: "Server", Reason: "Banned by an operator."},
+		{Target: "griefer2", Type: BanPlayers, Source: "admin", Reason: "Griefing: spawn"},
+	}
+	if !reflect.DeepEqual(entries, expected) {
+		t.Errorf("wrong ban entries:\nactual  : %+v\nexpected: %+v", entries, expected)
+	}
+
+	legacy, _ := newCmdTestWrapper(t, "1.12.2", map[string][]string{
+		"banlist ips": {
+			"[12:01:00] [Server thread/INFO]: There are 2 total banned IP addresses:",
+			"[12:01:00] [Server thread/INFO]: 203.0.113.7, 203.0.113.8",
+		},
+	})
+	names, err := legacy.BanList(BanIPs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(names, []string{"203.0.113.7", "203.0.113.8"}) {
+		t.Errorf("wrong legacy ban list: %v", names)
+	}
+}
+
+func TestWrapperPardon(t *testing.T) {
+	tests := []struct {
+		version   string
+		responses map[string][]string
+	}{
+		{"1.16.5", map[string][]string{
+			"pardon griefer1":       {"[12:01:00] [Server thread/INFO]: Unbanned griefer1"},
+			"pardon player1":        {"[12:01:00] [Server thread/INFO]: Nothing changed. The player isn't banned"},
+			"pardon-ip 203.0.113.7": {"[12:01:00] [Server thread/INFO]: Unbanned IP 203.0.113.7"},
+			"pardon-ip 203.0.113.9": {"[12:01:00] [Server thread/INFO]: Nothing changed. That IP isn't banned"},
+		}},
+		{"1.12.2", map[string][]string{
+			"pardon griefer1":       {"[12:01:00] [Server thread/INFO]: Unbanned player griefer1"},
+			"pardon player1":        {"[12:01:00] [Server thread/INFO]: Could not unban player player1"},
+			"pardon-ip 203.0.113.7": {"[12:01:00] [Server thread/INFO]: Unbanned IP address 203.0.113.7"},
+			"pardon-ip 203.0.113.9": {"[12:01:00] [Server thread/INFO]: You have entered an invalid IP address"},
+		}},
+	}
+	for _, tt := range tests {
+		wpr, _ := newCmdTestWrapper(t, tt.version, tt.responses)
+		if err := wpr.Pardon(PlayerName("griefer1")); err != nil {
+			t.Errorf("%s: %s", tt.version, err)
+		}
+		if err := wpr.Pardon(PlayerName("player1")); err == nil {
+			t.Errorf("%s: pardoning a player not banned should fail", tt.version)
+		}
+		if err := wpr.PardonIP(IPAddress("203.0.113.7")); err != nil {
+			t.Errorf("%s: %s", tt.version, err)
+		}
+		if err := wpr.PardonIP(IPAddress("203.0.113.9")); err == nil {
+			t.Errorf("%s: pardoning an IP not banned should fail", tt.version)
+		}
+	}
+}
@@ -0,0 +1,810 @@
+package wrapper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wlwanpan/minecraft-wrapper/events"
+)
+
+var (
+	// ErrBanServerExists is returned when adding a server to a BanSync
+	// under a name already taken.
+	ErrBanServerExists = errors.New("ban server already exists")
+	// ErrNotBanned is returned when pardoning a target that is not banned
+	// across the servers of a BanSync.
+	ErrNotBanned = errors.New("target not banned")
+)
+
+// BanServer is a server whose bans are kept in sync by a BanSync, which
+// the Wrapper implements. The server pardons its temporary bans on expiry,
+// see 'Wrapper.Moderate'.
+type BanServer interface {
+	Ban(target Target, reason string) error
+	BanIP(target Target, reason string) error
+	TempBan(player string, d time.Duration, reason string) error
+	Pardon(player PlayerName) error
+	PardonIP(ip IPAddress) error
+	BanListEntries(t BanListType) ([]BanEntry, error)
+}
+
+// SyncedBan is a ban enforced across the servers of a BanSync.
+type SyncedBan struct {
+	// Target is the name of the banned player, or the banned IP address.
+	Target string      `json:"target"`
+	Type   BanListType `json:"type"`
+	Reason string      `json:"reason"`
+	// Origin is the server the ban was first seen on, empty for the bans
+	// issued through the BanSync.
+	Origin string `json:"origin,omitempty"`
+	// Source is who banned the target on the origin server, if known.
+	Source  string    `json:"source,omitempty"`
+	Created time.Time `json:"created"`
+	// Expires is the end of a temporary ban, the zero time for permanent
+	// bans.
+	Expires time.Time `json:"expires"`
+}
+
+// Permanent returns whether the ban never expires.
+func (b SyncedBan) Permanent() bool {
+	return b.Expires.IsZero()
+}
+
+// AuditAction is the kind of an AuditEntry.
+type AuditAction string
+
+const (
+	// AuditBan is a ban decided, either seen on a server or issued through
+	// the BanSync.
+	AuditBan AuditAction = "ban"
+	// AuditPardon is a pardon decided, either seen on a server or issued
+	// through the BanSync.
+	AuditPardon AuditAction = "pardon"
+	// AuditBanExpired is a temporary ban reaching its expiry, the servers
+	// pardoning it on their own.
+	AuditBanExpired AuditAction = "ban-expired"
+	// AuditBanApplied is a ban propagated to a server.
+	AuditBanApplied AuditAction = "ban-applied"
+	// AuditPardonApplied is a pardon propagated to a server.
+	AuditPardonApplied AuditAction = "pardon-applied"
+	// AuditConflict is a ban replacing another one of the same target.
+	AuditConflict AuditAction = "conflict"
+	// AuditSyncFailed is a server whose ban lists could not be read.
+	AuditSyncFailed AuditAction = "sync-failed"
+)
+
+// AuditEntry is an entry of the audit trail of a BanSync.
+type AuditEntry struct {
+	Time   time.Time   `json:"time"`
+	Action AuditAction `json:"action"`
+	// Server is the server the action was seen on or applied to, empty for
+	// the decisions made through the BanSync.
+	Server string      `json:"server,omitempty"`
+	Type   BanListType `json:"type,omitempty"`
+	Target string      `json:"target,omitempty"`
+	Reason string      `json:"reason,omitempty"`
+	// Expires is the end of a temporary ban, if any.
+	Expires *time.Time `json:"expires,omitempty"`
+	// Detail explains the conflicts.
+	Detail string `json:"detail,omitempty"`
+	// Error is the failure of an action applied to a server.
+	Error string `json:"error,omitempty"`
+}
+
+// AuditLog records the audit trail of a BanSync.
+type AuditLog interface {
+	Record(e AuditEntry)
+}
+
+// AuditFunc adapts a func to the AuditLog interface.
+type AuditFunc func(e AuditEntry)
+
+// Record calls f(e).
+func (f AuditFunc) Record(e AuditEntry) {
+	f(e)
+}
+
+// JSONAuditLog is an AuditLog writing the entries as JSON lines.
+type JSONAuditLog struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	err error
+}
+
+// NewJSONAuditLog returns an AuditLog writing one JSON entry per line to w.
+func NewJSONAuditLog(w io.Writer) *JSONAuditLog {
+	return &JSONAuditLog{enc: json.NewEncoder(w)}
+}
+
+// Record writes the entry, unless a previous write failed.
+func (l *JSONAuditLog) Record(e AuditEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.err == nil {
+		l.err = l.enc.Encode(e)
+	}
+}
+
+// Err returns the first write error, the entries recorded after it being
+// dropped.
+func (l *JSONAuditLog) Err() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.err
+}
+
+// BanRecord is the state of a target across the servers of a BanSync,
+// saved to its store.
+type BanRecord struct {
+	Ban      SyncedBan `json:"ban"`
+	Pardoned bool      `json:"pardoned,omitempty"`
+	// Applied holds the servers the ban is known to be applied on.
+	Applied []string `json:"applied,omitempty"`
+}
+
+// BanSyncStore persists the records of a BanSync.
+type BanSyncStore interface {
+	Load() ([]BanRecord, error)
+	Save([]BanRecord) error
+}
+
+// JSONBanSyncStore is the default BanSyncStore, saving the records to a
+// JSON file.
+type JSONBanSyncStore struct {
+	path string
+}
+
+// NewJSONBanSyncStore returns a BanSyncStore reading and writing the
+// records to the JSON file at the given path.
+func NewJSONBanSyncStore(path string) *JSONBanSyncStore {
+	return &JSONBanSyncStore{path: path}
+}
+
+// Load returns the records saved, or none if the file does not exist.
+func (s *JSONBanSyncStore) Load() ([]BanRecord, error) {
+	records := []BanRecord{}
+	if err := readJSONFile(s.path, &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// Save replaces the file with the given records.
+func (s *JSONBanSyncStore) Save(records []BanRecord) error {
+	return writeJSONFile(s.path, records)
+}
+
+type banKey struct {
+	t      BanListType
+	target string
+}
+
+// banKeyOf returns the key of a ban target, player names being compared
+// case-insensitively as the server does.
+func banKeyOf(t BanListType, target string) banKey {
+	if t == BanPlayers {
+		target = strings.ToLower(target)
+	}
+	return banKey{t: t, target: target}
+}
+
+// banRecord is the state of a target across the servers. Pardoned records
+// are kept to tell the servers the pardon did not reach yet from the ones
+// banning the target again.
+type banRecord struct {
+	ban      SyncedBan
+	pardoned bool
+	// applied holds the servers the ban is known to be applied on.
+	applied map[string]bool
+}
+
+// banOp is a ban or a pardon to apply to a server.
+type banOp struct {
+	server  string
+	key     banKey
+	rec     *banRecord
+	ban     bool
+	target  string
+	reason  string
+	expires time.Time
+}
+
+// BanSync keeps the bans of a fleet of servers in sync: a ban or a pardon
+// seen on a server is propagated to the others. Temporary bans are applied
+// with the expiry left, each server pardoning them once expired.
+//
+// Bans are seen from the Banned game events of the wrappers, or the ones
+// passed to Observe, and from the ban lists polled by Sync, which also
+// catches the pardons. Propagation is state based: a ban seen for a target
+// already banned, like the echo of a propagated ban, is not propagated
+// again, nor is a pardon of a target no longer banned. The records are
+// saved to the store, so the pardons and expiries survive restarts.
+//
+// Conflicts are resolved by tracking the servers each ban is applied on. A
+// ban missing from a server it was applied on was pardoned there, and the
+// pardon is propagated, while a server that did not get the ban yet gets it
+// applied. Likewise, a pardoned target still banned on a server the pardon
+// did not reach is pardoned again, rather than banned everywhere. A new ban
+// of a banned target, with a different reason, replaces the ban, the latest
+// decision winning. Every decision and its propagation is recorded to the
+// audit log.
+type BanSync struct {
+	mu      sync.Mutex
+	store   BanSyncStore
+	servers map[string]BanServer
+	// unsubscribe stops observing the events of the wrappers.
+	unsubscribe map[string]func()
+	records     map[banKey]*banRecord
+	audit       AuditLog
+	now         func() time.Time
+}
+
+// NewBanSync returns a BanSync loading and persisting its records to the
+// given store, and recording its audit trail to the given log.
+func NewBanSync(store BanSyncStore, audit AuditLog) (*BanSync, error) {
+	records, err := store.Load()
+	if err != nil {
+		return nil, err
+	}
+	registerGameEvent(events.Banned)
+	bs := &BanSync{
+		store:       store,
+		servers:     map[string]BanServer{},
+		unsubscribe: map[string]func(){},
+		records:     map[banKey]*banRecord{},
+		audit:       audit,
+		now:         time.Now,
+	}
+	for _, r := range records {
+		rec := &banRecord{ban: r.Ban, pardoned: r.Pardoned, applied: map[string]bool{}}
+		for _, name := range r.Applied {
+			rec.applied[name] = true
+		}
+		bs.records[banKeyOf(r.Ban.Type, r.Ban.Target)] = rec
+	}
+	return bs, nil
+}
+
+// AddServer adds a server to the fleet. The bans of the fleet are applied
+// to it, and its own bans to the others, on the next Sync. The bans logged
+// by a Wrapper are propagated as they happen.
+func (bs *BanSync) AddServer(name string, s BanServer) error {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	if _, ok := bs.servers[name]; ok {
+		return fmt.Errorf("%w: %s", ErrBanServerExists, name)
+	}
+	bs.servers[name] = s
+	if w, ok := s.(*Wrapper); ok {
+		evs, cancel := w.events.subscribe()
+		bs.unsubscribe[name] = cancel
+		go bs.observe(name, evs)
+	}
+	return nil
+}
+
+// observe propagates the bans of a wrapper until unsubscribed, the
+// failures being recorded to the audit log.
+func (bs *BanSync) observe(server string, evs <-chan events.GameEvent) {
+	for ev := range evs {
+		bs.Observe(server, ev)
+	}
+}
+
+// RemoveServer removes a server from the fleet, leaving its bans as they
+// are. The records are saved with the next change.
+func (bs *BanSync) RemoveServer(name string) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	if cancel, ok := bs.unsubscribe[name]; ok {
+		cancel()
+		delete(bs.unsubscribe, name)
+	}
+	delete(bs.servers, name)
+	for _, rec := range bs.records {
+		delete(rec.applied, name)
+	}
+}
+
+// Bans returns the bans enforced across the servers, oldest first.
+func (bs *BanSync) Bans() []SyncedBan {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	bans := []SyncedBan{}
+	for _, rec := range bs.records {
+		if !rec.pardoned {
+			bans = append(bans, rec.ban)
+		}
+	}
+	sort.Slice(bans, func(i, j int) bool {
+		if !bans[i].Created.Equal(bans[j].Created) {
+			return bans[i].Created.Before(bans[j].Created)
+		}
+		return bans[i].Target < bans[j].Target
+	})
+	return bans
+}
+
+// Ban bans a player from all the servers. The ban is kept even if applying
+// it to a server fails, Sync applying it later.
+func (bs *BanSync) Ban(player, reason string) error {
+	return bs.issueBan(BanPlayers, player, reason, 0)
+}
+
+// BanIP bans an IP address from all the servers.
+func (bs *BanSync) BanIP(ip, reason string) error {
+	return bs.issueBan(BanIPs, ip, reason, 0)
+}
+
+// TempBan bans a player from all the servers for the given duration, each
+// server pardoning the player on expiry.
+func (bs *BanSync) TempBan(player string, d time.Duration, reason string) error {
+	if d <= 0 {
+		return fmt.Errorf("invalid ban duration %s", d)
+	}
+	return bs.issueBan(BanPlayers, player, reason, d)
+}
+
+// Pardon pardons a player on all the servers it is banned from.
+func (bs *BanSync) Pardon(player string) error {
+	return bs.issuePardon(BanPlayers, player)
+}
+
+// PardonIP pardons an IP address on all the servers it is banned from.
+func (bs *BanSync) PardonIP(ip string) error {
+	return bs.issuePardon(BanIPs, ip)
+}
+
+func validateBanTarget(t BanListType, target string) error {
+	if t == BanIPs && net.ParseIP(target) == nil {
+		return fmt.Errorf("%w: invalid ip %q", ErrInvalidEntry, target)
+	}
+	if t == BanPlayers && !playerNameRegex.MatchString(target) {
+		return fmt.Errorf("%w: invalid player name %q", ErrInvalidEntry, target)
+	}
+	return nil
+}
+
+func (bs *BanSync) issueBan(t BanListType, target, reason string, d time.Duration) error {
+	if err := validateBanTarget(t, target); err != nil {
+		return err
+	}
+	bs.mu.Lock()
+	b := SyncedBan{Target: target, Type: t, Reason: reason, Created: bs.now()}
+	if d > 0 {
+		b.Expires = b.Created.Add(d)
+	}
+	ops := bs.ban(b)
+	saveErr := bs.save()
+	bs.mu.Unlock()
+	return firstErr(bs.apply(ops), saveErr)
+}
+
+func (bs *BanSync) issuePardon(t BanListType, target string) error {
+	bs.mu.Lock()
+	key := banKeyOf(t, target)
+	if rec := bs.records[key]; rec == nil || rec.pardoned {
+		bs.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrNotBanned, target)
+	}
+	ops := bs.pardon(key, "")
+	saveErr := bs.save()
+	bs.mu.Unlock()
+	return firstErr(bs.apply(ops), saveErr)
+}
+
+// Observe propagates the ban of a Banned game event logged by a server,
+// the other events being ignored. It is called on the events of the
+// wrappers added, and is only needed for the other servers.
+func (bs *BanSync) Observe(server string, ev events.GameEvent) error {
+	if ev.Name != events.Banned {
+		return nil
+	}
+	b := SyncedBan{
+		Target: ev.Data["player_name"],
+		Type:   BanPlayers,
+		Reason: ev.Data["reason"],
+		Origin: server,
+		Source: ev.Data["source"],
+	}
+	if ip, ok := ev.Data["ip"]; ok {
+		b.Target, b.Type = ip, BanIPs
+	}
+	if expires, err := time.Parse(time.RFC3339, ev.Data["expires"]); err == nil {
+		b.Expires = expires
+	}
+	if b.Target == "" {
+		return nil
+	}
+	bs.mu.Lock()
+	b.Created = bs.now()
+	ops := bs.ban(b)
+	saveErr := bs.save()
+	bs.mu.Unlock()
+	return firstErr(bs.apply(ops), saveErr)
+}
+
+// Sync drops the expired bans, then reads the ban lists of every server,
+// propagating the bans and pardons made since the last Sync and applying
+// the bans a server missed. It goes through all the servers, returning the
+// first failure.
+func (bs *BanSync) Sync() error {
+	bs.mu.Lock()
+	bs.expire()
+	first := bs.save()
+	names := bs.serverNames()
+	bs.mu.Unlock()
+
+	for _, name := range names {
+		if err := bs.syncServer(name); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// Run calls Sync every interval until the context is done, the failures
+// being recorded to the audit log.
+func (bs *BanSync) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		bs.Sync()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (bs *BanSync) syncServer(name string) error {
+	bs.mu.Lock()
+	srv, ok := bs.servers[name]
+	bs.mu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	listed := map[banKey]BanEntry{}
+	for _, t := range []BanListType{BanPlayers, BanIPs} {
+		entries, err := srv.BanListEntries(t)
+		if err != nil {
+			bs.mu.Lock()
+			bs.record(AuditEntry{Action: AuditSyncFailed, Server: name, Type: t, Error: err.Error()})
+			bs.mu.Unlock()
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		for _, e := range entries {
+			e.Type = t
+			listed[banKeyOf(t, e.Target)] = e
+		}
+	}
+
+	bs.mu.Lock()
+	ops := bs.reconcile(name, listed)
+	saveErr := bs.save()
+	bs.mu.Unlock()
+	return firstErr(bs.apply(ops), saveErr)
+}
+
+// reconcile compares the bans listed by a server to the records, and
+// returns the operations bringing the fleet in line.
+func (bs *BanSync) reconcile(server string, listed map[banKey]BanEntry) []banOp {
+	ops := []banOp{}
+	for _, key := range sortedBanKeys(listed) {
+		e := listed[key]
+		rec := bs.records[key]
+		// An expired ban is still listed until the server pardons it, the
+		// legacy servers listing no reason.
+		expired := rec != nil && rec.pardoned && bs.expired(rec)
+		switch {
+		case expired && (e.Reason == "" || e.Reason == rec.ban.Reason):
+			// The server pardons the expired ban on its own.
+		case rec == nil || expired || rec.pardoned && !rec.applied[server]:
+			ops = append(ops, bs.ban(SyncedBan{
+				Target:  e.Target,
+				Type:    key.t,
+				Reason:  e.Reason,
+				Origin:  server,
+				Source:  e.Source,
+				Created: bs.now(),
+			})...)
+		case rec.pardoned:
+			// The pardon did not reach the server yet.
+			ops = append(ops, newBanOp(server, key, rec, false))
+		default:
+			rec.applied[server] = true
+		}
+	}
+
+	for _, key := range bs.recordKeys() {
+		rec := bs.records[key]
+		if _, ok := listed[key]; ok {
+			continue
+		}
+		switch {
+		case rec.pardoned:
+			delete(rec.applied, server)
+		case rec.applied[server]:
+			// The target was pardoned on the server.
+			ops = append(ops, bs.pardon(key, server)...)
+		default:
+			ops = append(ops, newBanOp(server, key, rec, true))
+		}
+	}
+	return ops
+}
+
+// ban records a ban and returns the operations propagating it. The ban of
+// a target already banned is not propagated, the ban being only replaced
+// if it conflicts with the recorded one. Locked.
+func (bs *BanSync) ban(b SyncedBan) []banOp {
+	key := banKeyOf(b.Type, b.Target)
+	if rec := bs.records[key]; rec != nil && !rec.pardoned {
+		if b.Origin != "" {
+			rec.applied[b.Origin] = true
+		}
+		if !banConflicts(rec.ban, b) {
+			return nil
+		}
+		e := bs.auditBan(AuditConflict, b)
+		e.Detail = fmt.Sprintf("replaces the ban from %q: %s", rec.ban.Origin, rec.ban.Reason)
+		bs.record(e)
+		rec.ban.Reason, rec.ban.Expires, rec.ban.Source = b.Reason, b.Expires, b.Source
+		if b.Origin != "" {
+			return nil
+		}
+		// The servers enforce the expiry, which has to be applied again.
+		ops := []banOp{}
+		for _, name := range bs.serverNames() {
+			ops = append(ops, newBanOp(name, key, rec, true))
+		}
+		return ops
+	}
+
+	rec := &banRecord{ban: b, applied: map[string]bool{}}
+	if b.Origin != "" {
+		rec.applied[b.Origin] = true
+	}
+	bs.records[key] = rec
+	bs.record(bs.auditBan(AuditBan, b))
+
+	ops := []banOp{}
+	for _, name := range bs.serverNames() {
+		if name != b.Origin {
+			ops = append(ops, newBanOp(name, key, rec, true))
+		}
+	}
+	return ops
+}
+
+// banConflicts returns whether a ban of a banned target is a new decision
+// rather than the echo of the recorded ban: the bans issued through the
+// BanSync are if they differ, while the bans seen on a server only are if their
+// reason differs, the legacy servers logging none.
+func banConflicts(recorded, b SyncedBan) bool {
+	if b.Origin == "" {
+		return b.Reason != recorded.Reason || !b.Expires.Equal(recorded.Expires)
+	}
+	return b.Reason != "" && recorded.Reason != "" && b.Reason != recorded.Reason
+}
+
+// pardon records the pardon of a target and returns the operations
+// propagating it to the servers the ban was applied on. Locked.
+func (bs *BanSync) pardon(key banKey, origin string) []banOp {
+	rec := bs.records[key]
+	if rec == nil || rec.pardoned {
+		return nil
+	}
+	rec.pardoned = true
+	delete(rec.applied, origin)
+	bs.record(AuditEntry{Action: AuditPardon, Server: origin, Type: key.t, Target: rec.ban.Target})
+	return bs.pardonOps(key, rec)
+}
+
+// expire drops the temporary bans past their expiry, the servers pardoning
+// them on their own. Locked.
+func (bs *BanSync) expire() {
+	for _, key := range bs.recordKeys() {
+		rec := bs.records[key]
+		if rec.pardoned || !bs.expired(rec) {
+			continue
+		}
+		rec.pardoned = true
+		bs.record(bs.auditBan(AuditBanExpired, rec.ban))
+	}
+}
+
+// expired returns whether a temporary ban is past its expiry. Locked.
+func (bs *BanSync) expired(rec *banRecord) bool {
+	return !rec.ban.Permanent() && !bs.now().Before(rec.ban.Expires)
+}
+
+func (bs *BanSync) pardonOps(key banKey, rec *banRecord) []banOp {
+	ops := []banOp{}
+	for _, name := range bs.serverNames() {
+		if rec.applied[name] {
+			ops = append(ops, newBanOp(name, key, rec, false))
+		}
+	}
+	return ops
+}
+
+func newBanOp(server string, key banKey, rec *banRecord, ban bool) banOp {
+	return banOp{
+		server:  server,
+		key:     key,
+		rec:     rec,
+		ban:     ban,
+		target:  rec.ban.Target,
+		reason:  rec.ban.Reason,
+		expires: rec.ban.Expires,
+	}
+}
+
+// current returns whether an operation still matches the record state, so
+// that a ban pardoned meanwhile is not applied. Locked.
+func (bs *BanSync) current(op banOp) bool {
+	return bs.records[op.key] == op.rec && op.rec.pardoned != op.ban
+}
+
+// apply runs the operations on the servers, going through all of them and
+// returning the first failure. The bans are only marked applied once seen
+// on the server, as the 'ban' command gets no response.
+func (bs *BanSync) apply(ops []banOp) error {
+	var first error
+	pardoned := false
+	for _, op := range ops {
+		bs.mu.Lock()
+		srv, ok := bs.servers[op.server]
+		ok = ok && bs.current(op)
+		left := op.expires.Sub(bs.now())
+		bs.mu.Unlock()
+		if !ok {
+			continue
+		}
+		if !op.expires.IsZero() && left <= 0 {
+			// The servers pardon the expired bans on their own.
+			continue
+		}
+
+		var err error
+		switch {
+		case op.ban && op.key.t == BanIPs:
+			err = srv.BanIP(IPAddress(op.target), op.reason)
+		case op.ban && !op.expires.IsZero():
+			err = srv.TempBan(op.target, left, op.reason)
+		case op.ban:
+			err = srv.Ban(PlayerName(op.target), op.reason)
+		case op.key.t == BanIPs:
+			err = srv.PardonIP(IPAddress(op.target))
+		default:
+			err = srv.Pardon(PlayerName(op.target))
+		}
+
+		bs.mu.Lock()
+		e := AuditEntry{Action: AuditPardonApplied, Server: op.server, Type: op.key.t, Target: op.target}
+		if op.ban {
+			e.Action, e.Reason = AuditBanApplied, op.reason
+		}
+		if err != nil {
+			e.Error = err.Error()
+		} else if !op.ban && bs.current(op) {
+			delete(op.rec.applied, op.server)
+			pardoned = true
+		}
+		bs.record(e)
+		bs.mu.Unlock()
+
+		if err != nil && first == nil {
+			first = fmt.Errorf("%s: %w", op.server, err)
+		}
+	}
+	if pardoned {
+		bs.mu.Lock()
+		first = firstErr(first, bs.save())
+		bs.mu.Unlock()
+	}
+	return first
+}
+
+// save writes the records to the store. Locked.
+func (bs *BanSync) save() error {
+	records := make([]BanRecord, 0, len(bs.records))
+	for _, key := range bs.recordKeys() {
+		rec := bs.records[key]
+		records = append(records, BanRecord{
+			Ban:      rec.ban,
+			Pardoned: rec.pardoned,
+			Applied:  sortedServers(rec.applied),
+		})
+	}
+	return bs.store.Save(records)
+}
+
+func firstErr(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (bs *BanSync) auditBan(action AuditAction, b SyncedBan) AuditEntry {
+	e := AuditEntry{
+		Action: action,
+		Server: b.Origin,
+		Type:   b.Type,
+		Target: b.Target,
+		Reason: b.Reason,
+	}
+	if !b.Permanent() {
+		expires := b.Expires
+		e.Expires = &expires
+	}
+	return e
+}
+
+// record adds an entry to the audit log. Locked.
+func (bs *BanSync) record(e AuditEntry) {
+	if bs.audit == nil {
+		return
+	}
+	e.Time = bs.now()
+	bs.audit.Record(e)
+}
+
+func (bs *BanSync) serverNames() []string {
+	names := make([]string, 0, len(bs.servers))
+	for name := range bs.servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedServers(applied map[string]bool) []string {
+	names := make([]string, 0, len(applied))
+	for name := range applied {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (bs *BanSync) recordKeys() []banKey {
+	keys := make([]banKey, 0, len(bs.records))
+	for key := range bs.records {
+		keys = append(keys, key)
+	}
+	sortBanKeys(keys)
+	return keys
+}
+
+func sortedBanKeys(listed map[banKey]BanEntry) []banKey {
+	keys := make([]banKey, 0, len(listed))
+	for key := range listed {
+		keys = append(keys, key)
+	}
+	sortBanKeys(keys)
+	return keys
+}
+
+func sortBanKeys(keys []banKey) {
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].t != keys[j].t {
+			return keys[i].t < keys[j].t
+		}
+		return keys[i].target < keys[j].target
+	})
+}
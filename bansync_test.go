@@ -0,0 +1,518 @@
+package wrapper
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wlwanpan/minecraft-wrapper/events"
+)
+
+var _ BanServer = (*Wrapper)(nil)
+
+// fakeBanServer is a BanServer holding its ban lists in memory.
+type fakeBanServer struct {
+	mu    sync.Mutex
+	bans  map[BanListType]map[string]string
+	calls []string
+	// offline makes every command fail.
+	offline bool
+	// notify gets the calls, if set.
+	notify chan string
+}
+
+func newFakeBanServer() *fakeBanServer {
+	return &fakeBanServer{bans: map[BanListType]map[string]string{
+		BanPlayers: {},
+		BanIPs:     {},
+	}}
+}
+
+func (s *fakeBanServer) do(call string, t BanListType, target, reason string, ban bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.offline {
+		return ErrWrapperNotOnline
+	}
+	s.calls = append(s.calls, call+" "+target)
+	if s.notify != nil {
+		s.notify <- call + " " + target
+	}
+	if ban {
+		s.bans[t][target] = reason
+		return nil
+	}
+	if _, ok := s.bans[t][target]; !ok {
+		return errors.New("Nothing changed. The player isn't banned")
+	}
+	delete(s.bans[t], target)
+	return nil
+}
+
+func (s *fakeBanServer) Ban(target Target, reason string) error {
+	return s.do("ban", BanPlayers, target.String(), reason, true)
+}
+
+func (s *fakeBanServer) BanIP(target Target, reason string) error {
+	return s.do("ban-ip", BanIPs, target.String(), reason, true)
+}
+
+func (s *fakeBanServer) TempBan(player string, d time.Duration, reason string) error {
+	return s.do("tempban", BanPlayers, player, reason, true)
+}
+
+func (s *fakeBanServer) Pardon(player PlayerName) error {
+	return s.do("pardon", BanPlayers, string(player), "", false)
+}
+
+func (s *fakeBanServer) PardonIP(ip IPAddress) error {
+	return s.do("pardon-ip", BanIPs, string(ip), "", false)
+}
+
+func (s *fakeBanServer) BanListEntries(t BanListType) ([]BanEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.offline {
+		return nil, ErrWrapperNotOnline
+	}
+	entries := []BanEntry{}
+	for target, reason := range s.bans[t] {
+		entries = append(entries, BanEntry{Target: target, Type: t, Source: "Server", Reason: reason})
+	}
+	return entries, nil
+}
+
+func (s *fakeBanServer) banned(t BanListType) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	targets := []string{}
+	for target := range s.bans[t] {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+	return targets
+}
+
+func (s *fakeBanServer) takeCalls() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	calls := s.calls
+	s.calls = nil
+	return calls
+}
+
+// memBanSyncStore is a BanSyncStore holding the records in memory.
+type memBanSyncStore struct {
+	records []BanRecord
+}
+
+func (s *memBanSyncStore) Load() ([]BanRecord, error) {
+	return s.records, nil
+}
+
+func (s *memBanSyncStore) Save(records []BanRecord) error {
+	s.records = records
+	return nil
+}
+
+type banSyncTest struct {
+	sync    *BanSync
+	store   *memBanSyncStore
+	servers map[string]*fakeBanServer
+	audit   []AuditEntry
+	now     time.Time
+}
+
+func newBanSyncTest(t *testing.T, names ...string) *banSyncTest {
+	bt := &banSyncTest{
+		store:   &memBanSyncStore{},
+		servers: map[string]*fakeBanServer{},
+		now:     time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC),
+	}
+	bt.restart(t)
+	for _, name := range names {
+		bt.servers[name] = newFakeBanServer()
+		if err := bt.sync.AddServer(name, bt.servers[name]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return bt
+}
+
+// restart replaces the BanSync with a new one loaded from the store, with
+// the same servers.
+func (bt *banSyncTest) restart(t *testing.T) {
+	bs, err := NewBanSync(bt.store, AuditFunc(func(e AuditEntry) {
+		bt.audit = append(bt.audit, e)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bs.now = func() time.Time { return bt.now }
+	bt.sync = bs
+	for name, s := range bt.servers {
+		if err := bt.sync.AddServer(name, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func (bt *banSyncTest) actions() []string {
+	actions := []string{}
+	for _, e := range bt.audit {
+		actions = append(actions, string(e.Action)+" "+e.Server+" "+e.Target)
+	}
+	bt.audit = nil
+	return actions
+}
+
+func bannedEvent(data map[string]string) events.GameEvent {
+	ev := events.NewGameEvent(events.Banned)
+	ev.Data = data
+	return ev
+}
+
+func TestBanSyncPropagation(t *testing.T) {
+	bt := newBanSyncTest(t, "a", "b", "c")
+	bt.servers["a"].bans[BanPlayers]["griefer1"] = "Griefing"
+	ev := bannedEvent(map[string]string{"player_name": "griefer1", "reason": "Griefing"})
+	if err := bt.sync.Observe("a", ev); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"b", "c"} {
+		if calls := bt.servers[name].takeCalls(); !reflect.DeepEqual(calls, []string{"ban griefer1"}) {
+			t.Errorf("ban should be propagated to %s, got %v", name, calls)
+		}
+	}
+	if calls := bt.servers["a"].takeCalls(); len(calls) != 0 {
+		t.Errorf("ban should not be applied back to its origin, got %v", calls)
+	}
+
+	// The propagated bans are logged by the other servers, which should not
+	// loop back.
+	if err := bt.sync.Observe("b", ev); err != nil {
+		t.Fatal(err)
+	}
+	if err := bt.sync.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	for name, s := range bt.servers {
+		if calls := s.takeCalls(); len(calls) != 0 {
+			t.Errorf("%s: propagated ban should not loop, got %v", name, calls)
+		}
+	}
+
+	ipEv := bannedEvent(map[string]string{"ip": "203.0.113.7", "reason": "Spam", "source": "admin"})
+	if err := bt.sync.Observe("b", ipEv); err != nil {
+		t.Fatal(err)
+	}
+	if banned := bt.servers["c"].banned(BanIPs); !reflect.DeepEqual(banned, []string{"203.0.113.7"}) {
+		t.Errorf("ip ban should be propagated, got %v", banned)
+	}
+
+	expected := []string{
+		"ban a griefer1", "ban-applied b griefer1", "ban-applied c griefer1",
+		"ban b 203.0.113.7", "ban-applied a 203.0.113.7", "ban-applied c 203.0.113.7",
+	}
+	if actions := bt.actions(); !reflect.DeepEqual(actions, expected) {
+		t.Errorf("wrong audit trail:\nactual  : %v\nexpected: %v", actions, expected)
+	}
+	bans := bt.sync.Bans()
+	if len(bans) != 2 || bans[0].Type != BanIPs || bans[0].Source != "admin" || bans[0].Origin != "b" {
+		t.Errorf("wrong bans: %+v", bans)
+	}
+}
+
+func TestBanSyncPardon(t *testing.T) {
+	bt := newBanSyncTest(t, "a", "b", "c")
+	if err := bt.sync.Ban("griefer1", "Griefing"); err != nil {
+		t.Fatal(err)
+	}
+	if err := bt.sync.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A pardon on a server is seen on the next sync, and propagated.
+	delete(bt.servers["b"].bans[BanPlayers], "griefer1")
+	bt.audit = nil
+	if err := bt.sync.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	for name, s := range bt.servers {
+		if banned := s.banned(BanPlayers); len(banned) != 0 {
+			t.Errorf("%s: pardon should be propagated, got %v", name, banned)
+		}
+	}
+	expected := []string{"pardon b griefer1", "pardon-applied a griefer1", "pardon-applied c griefer1"}
+	if actions := bt.actions(); !reflect.DeepEqual(actions, expected) {
+		t.Errorf("wrong audit trail:\nactual  : %v\nexpected: %v", actions, expected)
+	}
+	if err := bt.sync.Pardon("griefer1"); !errors.Is(err, ErrNotBanned) {
+		t.Errorf("pardoning a target not banned should fail, got %v", err)
+	}
+
+	if err := bt.sync.BanIP("203.0.113.7", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := bt.sync.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if err := bt.sync.PardonIP("203.0.113.7"); err != nil {
+		t.Fatal(err)
+	}
+	for name, s := range bt.servers {
+		if banned := s.banned(BanIPs); len(banned) != 0 {
+			t.Errorf("%s: ip should be pardoned, got %v", name, banned)
+		}
+	}
+}
+
+func TestBanSyncTempBan(t *testing.T) {
+	bt := newBanSyncTest(t, "a", "b")
+	if err := bt.sync.TempBan("griefer1", time.Hour, "Cooldown"); err != nil {
+		t.Fatal(err)
+	}
+	if err := bt.sync.TempBan("griefer2", 0, "Cooldown"); err == nil {
+		t.Error("temporary ban with no duration should fail")
+	}
+	if err := bt.sync.Ban("not a player", ""); !errors.Is(err, ErrInvalidEntry) {
+		t.Errorf("ban of an invalid name should fail, got %v", err)
+	}
+	for name, s := range bt.servers {
+		if calls := s.takeCalls(); !reflect.DeepEqual(calls, []string{"tempban griefer1"}) {
+			t.Errorf("%s: temporary ban should be applied as such, got %v", name, calls)
+		}
+	}
+	if err := bt.sync.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The records survive a restart, the servers joining later getting the
+	// ban with its expiry.
+	bt.now = bt.now.Add(30 * time.Minute)
+	bt.servers["c"] = newFakeBanServer()
+	bt.restart(t)
+	if err := bt.sync.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if calls := bt.servers["c"].takeCalls(); !reflect.DeepEqual(calls, []string{"tempban griefer1"}) {
+		t.Errorf("temporary ban should be applied to the new server, got %v", calls)
+	}
+	bans := bt.sync.Bans()
+	if len(bans) != 1 || !bans[0].Expires.Equal(bt.now.Add(30*time.Minute)) {
+		t.Errorf("temporary ban should keep its expiry, got %+v", bans)
+	}
+
+	// The servers pardon the ban on expiry, which is not propagated.
+	bt.now = bt.now.Add(30 * time.Minute)
+	delete(bt.servers["a"].bans[BanPlayers], "griefer1")
+	bt.audit = nil
+	if err := bt.sync.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	for name, s := range bt.servers {
+		if calls := s.takeCalls(); len(calls) != 0 {
+			t.Errorf("%s: expired ban should be left to the server, got %v", name, calls)
+		}
+	}
+	if actions := bt.actions(); !reflect.DeepEqual(actions, []string{"ban-expired  griefer1"}) {
+		t.Errorf("wrong audit trail: %v", actions)
+	}
+	if bans := bt.sync.Bans(); len(bans) != 0 {
+		t.Errorf("expired ban should be dropped, got %+v", bans)
+	}
+}
+
+func TestBanSyncObserveTempBan(t *testing.T) {
+	bt := newBanSyncTest(t, "a", "b")
+	expires := bt.now.Add(time.Hour)
+	bt.servers["a"].bans[BanPlayers]["griefer1"] = "Cooldown"
+	ev := bannedEvent(map[string]string{"player_name": "griefer1", "reason": "Cooldown", "expires": expires.Format(time.RFC3339)})
+	if err := bt.sync.Observe("a", ev); err != nil {
+		t.Fatal(err)
+	}
+	if calls := bt.servers["b"].takeCalls(); !reflect.DeepEqual(calls, []string{"tempban griefer1"}) {
+		t.Errorf("temporary ban should be propagated as such, got %v", calls)
+	}
+	if bans := bt.sync.Bans(); len(bans) != 1 || !bans[0].Expires.Equal(expires) {
+		t.Errorf("wrong bans: %+v", bans)
+	}
+}
+
+func TestBanSyncWrapperEvents(t *testing.T) {
+	wpr, tc := newCmdTestWrapper(t, "1.16.5", nil)
+	bt := newBanSyncTest(t)
+	b := newFakeBanServer()
+	b.notify = make(chan string, 1)
+	if err := bt.sync.AddServer("a", wpr); err != nil {
+		t.Fatal(err)
+	}
+	if err := bt.sync.AddServer("b", b); err != nil {
+		t.Fatal(err)
+	}
+
+	tc.lines <- "[12:01:00] [Server thread/INFO]: Banned griefer1: Griefing"
+	select {
+	case call := <-b.notify:
+		if call != "ban griefer1" {
+			t.Errorf("wrong call: %s", call)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the ban logged by the wrapper should be propagated")
+	}
+	bt.sync.RemoveServer("a")
+}
+
+func TestBanSyncConflicts(t *testing.T) {
+	bt := newBanSyncTest(t, "a", "b")
+	if err := bt.sync.Ban("griefer1", "Griefing"); err != nil {
+		t.Fatal(err)
+	}
+	if err := bt.sync.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The pardon does not reach the offline server, which should get it on
+	// a later sync rather than have its stale ban propagated.
+	bt.servers["b"].offline = true
+	if err := bt.sync.Pardon("griefer1"); err == nil {
+		t.Error("pardon on an offline server should fail")
+	}
+	if err := bt.sync.Sync(); err == nil {
+		t.Error("sync of an offline server should fail")
+	}
+	bt.servers["b"].offline = false
+	if err := bt.sync.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	for name, s := range bt.servers {
+		if banned := s.banned(BanPlayers); len(banned) != 0 {
+			t.Errorf("%s: stale ban should be pardoned, got %v", name, banned)
+		}
+	}
+
+	// A server banning the target again after the pardon is a new ban.
+	bt.servers["b"].bans[BanPlayers]["griefer1"] = "Griefing again"
+	if err := bt.sync.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if banned := bt.servers["a"].banned(BanPlayers); len(banned) != 1 {
+		t.Errorf("new ban should be propagated, got %v", banned)
+	}
+
+	// A ban with another reason replaces the recorded one.
+	bt.audit = nil
+	ev := bannedEvent(map[string]string{"player_name": "Griefer1", "reason": "Cheating"})
+	if err := bt.sync.Observe("a", ev); err != nil {
+		t.Fatal(err)
+	}
+	actions := bt.actions()
+	if !reflect.DeepEqual(actions, []string{"conflict a Griefer1"}) {
+		t.Errorf("conflicting ban should be audited, got %v", actions)
+	}
+	if bans := bt.sync.Bans(); len(bans) != 1 || bans[0].Reason != "Cheating" {
+		t.Errorf("latest ban should win, got %+v", bans)
+	}
+}
+
+func TestBanSyncAddServer(t *testing.T) {
+	bt := newBanSyncTest(t, "a")
+	bt.servers["a"].bans[BanPlayers]["griefer1"] = "Griefing"
+	if err := bt.sync.AddServer("a", newFakeBanServer()); !errors.Is(err, ErrBanServerExists) {
+		t.Errorf("adding a server twice should fail, got %v", err)
+	}
+	if err := bt.sync.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	b := newFakeBanServer()
+	b.bans[BanIPs]["203.0.113.7"] = "Spam"
+	if err := bt.sync.AddServer("b", b); err != nil {
+		t.Fatal(err)
+	}
+	if err := bt.sync.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if banned := b.banned(BanPlayers); !reflect.DeepEqual(banned, []string{"griefer1"}) {
+		t.Errorf("fleet bans should be applied to the new server, got %v", banned)
+	}
+	if banned := bt.servers["a"].banned(BanIPs); !reflect.DeepEqual(banned, []string{"203.0.113.7"}) {
+		t.Errorf("new server bans should be propagated, got %v", banned)
+	}
+}
+
+func TestJSONAuditLog(t *testing.T) {
+	buf := &bytes.Buffer{}
+	log := NewJSONAuditLog(buf)
+	expires := time.Date(2021, 7, 1, 13, 0, 0, 0, time.UTC)
+	log.Record(AuditEntry{Action: AuditBan, Server: "a", Type: BanPlayers, Target: "griefer1", Expires: &expires})
+	log.Record(AuditEntry{Action: AuditPardonApplied, Server: "b", Type: BanPlayers, Target: "griefer1", Error: "offline"})
+	if err := log.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %q", buf.String())
+	}
+	e := AuditEntry{}
+	if err := json.Unmarshal([]byte(lines[0]), &e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Action != AuditBan || e.Target != "griefer1" || e.Expires == nil || !e.Expires.Equal(expires) {
+		t.Errorf("wrong audit entry: %+v", e)
+	}
+	if strings.Contains(lines[1], "expires") {
+		t.Errorf("permanent entries should carry no expiry: %s", lines[1])
+	}
+}
+
+func TestJSONBanSyncStore(t *testing.T) {
+	dir := testDir(t)
+	store := NewJSONBanSyncStore(filepath.Join(dir, "bans.json"))
+
+	bs, err := NewBanSync(store, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bs.AddServer("a", newFakeBanServer()); err != nil {
+		t.Fatal(err)
+	}
+	if err := bs.TempBan("griefer1", time.Hour, "Cooldown"); err != nil {
+		t.Fatal(err)
+	}
+	if err := bs.BanIP("203.0.113.7", "Spam"); err != nil {
+		t.Fatal(err)
+	}
+	if err := bs.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := NewBanSync(store, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, expected := loaded.Bans(), bs.Bans()
+	if len(actual) != len(expected) {
+		t.Fatalf("wrong bans loaded:\nactual  : %+v\nexpected: %+v", actual, expected)
+	}
+	for i, ban := range expected {
+		a := actual[i]
+		if a.Target != ban.Target || a.Type != ban.Type || a.Reason != ban.Reason ||
+			!a.Created.Equal(ban.Created) || !a.Expires.Equal(ban.Expires) {
+			t.Errorf("wrong ban loaded:\nactual  : %+v\nexpected: %+v", a, ban)
+		}
+	}
+	for _, rec := range loaded.records {
+		if !rec.applied["a"] {
+			t.Errorf("%s should be applied on a", rec.ban.Target)
+		}
+	}
+}
@@ -0,0 +1,320 @@
+package wrapper
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/wlwanpan/minecraft-wrapper/events"
+)
+
+var (
+	// ErrChatCommandExists is returned when registering a chat command with a
+	// name or alias already taken by another command.
+	ErrChatCommandExists = errors.New("chat command already exists")
+	// ErrChatCommandInvalid is returned when registering a chat command with
+	// no name or handler.
+	ErrChatCommandInvalid = errors.New("chat command needs a name and a handler")
+)
+
+// ChatHandler handles a chat command sent by a player.
+type ChatHandler func(c *ChatContext) error
+
+// ChatCommand is a command that players can run from the chat, like "!home".
+type ChatCommand struct {
+	Name    string
+	Aliases []string
+	// Usage describes the arguments of the command, like "<player>", and is
+	// replied to the players calling the command with less than MinArgs.
+	Usage       string
+	Description string
+	MinArgs     int
+	// Cooldown is the time a player has to wait between two calls.
+	Cooldown time.Duration
+	// Permission is the permission the player needs to run the command,
+	// none being needed if empty.
+	Permission string
+	Handler    ChatHandler
+}
+
+// ChatContext holds the chat command being run by a player.
+type ChatContext struct {
+	Sender  string
+	Command *ChatCommand
+	Args    []string
+	// Message is the raw chat message, prefix included.
+	Message string
+	tell    func(target Target, msg string) error
+}
+
+// Reply answers the player that sent the command, using 'Tell'.
+func (c *ChatContext) Reply(msg string) error {
+	return c.tell(PlayerName(c.Sender), msg)
+}
+
+// Replyf formats and answers the player that sent the command.
+func (c *ChatContext) Replyf(format string, a ...interface{}) error {
+	return c.Reply(fmt.Sprintf(format, a...))
+}
+
+// PermissionChecker checks whether a player holds a permission.
+type PermissionChecker interface {
+	HasPermission(player, permission string) bool
+}
+
+// PermissionFunc adapts a func to the PermissionChecker interface.
+type PermissionFunc func(player, permission string) bool
+
+// HasPermission calls f(player, permission).
+func (f PermissionFunc) HasPermission(player, permission string) bool {
+	return f(player, permission)
+}
+
+// OpPermission is the permission granted to the operators by OpRoles.
+const OpPermission = "op"
+
+// Roles is a PermissionChecker mapping the player names to their roles, a
+// player holding a permission if one of its roles is named after it.
+type Roles map[string][]string
+
+// HasPermission returns whether one of the player roles is the permission.
+func (r Roles) HasPermission(player, permission string) bool {
+	for _, role := range r[player] {
+		if role == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// OpRoles returns the Roles granting the OpPermission to the given
+// operators.
+func OpRoles(ops ...string) Roles {
+	r := Roles{}
+	for _, op := range ops {
+		r[op] = append(r[op], OpPermission)
+	}
+	return r
+}
+
+// ChatRouter dispatches the chat messages starting with a prefix, like
+// "!tp player1", to the chat command registered with that name. A "help"
+// command listing the commands available to the sender is registered by
+// default.
+type ChatRouter struct {
+	prefix      string
+	tell        func(target Target, msg string) error
+	mu          sync.Mutex
+	commands    map[string]*ChatCommand
+	permissions PermissionChecker
+	cooldowns   map[string]time.Time
+	now         func() time.Time
+}
+
+// NewChatRouter returns a ChatRouter for the commands prefixed by the given
+// prefix, replying to the players with the wrapper 'Tell'.
+func NewChatRouter(w *Wrapper, prefix string) *ChatRouter {
+	return newChatRouter(w.Tell, prefix)
+}
+
+func newChatRouter(tell func(target Target, msg string) error, prefix string) *ChatRouter {
+	r := &ChatRouter{
+		prefix:    prefix,
+		tell:      tell,
+		commands:  map[string]*ChatCommand{},
+		cooldowns: map[string]time.Time{},
+		now:       time.Now,
+	}
+	r.Handle(ChatCommand{
+		Name:        "help",
+		Usage:       "[command]",
+		Description: "Lists the commands",
+		Handler:     r.handleHelp,
+	})
+	return r
+}
+
+// SetPermissions sets the checker of the commands permission. Commands
+// requiring a permission are denied to everyone until it is set.
+func (r *ChatRouter) SetPermissions(p PermissionChecker) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.permissions = p
+}
+
+// Handle registers a chat command, replacing the default "help" command
+// if named so.
+func (r *ChatRouter) Handle(cmd ChatCommand) error {
+	if cmd.Name == "" || cmd.Handler == nil {
+		return ErrChatCommandInvalid
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	names := append([]string{cmd.Name}, cmd.Aliases...)
+	for _, n := range names {
+		if c, ok := r.commands[strings.ToLower(n)]; ok && c.Name != "help" {
+			return ErrChatCommandExists
+		}
+	}
+	for _, n := range names {
+		r.commands[strings.ToLower(n)] = &cmd
+	}
+	return nil
+}
+
+// Dispatch runs the chat command of a player chat event. It returns false
+// if the event is not a chat command, and the error of the command handler.
+// The players are replied to when the command is unknown, on cooldown,
+// denied or called with too few arguments.
+func (r *ChatRouter) Dispatch(ev events.GameEvent) (bool, error) {
+	if ev.Name != events.PlayerSay {
+		return false, nil
+	}
+	msg := ev.Data["player_message"]
+	if !strings.HasPrefix(msg, r.prefix) {
+		return false, nil
+	}
+	args := splitChatArgs(strings.TrimPrefix(msg, r.prefix))
+	if len(args) == 0 {
+		return false, nil
+	}
+
+	sender := ev.Data["player_name"]
+	c := &ChatContext{
+		Sender:  sender,
+		Args:    args[1:],
+		Message: msg,
+		tell:    r.tell,
+	}
+
+	r.mu.Lock()
+	cmd, ok := r.commands[strings.ToLower(args[0])]
+	if !ok {
+		r.mu.Unlock()
+		return true, c.Replyf("Unknown command, see %shelp", r.prefix)
+	}
+	c.Command = cmd
+	if !r.allowed(sender, cmd) {
+		r.mu.Unlock()
+		return true, c.Reply("You do not have the permission to use this command")
+	}
+	if len(c.Args) < cmd.MinArgs {
+		r.mu.Unlock()
+		return true, c.Replyf("Usage: %s", r.usage(cmd))
+	}
+	key := cmd.Name + "\x00" + sender
+	now := r.now()
+	if until, ok := r.cooldowns[key]; ok && now.Before(until) {
+		r.mu.Unlock()
+		wait := until.Sub(now).Round(time.Second)
+		if wait < time.Second {
+			wait = time.Second
+		}
+		return true, c.Replyf("Wait %s before using %s%s again", wait, r.prefix, cmd.Name)
+	}
+	if cmd.Cooldown > 0 {
+		r.cooldowns[key] = now.Add(cmd.Cooldown)
+	}
+	r.mu.Unlock()
+
+	return true, cmd.Handler(c)
+}
+
+// Help returns the help lines of the commands available to a player.
+func (r *ChatRouter) Help(player string) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cmds := []*ChatCommand{}
+	for n, cmd := range r.commands {
+		if n == strings.ToLower(cmd.Name) && r.allowed(player, cmd) {
+			cmds = append(cmds, cmd)
+		}
+	}
+	sort.Slice(cmds, func(i, j int) bool {
+		return cmds[i].Name < cmds[j].Name
+	})
+
+	lines := make([]string, len(cmds))
+	for i, cmd := range cmds {
+		lines[i] = r.usage(cmd)
+		if cmd.Description != "" {
+			lines[i] += " - " + cmd.Description
+		}
+	}
+	return lines
+}
+
+func (r *ChatRouter) handleHelp(c *ChatContext) error {
+	if len(c.Args) > 0 {
+		r.mu.Lock()
+		cmd, ok := r.commands[strings.ToLower(strings.TrimPrefix(c.Args[0], r.prefix))]
+		allowed := ok && r.allowed(c.Sender, cmd)
+		r.mu.Unlock()
+		if !allowed {
+			return c.Replyf("Unknown command: %s", c.Args[0])
+		}
+		line := r.usage(cmd)
+		if len(cmd.Aliases) > 0 {
+			line += fmt.Sprintf(" (aliases: %s)", strings.Join(cmd.Aliases, ", "))
+		}
+		if cmd.Description != "" {
+			line += " - " + cmd.Description
+		}
+		return c.Reply(line)
+	}
+	for _, line := range r.Help(c.Sender) {
+		if err := c.Reply(line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *ChatRouter) allowed(player string, cmd *ChatCommand) bool {
+	if cmd.Permission == "" {
+		return true
+	}
+	return r.permissions != nil && r.permissions.HasPermission(player, cmd.Permission)
+}
+
+func (r *ChatRouter) usage(cmd *ChatCommand) string {
+	if cmd.Usage == "" {
+		return r.prefix + cmd.Name
+	}
+	return r.prefix + cmd.Name + " " + cmd.Usage
+}
+
+// splitChatArgs splits a chat command on spaces, keeping the double quoted
+// arguments whole: `tp "my home"` gives ["tp", "my home"].
+func splitChatArgs(s string) []string {
+	args := []string{}
+	var b strings.Builder
+	quoted, inArg := false, false
+	for _, r := range s {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			inArg = true
+		case unicode.IsSpace(r) && !quoted:
+			if inArg {
+				args = append(args, b.String())
+				b.Reset()
+				inArg = false
+			}
+		default:
+			b.WriteRune(r)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, b.String())
+	}
+	return args
+}
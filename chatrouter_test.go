@@ -0,0 +1,139 @@
+package wrapper
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/wlwanpan/minecraft-wrapper/events"
+)
+
+type testTeller struct {
+	replies []string
+}
+
+func (tt *testTeller) tell(target Target, msg string) error {
+	tt.replies = append(tt.replies, target.String()+": "+msg)
+	return nil
+}
+
+func chatEvent(player, msg string) events.GameEvent {
+	ev := events.NewGameEvent(events.PlayerSay)
+	ev.Data = map[string]string{
+		"player_name":    player,
+		"player_message": msg,
+	}
+	return ev
+}
+
+func TestChatRouterDispatch(t *testing.T) {
+	teller := &testTeller{}
+	r := newChatRouter(teller.tell, "!")
+	now := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { return now }
+	r.SetPermissions(OpRoles("admin"))
+
+	var called []string
+	err := r.Handle(ChatCommand{
+		Name:        "tp",
+		Aliases:     []string{"teleport"},
+		Usage:       "<player>",
+		Description: "Teleports to a player",
+		MinArgs:     1,
+		Cooldown:    30 * time.Second,
+		Handler: func(c *ChatContext) error {
+			called = append(called, c.Sender+" "+c.Args[0])
+			return c.Replyf("Teleporting to %s", c.Args[0])
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Handle(ChatCommand{Name: "TP", Handler: func(c *ChatContext) error { return nil }}); err != ErrChatCommandExists {
+		t.Errorf("duplicate command should fail, got %v", err)
+	}
+	r.Handle(ChatCommand{
+		Name:        "stop",
+		Description: "Stops the server",
+		Permission:  OpPermission,
+		Handler: func(c *ChatContext) error {
+			called = append(called, c.Sender+" stop")
+			return nil
+		},
+	})
+
+	dispatch := func(player, msg string) bool {
+		ok, err := r.Dispatch(chatEvent(player, msg))
+		if err != nil {
+			t.Errorf("%s: %s", msg, err)
+		}
+		return ok
+	}
+
+	if dispatch("player1", "hello !tp") {
+		t.Error("regular chat messages should not be dispatched")
+	}
+	dispatch("player1", "!tp")
+	dispatch("player1", "!Teleport \"player 2\"")
+	dispatch("player1", "!tp player3")
+	now = now.Add(31 * time.Second)
+	dispatch("player1", "!tp player3")
+	dispatch("player1", "!stop")
+	dispatch("admin", "!stop")
+	dispatch("player1", "!home")
+
+	expectedCalls := []string{"player1 player 2", "player1 player3", "admin stop"}
+	if !reflect.DeepEqual(called, expectedCalls) {
+		t.Errorf("wrong handlers calls:\nactual  : %v\nexpected: %v", called, expectedCalls)
+	}
+	expectedReplies := []string{
+		"player1: Usage: !tp <player>",
+		"player1: Teleporting to player 2",
+		"player1: Wait 30s before using !tp again",
+		"player1: Teleporting to player3",
+		"player1: You do not have the permission to use this command",
+		"player1: Unknown command, see !help",
+	}
+	if !reflect.DeepEqual(teller.replies, expectedReplies) {
+		t.Errorf("wrong replies:\nactual  : %v\nexpected: %v", teller.replies, expectedReplies)
+	}
+}
+
+func TestChatRouterHelp(t *testing.T) {
+	teller := &testTeller{}
+	r := newChatRouter(teller.tell, "!")
+	r.SetPermissions(Roles{"mod1": {"moderator"}})
+	r.Handle(ChatCommand{Name: "seed", Description: "Shows the world seed", Handler: func(c *ChatContext) error { return nil }})
+	r.Handle(ChatCommand{Name: "kick", Aliases: []string{"k"}, Usage: "<player>", Permission: "moderator", Handler: func(c *ChatContext) error { return nil }})
+
+	expected := []string{"!help [command] - Lists the commands", "!seed - Shows the world seed"}
+	if help := r.Help("player1"); !reflect.DeepEqual(help, expected) {
+		t.Errorf("wrong help for player1: %v", help)
+	}
+	expected = []string{"!help [command] - Lists the commands", "!kick <player>", "!seed - Shows the world seed"}
+	if help := r.Help("mod1"); !reflect.DeepEqual(help, expected) {
+		t.Errorf("wrong help for mod1: %v", help)
+	}
+
+	r.Dispatch(chatEvent("mod1", "!help !kick"))
+	r.Dispatch(chatEvent("player1", "!help kick"))
+	expected = []string{"mod1: !kick <player> (aliases: k)", "player1: Unknown command: kick"}
+	if !reflect.DeepEqual(teller.replies, expected) {
+		t.Errorf("wrong help replies: %v", teller.replies)
+	}
+}
+
+func TestSplitChatArgs(t *testing.T) {
+	tests := map[string][]string{
+		"":                        {},
+		"home":                    {"home"},
+		"  tp   player1 ":         {"tp", "player1"},
+		`sethome "my base" north`: {"sethome", "my base", "north"},
+		`msg ""`:                  {"msg", ""},
+	}
+	for in, expected := range tests {
+		if args := splitChatArgs(in); !reflect.DeepEqual(args, expected) {
+			t.Errorf("%q: wrong args %q, expected %q", in, args, expected)
+		}
+	}
+}
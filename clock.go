@@ -3,6 +3,7 @@ package wrapper
 import (
 	"context"
 	"math"
+	"sync"
 	"time"
 )
 
@@ -24,6 +25,7 @@ const (
 // clock represents an internal wrapper clock, meant to be always in sync
 // with the running game server clock (sync clock.Tick and game server tick).
 type clock struct {
+	mu         sync.Mutex
 	ticker     *time.Ticker
 	syncTicker *time.Ticker
 	LastSync   time.Time
@@ -44,7 +46,9 @@ func (c *clock) start(ctx context.Context) {
 			case <-ctx.Done():
 				return
 			case <-c.ticker.C:
+				c.mu.Lock()
 				c.Tick += GameTickPerSecond
+				c.mu.Unlock()
 			}
 		}
 	}()
@@ -59,10 +63,20 @@ func (c *clock) requestSync() <-chan time.Time {
 }
 
 func (c *clock) resetLastSync() {
+	c.mu.Lock()
 	c.LastSync = time.Now()
+	c.mu.Unlock()
+}
+
+func (c *clock) tick() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.Tick
 }
 
 func (c *clock) syncTick(t int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	delay := time.Since(c.LastSync).Seconds()
 	delayRoundUp := int(math.Floor(delay))
 	tickOffset := delayRoundUp * GameTickPerSecond
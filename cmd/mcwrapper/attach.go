@@ -0,0 +1,151 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/peterh/liner"
+)
+
+// consoleCommands are the commands completed on the console, the ones of
+// the vanilla server.
+var consoleCommands = []string{
+	"advancement", "ban", "ban-ip", "banlist", "bossbar", "clear", "clone",
+	"data", "datapack", "debug", "defaultgamemode", "deop", "difficulty",
+	"effect", "enchant", "execute", "experience", "fill", "forceload",
+	"function", "gamemode", "gamerule", "give", "help", "kick", "kill",
+	"list", "locate", "loot", "me", "msg", "op", "pardon", "pardon-ip",
+	"particle", "playsound", "recipe", "reload", "save-all", "save-off",
+	"save-on", "say", "schedule", "scoreboard", "seed", "setblock",
+	"setidletimeout", "setworldspawn", "spawnpoint", "spectate",
+	"spreadplayers", "stop", "stopsound", "summon", "tag", "team",
+	"teleport", "tell", "tellraw", "time", "title", "tp", "trigger",
+	"weather", "whitelist", "worldborder", "xp",
+}
+
+// attach opens an interactive console of the running server: the console
+// lines are printed as logged, and the lines typed written as commands.
+func attach(args []string) error {
+	fs := flag.NewFlagSet("attach", flag.ExitOnError)
+	socket := socketFlag(fs)
+	history := fs.String("history", defaultHistory(), "path of the command history file, none if empty")
+	fs.Parse(args)
+
+	// The log stream takes over its connection, the commands go through
+	// another one.
+	logs, err := dial(*socket)
+	if err != nil {
+		return err
+	}
+	defer logs.Close()
+	lines, err := logs.Logs()
+	if err != nil {
+		return err
+	}
+	c, err := dial(*socket)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	detached := make(chan struct{})
+	go func() {
+		for l := range lines {
+			fmt.Println(l)
+		}
+		select {
+		case <-detached:
+		default:
+			fmt.Fprintln(os.Stderr, "mcwrapper: connection closed, press Ctrl-D to exit")
+		}
+	}()
+	defer close(detached)
+
+	ln := liner.NewLiner()
+	defer ln.Close()
+	ln.SetCtrlCAborts(true)
+	ln.SetTabCompletionStyle(liner.TabPrints)
+	ln.SetWordCompleter(func(line string, pos int) (string, []string, string) {
+		return completeLine(line, pos, func() []string {
+			ps, err := c.Players()
+			if err != nil {
+				return nil
+			}
+			names := make([]string, len(ps))
+			for i, p := range ps {
+				names[i] = p.Name
+			}
+			return names
+		})
+	})
+	if *history != "" {
+		if f, err := os.Open(*history); err == nil {
+			ln.ReadHistory(f)
+			f.Close()
+		}
+	}
+
+	for {
+		line, err := ln.Prompt("> ")
+		if err == liner.ErrPromptAborted || err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		ln.AppendHistory(line)
+		if err := c.Send(strings.TrimPrefix(line, "/")); err != nil {
+			fmt.Fprintf(os.Stderr, "mcwrapper: %v\n", err)
+		}
+	}
+
+	if *history == "" {
+		return nil
+	}
+	f, err := os.Create(*history)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = ln.WriteHistory(f)
+	return err
+}
+
+func defaultHistory() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".mcwrapper_history")
+}
+
+// completeLine completes the word under the cursor, the first one being a
+// command and the others player names. As given by liner, pos is the index
+// of the cursor in runes.
+func completeLine(line string, pos int, players func() []string) (string, []string, string) {
+	runes := []rune(line)
+	head, tail := string(runes[:pos]), string(runes[pos:])
+	start := strings.LastIndex(head, " ") + 1
+	word := head[start:]
+	candidates := consoleCommands
+	if start > 0 {
+		candidates = players()
+	} else if strings.HasPrefix(word, "/") {
+		start, word = 1, word[1:]
+	}
+	completions := []string{}
+	for _, c := range candidates {
+		if strings.HasPrefix(c, word) {
+			completions = append(completions, c+" ")
+		}
+	}
+	return head[:start], completions, tail
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompleteLine(t *testing.T) {
+	players := func() []string { return []string{"player1", "player2", "admin"} }
+	tests := []struct {
+		line        string
+		pos         int
+		head        string
+		completions []string
+		tail        string
+	}{
+		{"sa", 2, "", []string{"save-all ", "save-off ", "save-on ", "say "}, ""},
+		{"/se", 3, "/", []string{"seed ", "setblock ", "setidletimeout ", "setworldspawn "}, ""},
+		{"kick pl", 7, "kick ", []string{"player1 ", "player2 "}, ""},
+		{"tp pl admin", 5, "tp ", []string{"player1 ", "player2 "}, " admin"},
+		{"say héhé pl", 11, "say héhé ", []string{"player1 ", "player2 "}, ""},
+		{"unknown", 7, "", []string{}, ""},
+	}
+	for _, tt := range tests {
+		head, completions, tail := completeLine(tt.line, tt.pos, players)
+		if head != tt.head || !reflect.DeepEqual(completions, tt.completions) || tail != tt.tail {
+			t.Errorf("%q at %d: wrong completion: actual=(%q, %q, %q), expected=(%q, %q, %q)",
+				tt.line, tt.pos, head, completions, tail, tt.head, tt.completions, tt.tail)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// send runs a console command on the running server, printing its output.
+func send(args []string) error {
+	fs := flag.NewFlagSet("send", flag.ExitOnError)
+	socket := socketFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: mcwrapper send [flags] <command>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	c, err := dial(*socket)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	lines, err := c.Command(strings.TrimPrefix(strings.Join(fs.Args(), " "), "/"))
+	if err != nil {
+		return err
+	}
+	for _, l := range lines {
+		fmt.Println(l)
+	}
+	return nil
+}
+
+func status(args []string) error {
+	fs := flag.NewFlagSet("status", flag.ExitOnError)
+	socket := socketFlag(fs)
+	fs.Parse(args)
+
+	c, err := dial(*socket)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	st, err := c.Status()
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "state:\t%s\n", st.State)
+	fmt.Fprintf(tw, "version:\t%s\n", st.Version)
+	fmt.Fprintf(tw, "players:\t%d\n", st.Players)
+	fmt.Fprintf(tw, "tick:\t%d\n", st.Tick)
+	return tw.Flush()
+}
+
+func players(args []string) error {
+	fs := flag.NewFlagSet("players", flag.ExitOnError)
+	socket := socketFlag(fs)
+	fs.Parse(args)
+
+	c, err := dial(*socket)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	ps, err := c.Players()
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tUUID\tIP\tONLINE")
+	for _, p := range ps {
+		online := "-"
+		if !p.LoginTime.IsZero() {
+			online = time.Since(p.LoginTime).Round(time.Second).String()
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", p.Name, p.UUID, p.IP, online)
+	}
+	return tw.Flush()
+}
+
+func backup(args []string) error {
+	fs := flag.NewFlagSet("backup", flag.ExitOnError)
+	socket := socketFlag(fs)
+	fs.Parse(args)
+
+	c, err := dial(*socket)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	path, err := c.Backup()
+	if err != nil {
+		return err
+	}
+	fmt.Println(path)
+	return nil
+}
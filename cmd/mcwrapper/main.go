@@ -0,0 +1,69 @@
+// Command mcwrapper runs a minecraft server from a config file, and controls
+// it from other processes over the Unix socket of the running instance.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	wrapper "github.com/wlwanpan/minecraft-wrapper"
+)
+
+const usage = `Usage: mcwrapper <command> [flags] [args]
+
+Commands:
+  run      start and supervise the server of a config file
+  attach   open an interactive console of the running server
+  send     run a console command, printing its output
+  status   print the state of the running server
+  players  list the players online
+  backup   back up the world of the running server
+
+Run 'mcwrapper <command> -h' for the flags of a command.
+`
+
+var commands = map[string]func(args []string) error{
+	"run":     run,
+	"attach":  attach,
+	"send":    send,
+	"status":  status,
+	"players": players,
+	"backup":  backup,
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("mcwrapper: ")
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "mcwrapper: unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+	if err := cmd(os.Args[2:]); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// socketFlag adds the flag of the control socket path, defaulting to the
+// MCWRAPPER_SOCKET environment variable, or to "mcwrapper.sock".
+func socketFlag(fs *flag.FlagSet) *string {
+	path := os.Getenv("MCWRAPPER_SOCKET")
+	if path == "" {
+		path = "mcwrapper.sock"
+	}
+	return fs.String("socket", path, "path of the control socket of the running instance")
+}
+
+func dial(path string) (*wrapper.ControlClient, error) {
+	c, err := wrapper.DialControl(path)
+	if err != nil {
+		return nil, fmt.Errorf("no running instance at %s: %w", path, err)
+	}
+	return c, nil
+}
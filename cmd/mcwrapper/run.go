@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+
+	wrapper "github.com/wlwanpan/minecraft-wrapper"
+)
+
+// run starts the server of the config and supervises it until interrupted,
+// serving the control socket meanwhile.
+func run(args []string) error {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	config := fs.String("config", "mcwrapper.yml", "path of the server config file")
+	socket := socketFlag(fs)
+	perm := fs.String("socket-perm", fmt.Sprintf("%#o", wrapper.DefaultControlPerm), "permissions of the control socket, giving access to the server")
+	fs.Parse(args)
+	mode, err := strconv.ParseUint(*perm, 8, 32)
+	if err != nil {
+		return fmt.Errorf("invalid socket permissions %q", *perm)
+	}
+
+	cfg, err := wrapper.LoadServerConfig(*config)
+	if err != nil {
+		return err
+	}
+	l, err := wrapper.ListenControl(*socket, os.FileMode(mode))
+	if err != nil {
+		return err
+	}
+
+	sup := wrapper.NewSupervisor(*cfg)
+	sup.Logf = log.Printf
+	sup.Output = os.Stdout
+	cs := sup.ControlServer()
+	go cs.Serve(l)
+	defer cs.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigs
+		log.Print("stopping server")
+		cancel()
+	}()
+	return sup.Run(ctx)
+}
@@ -0,0 +1,513 @@
+package wrapper
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrInvalidConfig is returned when loading a config file holding values
+// the wrapper can not run with.
+var ErrInvalidConfig = errors.New("invalid config")
+
+// ConfigError is an error of a config file, at the position of the faulty
+// value. It wraps ErrInvalidConfig.
+type ConfigError struct {
+	File string
+	// Line and Column are the position of the faulty value, Column being 0
+	// when unknown.
+	Line   int
+	Column int
+	Msg    string
+}
+
+func (e *ConfigError) Error() string {
+	pos := fmt.Sprintf("%s:%d", e.File, e.Line)
+	if e.Column > 0 {
+		pos = fmt.Sprintf("%s:%d", pos, e.Column)
+	}
+	return fmt.Sprintf("%s: %s: %s", pos, ErrInvalidConfig, e.Msg)
+}
+
+// Unwrap returns ErrInvalidConfig.
+func (e *ConfigError) Unwrap() error {
+	return ErrInvalidConfig
+}
+
+func configErrorAt(n *yaml.Node, format string, a ...interface{}) *ConfigError {
+	return &ConfigError{Line: n.Line, Column: n.Column, Msg: fmt.Sprintf(format, a...)}
+}
+
+// HeapSize is a java heap size in MB. In a config file, it is either a
+// count of MB or a size with a unit, like "512M" or "2G".
+type HeapSize int
+
+var heapSizeRegex = regexp.MustCompile(`^(\d+)([KkMmGg]?)$`)
+
+// UnmarshalYAML decodes the heap size from a config file.
+func (h *HeapSize) UnmarshalYAML(n *yaml.Node) error {
+	matches := heapSizeRegex.FindStringSubmatch(n.Value)
+	if n.Kind != yaml.ScalarNode || matches == nil {
+		return configErrorAt(n, "invalid heap size %q", n.Value)
+	}
+	size, err := strconv.Atoi(matches[1])
+	if err != nil {
+		return configErrorAt(n, "invalid heap size %q", n.Value)
+	}
+	switch strings.ToUpper(matches[2]) {
+	case "K":
+		size /= 1024
+	case "G":
+		size *= 1024
+	}
+	*h = HeapSize(size)
+	return nil
+}
+
+// RestartMode is when a server is restarted after stopping.
+type RestartMode string
+
+const (
+	RestartNever RestartMode = "never"
+	// RestartOnFailure restarts the servers stopping without a 'stop'
+	// command, like on a crash.
+	RestartOnFailure RestartMode = "on-failure"
+	RestartAlways    RestartMode = "always"
+)
+
+// RestartPolicy is how a server is restarted after stopping.
+type RestartPolicy struct {
+	// Mode is "never" if empty.
+	Mode RestartMode `yaml:"mode"`
+	// MaxRestarts is the count of restarts in a row before giving up,
+	// unlimited if 0.
+	MaxRestarts int `yaml:"max_restarts"`
+	// Delay is the time waited before restarting.
+	Delay time.Duration `yaml:"delay"`
+}
+
+// BackupConfig is the backup schedule of a server.
+type BackupConfig struct {
+	// Interval is the time between two backups, none being made if 0.
+	Interval time.Duration `yaml:"interval"`
+	// Dir is the directory the backups are written to.
+	Dir string `yaml:"dir"`
+	// Keep is the count of backups kept, all of them if 0.
+	Keep int `yaml:"keep"`
+}
+
+// TimeoutsConfig bounds the wait on a server, no timeout applying if 0.
+type TimeoutsConfig struct {
+	Start time.Duration `yaml:"start"`
+	Stop  time.Duration `yaml:"stop"`
+}
+
+// IntegrationsConfig enables the optional features of the wrapper, the
+// paths being relative to the server directory.
+type IntegrationsConfig struct {
+	// Sessions is the file the play sessions are saved to, see
+	// 'SessionTracker'. Sessions are not tracked if empty.
+	Sessions string `yaml:"sessions"`
+	// Moderation is the file the temporary bans and mutes are saved to,
+	// see 'Moderation'. The moderation is disabled if empty.
+	Moderation string `yaml:"moderation"`
+}
+
+// ServerConfig is the config of a wrapped server. In a config file, values
+// can refer to environment variables as "${VAR}", or "${VAR:-default}" to
+// default the unset ones, "$$" being a literal "$".
+// The restart policy and backup schedule are left to the process
+// supervising the wrapper, a Wrapper running its server once.
+type ServerConfig struct {
+	// Name identifies the server in a Manager.
+	Name string `yaml:"name"`
+	// Dir is the working directory of the server, holding its world and
+	// server files. When loaded from a file, a relative Dir is relative to
+	// the file directory.
+	Dir string `yaml:"dir"`
+	// Jar is the path to the server jar, relative to Dir.
+	Jar string `yaml:"jar"`
+	// Java is the java executable, "java" if empty.
+	Java        string   `yaml:"java"`
+	InitialHeap HeapSize `yaml:"initial_heap"`
+	MaxHeap     HeapSize `yaml:"max_heap"`
+	JVMFlags    []string `yaml:"jvm_flags"`
+	// LogProfile is the name of the LogProfile decoding the server logs,
+	// "auto" if empty.
+	LogProfile   string             `yaml:"log_profile"`
+	Restart      RestartPolicy      `yaml:"restart"`
+	Backup       BackupConfig       `yaml:"backup"`
+	Timeouts     TimeoutsConfig     `yaml:"timeouts"`
+	Integrations IntegrationsConfig `yaml:"integrations"`
+}
+
+// ManagerConfig is the config of a Manager and of the servers it runs.
+type ManagerConfig struct {
+	// Concurrency is the count of servers started or stopped at once, all
+	// of them if 0.
+	Concurrency int            `yaml:"concurrency"`
+	Servers     []ServerConfig `yaml:"servers"`
+}
+
+// configIssue is a misconfigured value, the field being the path of its
+// YAML key, like "restart.mode".
+type configIssue struct {
+	field string
+	msg   string
+}
+
+// Validate returns ErrInvalidConfig if the server can not be run with the
+// config.
+func (c ServerConfig) Validate() error {
+	if issue := c.validate(); issue != nil {
+		return fmt.Errorf("%w: %s: %s", ErrInvalidConfig, issue.field, issue.msg)
+	}
+	return nil
+}
+
+func (c ServerConfig) validate() *configIssue {
+	switch {
+	case c.Jar == "":
+		return &configIssue{"jar", "missing server jar"}
+	case c.InitialHeap <= 0:
+		return &configIssue{"initial_heap", "heap size should be positive"}
+	case c.MaxHeap <= 0:
+		return &configIssue{"max_heap", "heap size should be positive"}
+	case c.InitialHeap > c.MaxHeap:
+		return &configIssue{"initial_heap", fmt.Sprintf("initial heap %dM above max heap %dM", c.InitialHeap, c.MaxHeap)}
+	}
+	if _, ok := LogProfiles[c.logProfile()]; !ok {
+		return &configIssue{"log_profile", fmt.Sprintf("unknown log profile %q", c.LogProfile)}
+	}
+	switch c.Restart.Mode {
+	case "", RestartNever, RestartOnFailure, RestartAlways:
+	default:
+		return &configIssue{"restart.mode", fmt.Sprintf("unknown restart mode %q", c.Restart.Mode)}
+	}
+	switch {
+	case c.Restart.MaxRestarts < 0:
+		return &configIssue{"restart.max_restarts", "should not be negative"}
+	case c.Restart.Delay < 0:
+		return &configIssue{"restart.delay", "should not be negative"}
+	case c.Backup.Interval < 0:
+		return &configIssue{"backup.interval", "should not be negative"}
+	case c.Backup.Interval > 0 && c.Backup.Dir == "":
+		return &configIssue{"backup.dir", "missing backup directory"}
+	case c.Backup.Keep < 0:
+		return &configIssue{"backup.keep", "should not be negative"}
+	case c.Timeouts.Start < 0:
+		return &configIssue{"timeouts.start", "should not be negative"}
+	case c.Timeouts.Stop < 0:
+		return &configIssue{"timeouts.stop", "should not be negative"}
+	}
+	return nil
+}
+
+// Validate returns ErrInvalidConfig if a server is misconfigured, or if
+// two servers share a name.
+func (c *ManagerConfig) Validate() error {
+	if issue, i := c.validate(); issue != nil {
+		if i < 0 {
+			return fmt.Errorf("%w: %s: %s", ErrInvalidConfig, issue.field, issue.msg)
+		}
+		return fmt.Errorf("%w: server %d: %s: %s", ErrInvalidConfig, i, issue.field, issue.msg)
+	}
+	return nil
+}
+
+// validate returns the first issue of the config and the index of the
+// server it is about, -1 for the Manager ones.
+func (c *ManagerConfig) validate() (*configIssue, int) {
+	if c.Concurrency < 0 {
+		return &configIssue{"concurrency", "should not be negative"}, -1
+	}
+	seen := map[string]bool{}
+	for i, s := range c.Servers {
+		if s.Name == "" {
+			return &configIssue{"name", "missing server name"}, i
+		}
+		if seen[s.Name] {
+			return &configIssue{"name", fmt.Sprintf("duplicated server name %q", s.Name)}, i
+		}
+		seen[s.Name] = true
+		if issue := s.validate(); issue != nil {
+			return issue, i
+		}
+	}
+	return nil, -1
+}
+
+func (c ServerConfig) logProfile() string {
+	if c.LogProfile == "" {
+		return AutoLogProfile.Name
+	}
+	return c.LogProfile
+}
+
+func (c ServerConfig) java() string {
+	if c.Java == "" {
+		return "java"
+	}
+	return c.Java
+}
+
+// path returns a path of the config, relative to the server directory.
+func (c ServerConfig) path(p string) string {
+	if filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(c.Dir, p)
+}
+
+// NewConfiguredWrapper returns a Wrapper running the server of the config,
+// with its integrations enabled.
+func NewConfiguredWrapper(c ServerConfig) (*Wrapper, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	cmd := newJavaExec(c.java(), c.Jar, int(c.InitialHeap), int(c.MaxHeap), c.JVMFlags...)
+	cmd.cmd.Dir = c.Dir
+	wpr := NewWrapper(newConsole(cmd), NewLogParser(LogProfiles[c.logProfile()]))
+
+	if c.Integrations.Sessions != "" {
+		st, err := NewSessionTracker(NewJSONFileStore(c.path(c.Integrations.Sessions)))
+		if err != nil {
+			return nil, err
+		}
+		wpr.TrackSessions(st)
+	}
+	if c.Integrations.Moderation != "" {
+		m, err := NewModeration(NewJSONModerationStore(c.path(c.Integrations.Moderation)), DefaultMutePolicy)
+		if err != nil {
+			return nil, err
+		}
+		wpr.Moderate(m)
+	}
+	return wpr, nil
+}
+
+// LoadServerConfig reads and validates a YAML server config file.
+func LoadServerConfig(path string) (*ServerConfig, error) {
+	root, err := loadConfigNode(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &ServerConfig{}
+	if err := decodeConfigNode(path, root, cfg); err != nil {
+		return nil, err
+	}
+	if issue := cfg.validate(); issue != nil {
+		return nil, issueError(path, root, issue)
+	}
+	cfg.Dir = configDir(path, cfg.Dir)
+	return cfg, nil
+}
+
+// LoadManagerConfig reads and validates a YAML Manager config file.
+func LoadManagerConfig(path string) (*ManagerConfig, error) {
+	root, err := loadConfigNode(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &ManagerConfig{}
+	if err := decodeConfigNode(path, root, cfg); err != nil {
+		return nil, err
+	}
+	if issue, i := cfg.validate(); issue != nil {
+		n := root
+		if i >= 0 {
+			n = fieldNode(root, "servers").Content[i]
+		}
+		return nil, issueError(path, n, issue)
+	}
+	for i := range cfg.Servers {
+		cfg.Servers[i].Dir = configDir(path, cfg.Servers[i].Dir)
+	}
+	return cfg, nil
+}
+
+// configDir returns the server directory, relative to the config file.
+func configDir(path, dir string) string {
+	if filepath.IsAbs(dir) {
+		return dir
+	}
+	return filepath.Join(filepath.Dir(path), dir)
+}
+
+// yamlLineRegex matches the position of the yaml errors, "line 3: ...".
+var yamlLineRegex = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+// loadConfigNode parses a config file and substitutes the environment
+// variables, returning its root mapping.
+func loadConfigNode(path string) (*yaml.Node, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	doc := &yaml.Node{}
+	if err := yaml.NewDecoder(bytes.NewReader(data)).Decode(doc); err != nil {
+		return nil, yamlError(path, err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, &ConfigError{File: path, Line: 1, Msg: "config should be a mapping"}
+	}
+	root := doc.Content[0]
+	if err := expandEnv(root); err != nil {
+		err.File = path
+		return nil, err
+	}
+	return root, nil
+}
+
+// decodeConfigNode decodes a config, rejecting the unknown fields.
+func decodeConfigNode(path string, root *yaml.Node, cfg interface{}) error {
+	if err := checkFields(root, reflect.TypeOf(cfg).Elem()); err != nil {
+		err.File = path
+		return err
+	}
+	if err := root.Decode(cfg); err != nil {
+		var ce *ConfigError
+		if errors.As(err, &ce) {
+			ce.File = path
+			return ce
+		}
+		return yamlError(path, err)
+	}
+	return nil
+}
+
+// yamlError returns the error of the yaml package as a ConfigError, the
+// first one only for the type errors.
+func yamlError(path string, err error) error {
+	msg := err.Error()
+	if te, ok := err.(*yaml.TypeError); ok && len(te.Errors) > 0 {
+		msg = te.Errors[0]
+	}
+	matches := yamlLineRegex.FindStringSubmatch(msg)
+	if matches == nil {
+		return fmt.Errorf("%s: %w: %v", path, ErrInvalidConfig, err)
+	}
+	line, _ := strconv.Atoi(matches[1])
+	return &ConfigError{File: path, Line: line, Msg: matches[2]}
+}
+
+func issueError(path string, n *yaml.Node, issue *configIssue) error {
+	err := configErrorAt(fieldNode(n, issue.field), "%s: %s", issue.field, issue.msg)
+	err.File = path
+	return err
+}
+
+// fieldNode returns the value node of a dotted field path in a mapping, or
+// the deepest mapping found when the field is missing.
+func fieldNode(n *yaml.Node, field string) *yaml.Node {
+	for _, key := range strings.Split(field, ".") {
+		if n.Kind != yaml.MappingNode {
+			return n
+		}
+		found := false
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if n.Content[i].Value == key {
+				n, found = n.Content[i+1], true
+				break
+			}
+		}
+		if !found {
+			return n
+		}
+	}
+	return n
+}
+
+// checkFields returns an error on the first mapping key not matching a
+// field of the struct decoded.
+func checkFields(n *yaml.Node, t reflect.Type) *ConfigError {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case n.Kind == yaml.SequenceNode && t.Kind() == reflect.Slice:
+		for _, item := range n.Content {
+			if err := checkFields(item, t.Elem()); err != nil {
+				return err
+			}
+		}
+	case n.Kind == yaml.MappingNode && t.Kind() == reflect.Struct:
+		fields := map[string]reflect.Type{}
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name := strings.Split(f.Tag.Get("yaml"), ",")[0]
+			if name == "" {
+				name = strings.ToLower(f.Name)
+			}
+			fields[name] = f.Type
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key := n.Content[i]
+			ft, ok := fields[key.Value]
+			if !ok {
+				return configErrorAt(key, "unknown field %q", key.Value)
+			}
+			if err := checkFields(n.Content[i+1], ft); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// envRegex matches the environment variables of a config value, "${VAR}"
+// or "${VAR:-default}", and the escaped "$$".
+var envRegex = regexp.MustCompile(`\$\$|\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// expandEnv substitutes the environment variables of the scalar values,
+// returning an error on the unset ones with no default.
+func expandEnv(n *yaml.Node) *ConfigError {
+	if n.Kind == yaml.ScalarNode {
+		if !strings.Contains(n.Value, "$") {
+			return nil
+		}
+		var missing string
+		n.Value = envRegex.ReplaceAllStringFunc(n.Value, func(m string) string {
+			if m == "$$" {
+				return "$"
+			}
+			sub := envRegex.FindStringSubmatch(m)
+			if v, ok := os.LookupEnv(sub[1]); ok {
+				return v
+			}
+			if sub[2] == "" && missing == "" {
+				missing = sub[1]
+			}
+			return sub[3]
+		})
+		if missing != "" {
+			return configErrorAt(n, "environment variable %s is not set", missing)
+		}
+		if n.Style == 0 {
+			// The tag of a plain scalar was resolved before the
+			// substitution, resolve it again so '${N}' can be a number.
+			n.Tag = ""
+		}
+		return nil
+	}
+	for i, c := range n.Content {
+		// Keys are left as is.
+		if n.Kind == yaml.MappingNode && i%2 == 0 {
+			continue
+		}
+		if err := expandEnv(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package wrapper
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadManagerConfig(t *testing.T) {
+	cfg, err := LoadManagerConfig("testdata/config/manager.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &ManagerConfig{
+		Concurrency: 2,
+		Servers: []ServerConfig{
+			{
+				Name:        "lobby",
+				Dir:         "/srv/minecraft/lobby",
+				Jar:         "server.jar",
+				InitialHeap: 1024,
+				MaxHeap:     2048,
+				JVMFlags:    []string{"-XX:+UseG1GC"},
+				LogProfile:  "vanilla",
+			},
+			{
+				Name:        "survival",
+				Dir:         "/srv/minecraft/survival",
+				Jar:         "paper.jar",
+				InitialHeap: 2048,
+				MaxHeap:     4096,
+				LogProfile:  "paper",
+			},
+		},
+	}
+	if !reflect.DeepEqual(cfg, expected) {
+		t.Errorf("wrong config:\nactual  : %+v\nexpected: %+v", cfg, expected)
+	}
+}
+
+func TestLoadServerConfig(t *testing.T) {
+	os.Setenv("MCW_TEST_MAX_HEAP", "4096")
+	defer os.Unsetenv("MCW_TEST_MAX_HEAP")
+	cfg, err := LoadServerConfig("testdata/config/server.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &ServerConfig{
+		Name:        "survival",
+		Dir:         "/srv/minecraft/survival",
+		Jar:         "paper.jar",
+		Java:        "/usr/lib/jvm/java-17/bin/java",
+		InitialHeap: 1024,
+		MaxHeap:     4096,
+		JVMFlags:    []string{"-XX:+UseG1GC", "-Dmotd=$HOME"},
+		LogProfile:  "paper",
+		Restart:     RestartPolicy{Mode: RestartOnFailure, MaxRestarts: 3, Delay: 10 * time.Second},
+		Backup:      BackupConfig{Interval: 6 * time.Hour, Dir: "backups", Keep: 4},
+		Timeouts:    TimeoutsConfig{Start: 2 * time.Minute, Stop: 30 * time.Second},
+		Integrations: IntegrationsConfig{
+			Sessions:   "sessions.json",
+			Moderation: "moderation.json",
+		},
+	}
+	if !reflect.DeepEqual(cfg, expected) {
+		t.Errorf("wrong config:\nactual  : %+v\nexpected: %+v", cfg, expected)
+	}
+
+	// A relative directory is relative to the config file.
+	os.Setenv("MCW_TEST_DIR", "survival")
+	defer os.Unsetenv("MCW_TEST_DIR")
+	os.Setenv("MCW_TEST_MAX_RESTARTS", "5")
+	defer os.Unsetenv("MCW_TEST_MAX_RESTARTS")
+	cfg, err = LoadServerConfig("testdata/config/server.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dir := filepath.Join("testdata", "config", "survival"); cfg.Dir != dir {
+		t.Errorf("wrong server dir: actual=%s, expected=%s", cfg.Dir, dir)
+	}
+	if cfg.Restart.MaxRestarts != 5 {
+		t.Errorf("wrong max restarts: actual=%d, expected=5", cfg.Restart.MaxRestarts)
+	}
+}
+
+func TestLoadServerConfigErrors(t *testing.T) {
+	dir := testDir(t)
+
+	const valid = "jar: server.jar\ninitial_heap: 1G\nmax_heap: 1G\n"
+	tests := map[string]struct {
+		content string
+		// pos is the expected position of the error, "line:column".
+		pos string
+		msg string
+	}{
+		"unset env":     {valid + "java: ${MCW_TEST_UNSET}/java\n", "4:7", "MCW_TEST_UNSET is not set"},
+		"unknown field": {valid + "restart:\n  retries: 3\n", "5:3", `unknown field "retries"`},
+		"heap unit":     {"jar: server.jar\ninitial_heap: 1T\nmax_heap: 1G\n", "2:15", `invalid heap size "1T"`},
+		"restart mode":  {valid + "restart:\n  mode: sometimes\n", "5:9", "unknown restart mode"},
+		"backup dir":    {valid + "backup:\n  interval: 1h\n", "5:3", "backup.dir: missing backup directory"},
+		"duration":      {valid + "timeouts:\n  stop: soon\n", "5", "cannot unmarshal"},
+		"syntax":        {valid + "jvm_flags: [\n", "", "did not find expected node content"},
+	}
+	for name, test := range tests {
+		path := filepath.Join(dir, "server.yml")
+		if err := ioutil.WriteFile(path, []byte(test.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		_, err := LoadServerConfig(path)
+		if !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("%s: config should be invalid, got %v", name, err)
+			continue
+		}
+		if !strings.HasPrefix(err.Error(), path+":"+test.pos) || !strings.Contains(err.Error(), test.msg) {
+			t.Errorf("%s: wrong error %q, expected %s:%s ... %s", name, err, path, test.pos, test.msg)
+		}
+	}
+}
+
+func TestLoadManagerConfigInvalid(t *testing.T) {
+	dir := testDir(t)
+
+	tests := map[string]string{
+		"unknown field": "servers:\n  - name: lobby\n    jar: server.jar\n    heap: 1024\n",
+		"no jar":        "servers:\n  - name: lobby\n    initial_heap: 1024\n    max_heap: 1024\n",
+		"heap sizes":    "servers:\n  - name: lobby\n    jar: server.jar\n    initial_heap: 2048\n    max_heap: 1024\n",
+		"log profile":   "servers:\n  - name: lobby\n    jar: server.jar\n    initial_heap: 1024\n    max_heap: 1024\n    log_profile: bedrock\n",
+		"duplicated": "servers:\n  - name: lobby\n    jar: server.jar\n    initial_heap: 1024\n    max_heap: 1024\n" +
+			"  - name: lobby\n    jar: server.jar\n    initial_heap: 1024\n    max_heap: 1024\n",
+	}
+	for name, content := range tests {
+		path := filepath.Join(dir, "manager.yml")
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadManagerConfig(path); !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("%s: config should be invalid, got %v", name, err)
+		}
+	}
+}
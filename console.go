@@ -18,6 +18,14 @@ type defaultConsole struct {
 	stdin  *bufio.Writer
 }
 
+// NewDefaultConsole returns the Console used by 'NewDefaultWrapper', to be
+// paired with a custom LogParser in 'NewWrapper'. For example, to use a fixed
+// log profile: NewWrapper(NewDefaultConsole(server, 1024, 1024), NewLogParser(PaperLogProfile))
+// The optional jvmFlags are passed to java ahead of the '-jar' flag.
+func NewDefaultConsole(server string, initial, max int, jvmFlags ...string) Console {
+	return newConsole(javaExecCmd(server, initial, max, jvmFlags...))
+}
+
 func newConsole(cmd JavaExec) *defaultConsole {
 	c := &defaultConsole{
 		cmd: cmd,
@@ -46,5 +54,12 @@ func (c *defaultConsole) WriteCmd(cmd string) error {
 }
 
 func (c *defaultConsole) ReadLine() (string, error) {
-	return c.stdout.ReadString('\n')
+	line, err := c.stdout.ReadString('\n')
+	if err != nil {
+		// The output is read to its end, the process can be reaped.
+		if j, ok := c.cmd.(interface{ wait() error }); ok {
+			j.wait()
+		}
+	}
+	return line, err
 }
@@ -4,6 +4,9 @@ import (
 	"bufio"
 	"io"
 	"os"
+	"sync"
+	"testing"
+	"time"
 )
 
 // testConsole provide a test console implementation of the interface Console,
@@ -11,6 +14,11 @@ import (
 // mainly due used for unit tests in test files.
 type testConsole struct {
 	scnr *bufio.Scanner
+	// idle keeps the console open once the file is read, as a server left
+	// running would, until killed.
+	idle   bool
+	killed chan struct{}
+	once   sync.Once
 }
 
 func (tc *testConsole) Start() error {
@@ -18,6 +26,7 @@ func (tc *testConsole) Start() error {
 }
 
 func (tc *testConsole) Kill() error {
+	tc.once.Do(func() { close(tc.killed) })
 	return nil
 }
 
@@ -29,6 +38,9 @@ func (tc *testConsole) ReadLine() (string, error) {
 	if tc.scnr.Scan() {
 		return tc.scnr.Text(), nil
 	}
+	if tc.idle {
+		<-tc.killed
+	}
 	return "", io.EOF
 }
 
@@ -38,6 +50,87 @@ func newTestConsole(filename string) (*testConsole, error) {
 		return nil, err
 	}
 	return &testConsole{
-		scnr: bufio.NewScanner(file),
+		scnr:   bufio.NewScanner(file),
+		killed: make(chan struct{}),
 	}, nil
 }
+
+// cmdTestConsole provide a test console implementation of the interface Console,
+// replying to the commands written with canned log lines. It is used to unit
+// test the parsing of the commands output.
+type cmdTestConsole struct {
+	mu        sync.Mutex
+	cmds      []string
+	lines     chan string
+	responses map[string][]string
+	// notify receives the commands written, if set, the ones overflowing
+	// its buffer being dropped.
+	notify chan string
+}
+
+func (tc *cmdTestConsole) Start() error {
+	return nil
+}
+
+func (tc *cmdTestConsole) Kill() error {
+	return nil
+}
+
+func (tc *cmdTestConsole) WriteCmd(c string) error {
+	tc.mu.Lock()
+	tc.cmds = append(tc.cmds, c)
+	tc.mu.Unlock()
+	select {
+	case tc.notify <- c:
+	default:
+	}
+
+	lines := tc.responses[c]
+	// The wrapper waits on the response before writing the command, which
+	// is logged from another goroutine as the server would.
+	go func() {
+		for _, l := range lines {
+			tc.lines <- l
+		}
+	}()
+	return nil
+}
+
+func (tc *cmdTestConsole) ReadLine() (string, error) {
+	return <-tc.lines, nil
+}
+
+// written returns the commands written to the console.
+func (tc *cmdTestConsole) written() []string {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	return append([]string{}, tc.cmds...)
+}
+
+// newCmdTestWrapper returns an online wrapper running a server of the given
+// version, replying to commands with the given responses. The setup funcs
+// are called on the wrapper before starting it.
+func newCmdTestWrapper(t *testing.T, version string, responses map[string][]string, setup ...func(*Wrapper)) (*Wrapper, *cmdTestConsole) {
+	tc := &cmdTestConsole{
+		lines:     make(chan string, 16),
+		responses: responses,
+		notify:    make(chan string, 64),
+	}
+	tc.lines <- "[12:00:00] [Server thread/INFO]: Starting minecraft server version " + version
+	tc.lines <- "[12:00:00] [Server thread/INFO]: Starting Minecraft server on *:25565"
+	tc.lines <- "[12:00:01] [Server thread/INFO]: Done (1.000s)! For help, type \"help\""
+
+	wpr := NewWrapper(tc, NewLogParser(VanillaLogProfile))
+	for _, f := range setup {
+		f(wpr)
+	}
+	if err := wpr.Start(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-wpr.Loaded():
+	case <-time.After(1 * time.Second):
+		t.Fatal("wrapper timeout, failed to start")
+	}
+	return wpr, tc
+}
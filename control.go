@@ -0,0 +1,648 @@
+package wrapper
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wlwanpan/minecraft-wrapper/events"
+)
+
+var (
+	// ErrUnknownOp is returned by the control server on a request it does
+	// not support.
+	ErrUnknownOp = errors.New("unknown op")
+	// ErrControlInUse is returned when listening on a control socket another
+	// process listens on.
+	ErrControlInUse = errors.New("control socket in use")
+	// ErrControlClosed is returned when serving with a closed ControlServer.
+	ErrControlClosed = errors.New("control server closed")
+)
+
+// DefaultControlPerm gives access to the control socket to the user and the
+// group of the wrapper process.
+const DefaultControlPerm os.FileMode = 0660
+
+// The ops of the control protocol.
+const (
+	// ControlCmd runs a console command, responding the console lines
+	// logged in return.
+	ControlCmd = "cmd"
+	// ControlSend writes a console command, not waiting for its output.
+	ControlSend    = "send"
+	ControlStatus  = "status"
+	ControlPlayers = "players"
+	ControlBackup  = "backup"
+	// ControlLogs streams the console lines, one response per line, until
+	// the connection is closed.
+	ControlLogs = "logs"
+	// ControlEvents streams the game events, the ones named in the request
+	// or all of them, one response per event until the connection is
+	// closed.
+	ControlEvents = "events"
+)
+
+const (
+	// controlCmdQuiet is the time without console lines ending the output
+	// of a command.
+	controlCmdQuiet = 250 * time.Millisecond
+	// controlCmdTimeout bounds the output of a command.
+	controlCmdTimeout = 3 * time.Second
+	// controlBackupTimeout bounds the backups requested.
+	controlBackupTimeout = 10 * time.Minute
+)
+
+// ControlRequest is a request of the control protocol, sent as a line of
+// JSON.
+type ControlRequest struct {
+	Op  string `json:"op"`
+	Cmd string `json:"cmd,omitempty"`
+	// Events are the names of the game events streamed by ControlEvents.
+	Events []string `json:"events,omitempty"`
+}
+
+// ControlResponse is the response to a ControlRequest, sent as a line of
+// JSON. Error is set on failure, the other fields depending on the op.
+type ControlResponse struct {
+	Error   string            `json:"error,omitempty"`
+	Lines   []string          `json:"lines,omitempty"`
+	Line    string            `json:"line,omitempty"`
+	Event   *events.GameEvent `json:"event,omitempty"`
+	Status  *ServerStatus     `json:"status,omitempty"`
+	Players []Player          `json:"players,omitempty"`
+	Path    string            `json:"path,omitempty"`
+}
+
+// ServerStatus is the status of the server behind a control server.
+type ServerStatus struct {
+	State   string `json:"state"`
+	Version string `json:"version"`
+	// Players is the count of players online.
+	Players int `json:"players"`
+	Tick    int `json:"tick"`
+}
+
+// ControlServer serves the control protocol to the local processes, giving
+// them access to a running wrapper, see 'ListenControl'. Each line of JSON
+// encoded ControlRequest is answered by a line of JSON encoded
+// ControlResponse, like:
+//
+//	{"op":"cmd","cmd":"list"}
+//	{"lines":["[12:01:00] [Server thread/INFO]: There are 0 of a max of 20 players online: "]}
+//
+// The 'logs' and 'events' ops turn the connection into a stream of responses, until
+// closed by the client, starting with an empty response once the stream
+// is set up.
+type ControlServer struct {
+	wrapper func() *Wrapper
+	backup  func(ctx context.Context) (string, error)
+
+	mu        sync.Mutex
+	listeners map[net.Listener]struct{}
+	// conns are the connections open, true while handling a request.
+	conns    map[net.Conn]bool
+	closed   bool
+	inflight sync.WaitGroup
+}
+
+// NewControlServer returns a ControlServer of the given wrapper, backups
+// being disabled.
+func NewControlServer(w *Wrapper) *ControlServer {
+	return newControlServer(func() *Wrapper { return w }, func(context.Context) (string, error) {
+		return "", ErrBackupDisabled
+	})
+}
+
+// ControlServer returns a ControlServer of the server run by the
+// Supervisor, across its restarts.
+func (s *Supervisor) ControlServer() *ControlServer {
+	return newControlServer(s.Wrapper, s.Backup)
+}
+
+func newControlServer(w func() *Wrapper, backup func(context.Context) (string, error)) *ControlServer {
+	return &ControlServer{
+		wrapper:   w,
+		backup:    backup,
+		listeners: map[net.Listener]struct{}{},
+		conns:     map[net.Conn]bool{},
+	}
+}
+
+// ListenControl listens on the Unix socket at path, for a ControlServer to
+// serve. The control protocol giving access to the server console, the
+// access is controlled by the socket file permissions: the socket is
+// created with perm apart, before being moved to path, so it never is
+// reachable with looser ones. Its directory should not be writable by the
+// other users. A socket left at path by a process not running anymore is
+// replaced, the socket being removed once the listener is closed.
+func ListenControl(path string, perm os.FileMode) (net.Listener, error) {
+	if fi, err := os.Lstat(path); err == nil {
+		if fi.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+		if c, err := net.Dial("unix", path); err == nil {
+			c.Close()
+			return nil, fmt.Errorf("%w: %s", ErrControlInUse, path)
+		}
+	}
+
+	// The temporary directory is only accessible to the user.
+	tmpDir, err := ioutil.TempDir(filepath.Dir(path), ".control")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+	tmp := filepath.Join(tmpDir, "control.sock")
+	l, err := net.ListenUnix("unix", &net.UnixAddr{Name: tmp, Net: "unix"})
+	if err != nil {
+		return nil, err
+	}
+	l.SetUnlinkOnClose(false)
+	if err := os.Chmod(tmp, perm); err != nil {
+		l.Close()
+		return nil, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		l.Close()
+		return nil, err
+	}
+	return &controlListener{UnixListener: l, path: path}, nil
+}
+
+// controlListener removes its socket file once closed, the socket having
+// been moved from the path it was created at.
+type controlListener struct {
+	*net.UnixListener
+	path string
+	once sync.Once
+}
+
+func (l *controlListener) Close() error {
+	err := l.UnixListener.Close()
+	l.once.Do(func() {
+		os.Remove(l.path)
+	})
+	return err
+}
+
+// Serve accepts the connections of the listener until it is closed, like
+// on 'Close'.
+func (cs *ControlServer) Serve(l net.Listener) error {
+	cs.mu.Lock()
+	if cs.closed {
+		cs.mu.Unlock()
+		l.Close()
+		return ErrControlClosed
+	}
+	cs.listeners[l] = struct{}{}
+	cs.mu.Unlock()
+	defer func() {
+		cs.mu.Lock()
+		delete(cs.listeners, l)
+		cs.mu.Unlock()
+	}()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		cs.mu.Lock()
+		cs.conns[conn] = false
+		cs.mu.Unlock()
+		go cs.serveConn(conn)
+	}
+}
+
+// Close closes the listeners and the connections of the ControlServer,
+// once the requests being handled are responded to.
+func (cs *ControlServer) Close() error {
+	cs.mu.Lock()
+	cs.closed = true
+	var first error
+	for l := range cs.listeners {
+		if err := l.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	for c, busy := range cs.conns {
+		if !busy {
+			c.Close()
+		}
+	}
+	cs.mu.Unlock()
+
+	cs.inflight.Wait()
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	for c := range cs.conns {
+		c.Close()
+	}
+	return first
+}
+
+// begin marks a request of the connection as being handled, returning
+// false once the ControlServer is closed.
+func (cs *ControlServer) begin(conn net.Conn) bool {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	if cs.closed {
+		return false
+	}
+	cs.conns[conn] = true
+	cs.inflight.Add(1)
+	return true
+}
+
+func (cs *ControlServer) end(conn net.Conn) {
+	cs.mu.Lock()
+	cs.conns[conn] = false
+	cs.mu.Unlock()
+	cs.inflight.Done()
+}
+
+func (cs *ControlServer) serveConn(conn net.Conn) {
+	defer func() {
+		cs.mu.Lock()
+		delete(cs.conns, conn)
+		cs.mu.Unlock()
+		conn.Close()
+	}()
+	enc := json.NewEncoder(conn)
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		req := ControlRequest{}
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			enc.Encode(ControlResponse{Error: fmt.Sprintf("invalid request: %v", err)})
+			continue
+		}
+		switch req.Op {
+		case ControlLogs:
+			cs.stream(conn, enc, forwardLines)
+			return
+		case ControlEvents:
+			cs.stream(conn, enc, eventsForwarder(req.Events))
+			return
+		}
+		if !cs.begin(conn) {
+			return
+		}
+		resp, err := cs.handle(req)
+		if err != nil {
+			resp = ControlResponse{Error: err.Error()}
+		}
+		err = enc.Encode(resp)
+		cs.end(conn)
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (cs *ControlServer) handle(req ControlRequest) (ControlResponse, error) {
+	if req.Op == ControlBackup {
+		ctx, cancel := context.WithTimeout(context.Background(), controlBackupTimeout)
+		defer cancel()
+		path, err := cs.backup(ctx)
+		return ControlResponse{Path: path}, err
+	}
+
+	w := cs.wrapper()
+	if w == nil {
+		return ControlResponse{}, ErrNotRunning
+	}
+	switch req.Op {
+	case ControlCmd:
+		lines, err := w.runCmd(req.Cmd)
+		return ControlResponse{Lines: lines}, err
+	case ControlSend:
+		return ControlResponse{}, w.writeToConsole(req.Cmd)
+	case ControlStatus:
+		return ControlResponse{Status: &ServerStatus{
+			State:   w.State(),
+			Version: w.GameVersion(),
+			Players: len(w.List()),
+			Tick:    w.Tick(),
+		}}, nil
+	case ControlPlayers:
+		return ControlResponse{Players: w.List()}, nil
+	default:
+		return ControlResponse{}, fmt.Errorf("%w: %q", ErrUnknownOp, req.Op)
+	}
+}
+
+// forwarder writes the values of a feed of the wrapper to the connection
+// until done, calling started once subscribed to the feed.
+type forwarder func(w *Wrapper, enc *json.Encoder, started func() error, done <-chan struct{}) error
+
+func forwardLines(w *Wrapper, enc *json.Encoder, started func() error, done <-chan struct{}) error {
+	lines, cancel := w.lines.subscribe()
+	defer cancel()
+	if err := started(); err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-done:
+			return nil
+		case line := <-lines:
+			if err := enc.Encode(ControlResponse{Line: line}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// eventsForwarder returns the forwarder of the game events with the given
+// names, or all of them if none.
+func eventsForwarder(names []string) forwarder {
+	filter := map[string]bool{}
+	for _, name := range names {
+		filter[name] = true
+	}
+	return func(w *Wrapper, enc *json.Encoder, started func() error, done <-chan struct{}) error {
+		evs, cancel := w.events.subscribe()
+		defer cancel()
+		if err := started(); err != nil {
+			return err
+		}
+		for {
+			select {
+			case <-done:
+				return nil
+			case ev := <-evs:
+				if len(filter) > 0 && !filter[ev.Name] {
+					continue
+				}
+				if err := enc.Encode(ControlResponse{Event: &ev}); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// stream forwards a feed of the wrapper to the connection until it is
+// closed, following the wrappers run by the Supervisor on restarts. An
+// empty response is written first, once the stream is set up.
+func (cs *ControlServer) stream(conn net.Conn, enc *json.Encoder, forward forwarder) {
+	// The client sends nothing more, reading detects the connection close.
+	closed := make(chan struct{})
+	go func() {
+		bufio.NewReader(conn).ReadString('\n')
+		close(closed)
+	}()
+	acked := false
+	started := func() error {
+		if acked {
+			return nil
+		}
+		acked = true
+		return enc.Encode(ControlResponse{})
+	}
+	ticker := time.NewTicker(managerPollInterval)
+	defer ticker.Stop()
+	for {
+		w := cs.wrapper()
+		if w == nil {
+			if err := started(); err != nil {
+				return
+			}
+			select {
+			case <-closed:
+				return
+			case <-ticker.C:
+				continue
+			}
+		}
+		// done is closed once the connection is, or once the Supervisor
+		// runs another wrapper.
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for cs.wrapper() == w {
+				select {
+				case <-closed:
+					return
+				case <-ticker.C:
+				}
+			}
+		}()
+		err := forward(w, enc, started, done)
+		<-done
+		if err != nil {
+			return
+		}
+		select {
+		case <-closed:
+			return
+		default:
+		}
+	}
+}
+
+// runCmd writes a console command, returning the lines logged until the
+// console goes quiet.
+func (w *Wrapper) runCmd(cmd string) ([]string, error) {
+	w.cmdMu.Lock()
+	defer w.cmdMu.Unlock()
+
+	lines, cancel := w.lines.subscribe()
+	defer cancel()
+	// The lines read before the command is written are not its output.
+	for len(lines) > 0 {
+		<-lines
+	}
+	if err := w.writeToConsole(cmd); err != nil {
+		return nil, err
+	}
+	output := []string{}
+	deadline := time.After(controlCmdTimeout)
+	quiet := time.NewTimer(controlCmdTimeout)
+	defer quiet.Stop()
+	for {
+		select {
+		case line := <-lines:
+			output = append(output, line)
+			if !quiet.Stop() {
+				<-quiet.C
+			}
+			quiet.Reset(controlCmdQuiet)
+		case <-quiet.C:
+			return output, nil
+		case <-deadline:
+			return output, nil
+		}
+	}
+}
+
+// controlErrors are the errors told apart by the ControlClient.
+var controlErrors = []error{
+	ErrWrapperNotOnline,
+	ErrWrapperResponseTimeout,
+	ErrNotRunning,
+	ErrBackupDisabled,
+	ErrWorldNotFound,
+	ErrUnknownOp,
+}
+
+// ControlClient is a client of a ControlServer.
+type ControlClient struct {
+	conn    net.Conn
+	scanner *bufio.Scanner
+	enc     *json.Encoder
+}
+
+// DialControl connects to the control server listening on the Unix socket
+// at path.
+func DialControl(path string) (*ControlClient, error) {
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	scanner := bufio.NewScanner(conn)
+	// Responses hold the output of commands, possibly long.
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	return &ControlClient{conn: conn, scanner: scanner, enc: json.NewEncoder(conn)}, nil
+}
+
+// Close closes the connection.
+func (c *ControlClient) Close() error {
+	return c.conn.Close()
+}
+
+// Command runs a console command, returning the lines logged in return.
+func (c *ControlClient) Command(cmd string) ([]string, error) {
+	resp, err := c.do(ControlRequest{Op: ControlCmd, Cmd: cmd})
+	return resp.Lines, err
+}
+
+// Send writes a console command, not waiting for its output.
+func (c *ControlClient) Send(cmd string) error {
+	_, err := c.do(ControlRequest{Op: ControlSend, Cmd: cmd})
+	return err
+}
+
+// Status returns the status of the server.
+func (c *ControlClient) Status() (ServerStatus, error) {
+	resp, err := c.do(ControlRequest{Op: ControlStatus})
+	if err != nil {
+		return ServerStatus{}, err
+	}
+	if resp.Status == nil {
+		return ServerStatus{}, errors.New("missing status in response")
+	}
+	return *resp.Status, nil
+}
+
+// Players returns the players online.
+func (c *ControlClient) Players() ([]Player, error) {
+	resp, err := c.do(ControlRequest{Op: ControlPlayers})
+	if resp.Players == nil {
+		resp.Players = []Player{}
+	}
+	return resp.Players, err
+}
+
+// Backup backs up the world of the server, returning the archive path.
+func (c *ControlClient) Backup() (string, error) {
+	resp, err := c.do(ControlRequest{Op: ControlBackup})
+	return resp.Path, err
+}
+
+// Logs streams the console lines of the server until the client is closed.
+// The connection is dedicated to the stream, no other request can be made
+// with the client.
+func (c *ControlClient) Logs() (<-chan string, error) {
+	responses, err := c.stream(ControlRequest{Op: ControlLogs})
+	if err != nil {
+		return nil, err
+	}
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		for resp := range responses {
+			lines <- resp.Line
+		}
+	}()
+	return lines, nil
+}
+
+// Events streams the game events of the server with the given names, or
+// all of them if none, until the client is closed. As with 'Logs', the
+// connection is dedicated to the stream.
+func (c *ControlClient) Events(names ...string) (<-chan events.GameEvent, error) {
+	responses, err := c.stream(ControlRequest{Op: ControlEvents, Events: names})
+	if err != nil {
+		return nil, err
+	}
+	evs := make(chan events.GameEvent)
+	go func() {
+		defer close(evs)
+		for resp := range responses {
+			if resp.Event != nil {
+				evs <- *resp.Event
+			}
+		}
+	}()
+	return evs, nil
+}
+
+// stream sends a streaming request, returning its responses once the
+// stream started, until the connection is closed.
+func (c *ControlClient) stream(req ControlRequest) (<-chan ControlResponse, error) {
+	if _, err := c.do(req); err != nil {
+		return nil, err
+	}
+	responses := make(chan ControlResponse)
+	go func() {
+		defer close(responses)
+		for c.scanner.Scan() {
+			resp := ControlResponse{}
+			if err := json.Unmarshal(c.scanner.Bytes(), &resp); err != nil {
+				return
+			}
+			responses <- resp
+		}
+	}()
+	return responses, nil
+}
+
+func (c *ControlClient) do(req ControlRequest) (ControlResponse, error) {
+	if err := c.enc.Encode(req); err != nil {
+		return ControlResponse{}, err
+	}
+	if !c.scanner.Scan() {
+		if err := c.scanner.Err(); err != nil {
+			return ControlResponse{}, err
+		}
+		return ControlResponse{}, errors.New("connection closed by the control server")
+	}
+	resp := ControlResponse{}
+	if err := json.Unmarshal(c.scanner.Bytes(), &resp); err != nil {
+		return ControlResponse{}, err
+	}
+	if resp.Error != "" {
+		return resp, controlError(resp.Error)
+	}
+	return resp, nil
+}
+
+// controlError returns the error of a response, wrapping the known errors.
+func controlError(msg string) error {
+	for _, e := range controlErrors {
+		if msg == e.Error() || strings.HasPrefix(msg, e.Error()+":") {
+			return fmt.Errorf("%w%s", e, strings.TrimPrefix(msg, e.Error()))
+		}
+	}
+	return errors.New(msg)
+}
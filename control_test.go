@@ -0,0 +1,196 @@
+package wrapper
+
+import (
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/wlwanpan/minecraft-wrapper/events"
+)
+
+func TestControlServer(t *testing.T) {
+	wpr, tc := newCmdTestWrapper(t, "1.16.5", map[string][]string{
+		"seed": {"[12:01:00] [Server thread/INFO]: Seed: [-1234]"},
+	})
+	// The login lines are handled before the command, not being part of
+	// its output.
+	lines, cancel := wpr.lines.subscribe()
+	tc.lines <- "[12:00:02] [Server thread/INFO]: UUID of player player1 is 4b2e2a5c-7a1f-4c52-9f0e-0e0d8a6b3f2e"
+	tc.lines <- "[12:00:02] [Server thread/INFO]: player1[/127.0.0.1:53210] logged in with entity id 1 at (0.5, 64.0, 0.5)"
+	for i := 0; i < 2; i++ {
+		<-lines
+	}
+	cancel()
+
+	dir := testDir(t)
+	path := filepath.Join(dir, "mcwrapper.sock")
+	l, err := ListenControl(path, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs := NewControlServer(wpr)
+	go cs.Serve(l)
+	defer cs.Close()
+
+	c, err := DialControl(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	output, err := c.Command("seed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"[12:01:00] [Server thread/INFO]: Seed: [-1234]"}; !reflect.DeepEqual(output, expected) {
+		t.Errorf("wrong command output: %v", output)
+	}
+	st, err := c.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.State != WrapperOnline || st.Version != "1.16.5" || st.Players != 1 {
+		t.Errorf("wrong status: %+v", st)
+	}
+	players, err := c.Players()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(players) != 1 || players[0].Name != "player1" || players[0].IP != "127.0.0.1" {
+		t.Errorf("wrong players: %+v", players)
+	}
+	if _, err := c.Backup(); !errors.Is(err, ErrBackupDisabled) {
+		t.Errorf("backup of a bare wrapper should fail, got %v", err)
+	}
+	if _, err := c.do(ControlRequest{Op: "reboot"}); !errors.Is(err, ErrUnknownOp) {
+		t.Errorf("unknown op should fail, got %v", err)
+	}
+	if err := c.Send("say hello"); err != nil {
+		t.Fatal(err)
+	}
+	if cmds := tc.written(); cmds[len(cmds)-1] != "say hello" {
+		t.Errorf("command should be sent, got %v", cmds)
+	}
+}
+
+func TestControlLogs(t *testing.T) {
+	wpr, tc := newCmdTestWrapper(t, "1.16.5", nil)
+	dir := testDir(t)
+	path := filepath.Join(dir, "mcwrapper.sock")
+	l, err := ListenControl(path, DefaultControlPerm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs := NewControlServer(wpr)
+	go cs.Serve(l)
+	defer cs.Close()
+
+	c, err := DialControl(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	stream, err := c.Logs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tc.lines <- "[12:02:00] [Server thread/INFO]: [Server] hello"
+	select {
+	case line := <-stream:
+		if line != "[12:02:00] [Server thread/INFO]: [Server] hello" {
+			t.Errorf("wrong line streamed: %q", line)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for the streamed line")
+	}
+}
+
+func TestListenControl(t *testing.T) {
+	dir := testDir(t)
+	path := filepath.Join(dir, "mcwrapper.sock")
+
+	// A socket left by a process not running anymore.
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	l, err := ListenControl(path, 0640)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode()&os.ModeSocket == 0 || fi.Mode().Perm() != 0640 {
+		t.Errorf("wrong socket mode %s", fi.Mode())
+	}
+	if _, err := ListenControl(path, 0640); !errors.Is(err, ErrControlInUse) {
+		t.Errorf("listening on a socket in use should fail, got %v", err)
+	}
+	if entries, _ := ioutil.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("temporary files should be removed, got %d entries", len(entries))
+	}
+
+	l.Close()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("socket should be removed once closed, got %v", err)
+	}
+
+	file := filepath.Join(dir, "server.properties")
+	if err := ioutil.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ListenControl(file, 0640); err == nil {
+		t.Error("listening on a regular file should fail")
+	}
+}
+
+func TestControlEvents(t *testing.T) {
+	wpr, tc := newCmdTestWrapper(t, "1.16.5", nil)
+	dir := testDir(t)
+	path := filepath.Join(dir, "mcwrapper.sock")
+	l, err := ListenControl(path, DefaultControlPerm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs := NewControlServer(wpr)
+	go cs.Serve(l)
+	defer cs.Close()
+
+	c, err := DialControl(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	evs, err := c.Events(events.PlayerSay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tc.lines <- "[12:01:00] [Server thread/INFO]: player1 joined the game"
+	tc.lines <- "[12:01:01] [Server thread/INFO]: <player1> hello"
+	select {
+	case ev := <-evs:
+		if ev.Name != events.PlayerSay || ev.Data["player_name"] != "player1" || ev.Data["player_message"] != "hello" {
+			t.Errorf("wrong event streamed: %s %v", ev.Name, ev.Data)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for the streamed event")
+	}
+	// The stream leaves the game events to the wrapper consumer.
+	for i := 0; i < 2; i++ {
+		select {
+		case <-wpr.GameEvents():
+		case <-time.After(time.Second):
+			t.Fatal("game events should still be sent to the wrapper channel")
+		}
+	}
+}
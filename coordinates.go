@@ -0,0 +1,143 @@
+package wrapper
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ErrInvalidPosition is returned by the commands given a position the game
+// would reject.
+var ErrInvalidPosition = errors.New("invalid position")
+
+type coordinateKind byte
+
+const (
+	absoluteCoordinate coordinateKind = iota
+	relativeCoordinate
+	localCoordinate
+)
+
+// Coordinate is a coordinate of a command position, either absolute, like
+// "64", relative to the position running the command, like "~-5", or local
+// to its rotation, like "^2".
+type Coordinate struct {
+	kind  coordinateKind
+	value float64
+}
+
+// Absolute returns the coordinate at the given world value.
+func Absolute(v float64) Coordinate {
+	return Coordinate{kind: absoluteCoordinate, value: v}
+}
+
+// Relative returns the coordinate offset from the position running the
+// command, in world axes.
+func Relative(offset float64) Coordinate {
+	return Coordinate{kind: relativeCoordinate, value: offset}
+}
+
+// Local returns the coordinate offset from the position running the
+// command, in the axes of its rotation: left, up and forward.
+func Local(offset float64) Coordinate {
+	return Coordinate{kind: localCoordinate, value: offset}
+}
+
+// String returns the coordinate in the command syntax.
+func (c Coordinate) String() string {
+	prefix := ""
+	switch c.kind {
+	case relativeCoordinate:
+		prefix = "~"
+	case localCoordinate:
+		prefix = "^"
+	}
+	if prefix != "" && c.value == 0 {
+		return prefix
+	}
+	return prefix + formatFloat(c.value)
+}
+
+// Position is the position argument of a command, like "~ ~1 ~".
+type Position struct {
+	X, Y, Z Coordinate
+}
+
+// AbsolutePosition returns the position at the given world coordinates.
+func AbsolutePosition(x, y, z float64) Position {
+	return Position{Absolute(x), Absolute(y), Absolute(z)}
+}
+
+// RelativePosition returns the position offset from the position running
+// the command, "~ ~ ~" being the position itself.
+func RelativePosition(dx, dy, dz float64) Position {
+	return Position{Relative(dx), Relative(dy), Relative(dz)}
+}
+
+// LocalPosition returns the position offset from the position running the
+// command in the axes of its rotation, "^ ^ ^1" being one block ahead.
+func LocalPosition(left, up, forward float64) Position {
+	return Position{Local(left), Local(up), Local(forward)}
+}
+
+// String returns the position in the command syntax.
+func (p Position) String() string {
+	return fmt.Sprintf("%s %s %s", p.X, p.Y, p.Z)
+}
+
+// Validate returns an error if the position would be rejected by the game:
+// local coordinates can not be mixed with the others.
+func (p Position) Validate() error {
+	locals := 0
+	for _, c := range []Coordinate{p.X, p.Y, p.Z} {
+		if math.IsInf(c.value, 0) || math.IsNaN(c.value) {
+			return fmt.Errorf("%w %s: coordinates should be finite", ErrInvalidPosition, p)
+		}
+		if c.kind == localCoordinate {
+			locals++
+		}
+	}
+	if locals != 0 && locals != 3 {
+		return fmt.Errorf("%w %s: local coordinates can not be mixed with others", ErrInvalidPosition, p)
+	}
+	return nil
+}
+
+// validateBlock returns an error if the position is not a valid block
+// position, whose absolute coordinates are integers.
+func (p Position) validateBlock() error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	for _, c := range []Coordinate{p.X, p.Y, p.Z} {
+		if c.kind == absoluteCoordinate && c.value != math.Trunc(c.value) {
+			return fmt.Errorf("%w %s: block coordinates should be integers", ErrInvalidPosition, p)
+		}
+	}
+	return nil
+}
+
+// Rotation is the rotation argument of a command, either absolute or
+// relative to the rotation of the entity running the command.
+type Rotation struct {
+	// Yaw is the horizontal rotation, from -180 to 180, 0 facing south.
+	Yaw Coordinate
+	// Pitch is the vertical rotation, from -90 facing up to 90 facing down.
+	Pitch Coordinate
+}
+
+// String returns the rotation in the command syntax.
+func (r Rotation) String() string {
+	return fmt.Sprintf("%s %s", r.Yaw, r.Pitch)
+}
+
+// Validate returns an error if the rotation would be rejected by the game,
+// which does not support local rotations.
+func (r Rotation) Validate() error {
+	for _, c := range []Coordinate{r.Yaw, r.Pitch} {
+		if math.IsInf(c.value, 0) || math.IsNaN(c.value) || c.kind == localCoordinate {
+			return fmt.Errorf("%w: invalid rotation %s", ErrInvalidPosition, r)
+		}
+	}
+	return nil
+}
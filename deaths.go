@@ -0,0 +1,221 @@
+package wrapper
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// deathMessage is a vanilla death message, from the 'death.*' translation
+// keys, where '%1$s' is the victim, '%2$s' the killer and '%3$s' the weapon.
+type deathMessage struct {
+	key    string
+	cause  string
+	format string
+}
+
+// deathMessages is the catalogue of the vanilla death messages, see:
+// https://minecraft.gamepedia.com/Death_messages. The cause is the damage
+// type of the death, while the key identifies the exact message logged.
+var deathMessages = []deathMessage{
+	{"death.attack.anvil", "falling_anvil", "%1$s was squashed by a falling anvil"},
+	{"death.attack.anvil.player", "falling_anvil", "%1$s was squashed by a falling anvil whilst fighting %2$s"},
+	{"death.attack.arrow", "arrow", "%1$s was shot by %2$s"},
+	{"death.attack.arrow.item", "arrow", "%1$s was shot by %2$s using %3$s"},
+	{"death.attack.badRespawnPoint.message", "bad_respawn_point", "%1$s was killed by %2$s"},
+	{"death.attack.cactus", "cactus", "%1$s was pricked to death"},
+	{"death.attack.cactus.player", "cactus", "%1$s walked into a cactus whilst trying to escape %2$s"},
+	{"death.attack.cramming", "cramming", "%1$s was squished too much"},
+	{"death.attack.cramming.player", "cramming", "%1$s was squashed by %2$s"},
+	{"death.attack.dragonBreath", "dragon_breath", "%1$s was roasted in dragon breath"},
+	{"death.attack.dragonBreath.player", "dragon_breath", "%1$s was roasted in dragon breath by %2$s"},
+	{"death.attack.drown", "drown", "%1$s drowned"},
+	{"death.attack.drown.player", "drown", "%1$s drowned whilst trying to escape %2$s"},
+	{"death.attack.dryout", "dry_out", "%1$s died from dehydration"},
+	{"death.attack.dryout.player", "dry_out", "%1$s died from dehydration whilst trying to escape %2$s"},
+	{"death.attack.even_more_magic", "magic", "%1$s was killed by even more magic"},
+	{"death.attack.explosion", "explosion", "%1$s blew up"},
+	{"death.attack.explosion.player", "explosion", "%1$s was blown up by %2$s"},
+	{"death.attack.explosion.player.item", "explosion", "%1$s was blown up by %2$s using %3$s"},
+	{"death.attack.fall", "fall", "%1$s hit the ground too hard"},
+	{"death.attack.fall.player", "fall", "%1$s hit the ground too hard whilst trying to escape %2$s"},
+	{"death.attack.fallingBlock", "falling_block", "%1$s was squashed by a falling block"},
+	{"death.attack.fallingBlock.player", "falling_block", "%1$s was squashed by a falling block whilst fighting %2$s"},
+	{"death.attack.fallingStalactite", "falling_stalactite", "%1$s was skewered by a falling stalactite"},
+	{"death.attack.fallingStalactite.player", "falling_stalactite", "%1$s was skewered by a falling stalactite whilst fighting %2$s"},
+	{"death.attack.fireball", "fireball", "%1$s was fireballed by %2$s"},
+	{"death.attack.fireball.item", "fireball", "%1$s was fireballed by %2$s using %3$s"},
+	{"death.attack.fireworks", "fireworks", "%1$s went off with a bang"},
+	{"death.attack.fireworks.item", "fireworks", "%1$s went off with a bang due to a firework fired from %3$s by %2$s"},
+	{"death.attack.fireworks.player", "fireworks", "%1$s went off with a bang whilst fighting %2$s"},
+	{"death.attack.flyIntoWall", "fly_into_wall", "%1$s experienced kinetic energy"},
+	{"death.attack.flyIntoWall.player", "fly_into_wall", "%1$s experienced kinetic energy whilst trying to escape %2$s"},
+	{"death.attack.freeze", "freeze", "%1$s froze to death"},
+	{"death.attack.freeze.player", "freeze", "%1$s was frozen to death by %2$s"},
+	{"death.attack.generic", "generic", "%1$s died"},
+	{"death.attack.generic.player", "generic", "%1$s died because of %2$s"},
+	{"death.attack.genericKill", "generic_kill", "%1$s was killed"},
+	{"death.attack.genericKill.player", "generic_kill", "%1$s was killed whilst fighting %2$s"},
+	{"death.attack.hotFloor", "hot_floor", "%1$s discovered the floor was lava"},
+	{"death.attack.hotFloor.player", "hot_floor", "%1$s walked into the danger zone due to %2$s"},
+	{"death.attack.hotFloor.player.legacy", "hot_floor", "%1$s walked into danger zone due to %2$s"},
+	{"death.attack.inFire", "in_fire", "%1$s went up in flames"},
+	{"death.attack.inFire.player", "in_fire", "%1$s walked into fire whilst fighting %2$s"},
+	{"death.attack.inWall", "in_wall", "%1$s suffocated in a wall"},
+	{"death.attack.inWall.player", "in_wall", "%1$s suffocated in a wall whilst fighting %2$s"},
+	{"death.attack.indirectMagic", "indirect_magic", "%1$s was killed by %2$s using magic"},
+	{"death.attack.indirectMagic.item", "indirect_magic", "%1$s was killed by %2$s using %3$s"},
+	{"death.attack.lava", "lava", "%1$s tried to swim in lava"},
+	{"death.attack.lava.player", "lava", "%1$s tried to swim in lava to escape %2$s"},
+	{"death.attack.lightningBolt", "lightning_bolt", "%1$s was struck by lightning"},
+	{"death.attack.lightningBolt.player", "lightning_bolt", "%1$s was struck by lightning whilst fighting %2$s"},
+	{"death.attack.maceSmash.player", "mace_smash", "%1$s was smashed by %2$s"},
+	{"death.attack.maceSmash.player.item", "mace_smash", "%1$s was smashed by %2$s with %3$s"},
+	{"death.attack.magic", "magic", "%1$s was killed by magic"},
+	{"death.attack.magic.player", "magic", "%1$s was killed by magic whilst trying to escape %2$s"},
+	{"death.attack.mob", "mob_attack", "%1$s was slain by %2$s"},
+	{"death.attack.mob.item", "mob_attack", "%1$s was slain by %2$s using %3$s"},
+	{"death.attack.onFire", "on_fire", "%1$s burned to death"},
+	{"death.attack.onFire.item", "on_fire", "%1$s was burnt to a crisp whilst fighting %2$s wielding %3$s"},
+	{"death.attack.onFire.player", "on_fire", "%1$s was burnt to a crisp whilst fighting %2$s"},
+	{"death.attack.outOfWorld", "out_of_world", "%1$s fell out of the world"},
+	{"death.attack.outOfWorld.player", "out_of_world", "%1$s didn't want to live in the same world as %2$s"},
+	{"death.attack.outsideBorder", "outside_border", "%1$s left the confines of this world"},
+	{"death.attack.outsideBorder.player", "outside_border", "%1$s left the confines of this world whilst fighting %2$s"},
+	{"death.attack.sonic_boom", "sonic_boom", "%1$s was obliterated by a sonically-charged shriek"},
+	{"death.attack.sonic_boom.item", "sonic_boom", "%1$s was obliterated by a sonically-charged shriek whilst trying to escape %2$s wielding %3$s"},
+	{"death.attack.sonic_boom.player", "sonic_boom", "%1$s was obliterated by a sonically-charged shriek whilst trying to escape %2$s"},
+	{"death.attack.spit", "spit", "%1$s was spit by %2$s"},
+	{"death.attack.spit.item", "spit", "%1$s was spit by %2$s using %3$s"},
+	{"death.attack.stalagmite", "stalagmite", "%1$s was impaled on a stalagmite"},
+	{"death.attack.stalagmite.player", "stalagmite", "%1$s was impaled on a stalagmite whilst fighting %2$s"},
+	{"death.attack.starve", "starve", "%1$s starved to death"},
+	{"death.attack.starve.player", "starve", "%1$s starved to death whilst fighting %2$s"},
+	{"death.attack.sting", "sting", "%1$s was stung to death"},
+	{"death.attack.sting.item", "sting", "%1$s was stung to death by %2$s using %3$s"},
+	{"death.attack.sting.player", "sting", "%1$s was stung to death by %2$s"},
+	{"death.attack.sweetBerryBush", "sweet_berry_bush", "%1$s was poked to death by a sweet berry bush"},
+	{"death.attack.sweetBerryBush.player", "sweet_berry_bush", "%1$s was poked to death by a sweet berry bush whilst trying to escape %2$s"},
+	{"death.attack.thorns", "thorns", "%1$s was killed trying to hurt %2$s"},
+	{"death.attack.thorns.item", "thorns", "%1$s was killed by %3$s trying to hurt %2$s"},
+	{"death.attack.thrown", "thrown", "%1$s was pummeled by %2$s"},
+	{"death.attack.thrown.item", "thrown", "%1$s was pummeled by %2$s using %3$s"},
+	{"death.attack.trident", "trident", "%1$s was impaled by %2$s"},
+	{"death.attack.trident.item", "trident", "%1$s was impaled by %2$s with %3$s"},
+	{"death.attack.wither", "wither", "%1$s withered away"},
+	{"death.attack.wither.player", "wither", "%1$s withered away whilst fighting %2$s"},
+	{"death.attack.witherSkull", "wither_skull", "%1$s was shot by a skull from %2$s"},
+	{"death.attack.witherSkull.item", "wither_skull", "%1$s was shot by a skull from %2$s using %3$s"},
+	{"death.fell.accident.generic", "fall", "%1$s fell from a high place"},
+	{"death.fell.accident.ladder", "fall", "%1$s fell off a ladder"},
+	{"death.fell.accident.other_climbable", "fall", "%1$s fell while climbing"},
+	{"death.fell.accident.scaffolding", "fall", "%1$s fell off scaffolding"},
+	{"death.fell.accident.twisting_vines", "fall", "%1$s fell off some twisting vines"},
+	{"death.fell.accident.vines", "fall", "%1$s fell off some vines"},
+	{"death.fell.accident.water", "fall", "%1$s fell out of the water"},
+	{"death.fell.accident.weeping_vines", "fall", "%1$s fell off some weeping vines"},
+	{"death.fell.assist", "fall", "%1$s was doomed to fall by %2$s"},
+	{"death.fell.assist.item", "fall", "%1$s was doomed to fall by %2$s using %3$s"},
+	{"death.fell.finish", "fall", "%1$s fell too far and was finished by %2$s"},
+	{"death.fell.finish.item", "fall", "%1$s fell too far and was finished by %2$s using %3$s"},
+	{"death.fell.killer", "fall", "%1$s was doomed to fall"},
+}
+
+// deathMatcher matches the log output of a single death message.
+type deathMatcher struct {
+	deathMessage
+	// lead is the literal text following the victim name, checked before
+	// running the regex.
+	lead  string
+	regex *regexp.Regexp
+}
+
+// deathMatchers indexes the death matchers by the first word following
+// the victim name, each list sorted from the most to the least specific
+// message so that "was slain by X using Y" is never read as "was slain by X".
+var deathMatchers = map[string][]*deathMatcher{}
+
+var deathPlaceholders = map[string]string{
+	"%1$s": `(?P<victim>\S+)`,
+	"%2$s": `(?P<killer>.+)`,
+	"%3$s": `(?P<weapon>.+)`,
+}
+
+func init() {
+	placeholderRegex := regexp.MustCompile(`%[123]\$s`)
+	for _, dm := range deathMessages {
+		format := strings.TrimPrefix(dm.format, "%1$s ")
+		lead := format
+		if i := strings.Index(lead, "%"); i >= 0 {
+			lead = lead[:i]
+		}
+
+		pattern := "^" + deathPlaceholders["%1$s"] + " "
+		last := 0
+		for _, loc := range placeholderRegex.FindAllStringIndex(format, -1) {
+			pattern += regexp.QuoteMeta(format[last:loc[0]]) + deathPlaceholders[format[loc[0]:loc[1]]]
+			last = loc[1]
+		}
+		pattern += regexp.QuoteMeta(format[last:]) + "$"
+
+		word := strings.SplitN(lead, " ", 2)[0]
+		deathMatchers[word] = append(deathMatchers[word], &deathMatcher{
+			deathMessage: dm,
+			lead:         lead,
+			regex:        regexp.MustCompile(pattern),
+		})
+	}
+	for _, dms := range deathMatchers {
+		sort.SliceStable(dms, func(i, j int) bool {
+			return literalLen(dms[i].format) > literalLen(dms[j].format)
+		})
+	}
+}
+
+// literalLen returns the length of a death message format, placeholders
+// excluded, used to rank the messages by specificity.
+func literalLen(format string) int {
+	n := len(format)
+	for p := range deathPlaceholders {
+		n -= strings.Count(format, p) * len(p)
+	}
+	return n
+}
+
+// matchDeathMessage matches a log output against the death messages
+// catalogue. The returned matches are: the output, the victim, the killer,
+// the weapon, the death cause and the death message key.
+func matchDeathMessage(output string) []string {
+	sep := strings.IndexByte(output, ' ')
+	if sep < 0 {
+		return nil
+	}
+	rest := output[sep+1:]
+	word := rest
+	if i := strings.IndexByte(rest, ' '); i >= 0 {
+		word = rest[:i]
+	}
+
+	for _, dm := range deathMatchers[word] {
+		if !strings.HasPrefix(rest, dm.lead) {
+			continue
+		}
+		matches := dm.regex.FindStringSubmatch(output)
+		if matches == nil {
+			continue
+		}
+		result := []string{output, "", "", "", dm.cause, dm.key}
+		for i, name := range dm.regex.SubexpNames() {
+			switch name {
+			case "victim":
+				result[1] = matches[i]
+			case "killer":
+				result[2] = matches[i]
+			case "weapon":
+				result[3] = strings.TrimSuffix(strings.TrimPrefix(matches[i], "["), "]")
+			}
+		}
+		return result
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package wrapper
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	"github.com/wlwanpan/minecraft-wrapper/events"
+)
+
+func TestDeathMessagesGoldenLog(t *testing.T) {
+	b, err := ioutil.ReadFile("testdata/deaths/death_messages_golden.json")
+	if err != nil {
+		t.Fatalf("failed to load golden file: %s", err)
+	}
+	golden := []map[string]string{}
+	if err := json.Unmarshal(b, &golden); err != nil {
+		t.Fatalf("failed to decode golden file: %s", err)
+	}
+
+	evs := parseProfileTestLog(t, logParserFunc, "testdata/deaths/death_messages_log")
+	if len(evs) != len(golden) {
+		t.Fatalf("wrong event count detected: actual=%d, expected=%d", len(evs), len(golden))
+	}
+
+	covered := map[string]bool{}
+	for i, ev := range evs {
+		gev := ev.(events.GameEvent)
+		if gev.Name != events.PlayerDied {
+			t.Errorf("event mismatched at %d: actual=%s, expected=%s", i, gev.Name, events.PlayerDied)
+			continue
+		}
+		// The deprecated data are covered apart.
+		delete(gev.Data, "death_by")
+		delete(gev.Data, "death_details")
+		if !reflect.DeepEqual(gev.Data, golden[i]) {
+			t.Errorf("data mismatch at %d:\nactual  : %v\nexpected: %v", i, gev.Data, golden[i])
+		}
+		covered[gev.Data["death_key"]] = true
+	}
+
+	for _, dm := range deathMessages {
+		if !covered[dm.key] {
+			t.Errorf("death message '%s' is not covered by the golden log", dm.key)
+		}
+	}
+}
+
+func TestDeathMessagesNotMatched(t *testing.T) {
+	outputs := []string{
+		"player1 fell",
+		"player1 was slain",
+		"Preparing spawn area: 83%",
+		"player1 has made the advancement [Monster Hunter]",
+	}
+	for _, output := range outputs {
+		if matches := matchDeathMessage(output); matches != nil {
+			t.Errorf("output '%s' should not match a death message, got key %s", output, matches[5])
+		}
+	}
+}
+
+func TestDeathMessagesDeprecatedData(t *testing.T) {
+	tests := []struct {
+		output  string
+		by      string
+		details string
+	}{
+		{"player1 was slain by Zombie", "was slain", " by Zombie"},
+		{"player1 was shot by player2 using [Bow]", "was shot", " by player2 using [Bow]"},
+		{"player1 drowned", "drowned", ""},
+		{"player1 went up in flames", "went up in flames", ""},
+	}
+	for _, tt := range tests {
+		gev, _ := handlePlayerDied(matchDeathMessage(tt.output), 0)
+		if gev.Data["death_by"] != tt.by || gev.Data["death_details"] != tt.details {
+			t.Errorf("%s: wrong deprecated data: death_by=%q, death_details=%q", tt.output, gev.Data["death_by"], gev.Data["death_details"])
+		}
+	}
+}
+
+func TestWrapperDeathKillerType(t *testing.T) {
+	wpr := NewWrapper(&testConsole{}, logParserFunc)
+	wpr.players.update("player2", func(p *Player) { p.UUID = "player-2-uuid" })
+
+	tests := []struct {
+		output     string
+		killerType string
+		cause      string
+	}{
+		{"player1 was slain by player2", "player", "player_attack"},
+		{"player1 was slain by Zombie", "entity", "mob_attack"},
+		{"player1 was shot by player2 using [Bow]", "player", "arrow"},
+	}
+	for _, tt := range tests {
+		gev, _ := handlePlayerDied(matchDeathMessage(tt.output), 0)
+		wpr.handleGameEvent(gev)
+		ev := <-wpr.GameEvents()
+		if ev.Data["killer_type"] != tt.killerType {
+			t.Errorf("%s: killer type should be %s, got %s", tt.output, tt.killerType, ev.Data["killer_type"])
+		}
+		if ev.Data["death_cause"] != tt.cause {
+			t.Errorf("%s: death cause should be %s, got %s", tt.output, tt.cause, ev.Data["death_cause"])
+		}
+	}
+}
@@ -25,27 +25,45 @@ const (
 
 // Game related events that provide player/server related information.
 const (
-	Banned           string = "banned"
-	BanList                 = "ban-list"
-	BanListEntry            = "ban-list-entry"
-	DataGet                 = "data-get"
-	DataGetNoEntity         = "data-get-no-entity"
-	DefaultGameMode         = "default-game-mode"
-	Difficulty              = "difficulty"
-	ExperienceAdd           = "experience-add"
-	ExperienceQuery         = "experience-query"
-	Give                    = "give"
-	NoPlayerFound           = "no-player-found"
-	PlayerJoined            = "player-joined"
-	PlayerLeft              = "player-left"
-	PlayerUUID              = "player-uuid"
-	PlayerSay               = "player-say"
-	PlayerDied              = "player-died"
-	Kicked                  = "kicked"
-	Seed                    = "seed"
-	ServerOverloaded        = "server-overloaded"
-	TimeIs                  = "time-is"
-	UnknownItem             = "unknown-item"
-	Version                 = "version"
-	WhisperTo               = "whisper-to"
+	Advancement          string = "advancement"
+	Banned                      = "banned"
+	BanList                     = "ban-list"
+	BanListEntry                = "ban-list-entry"
+	DataGet                     = "data-get"
+	DataGetNoEntity             = "data-get-no-entity"
+	DefaultGameMode             = "default-game-mode"
+	Difficulty                  = "difficulty"
+	Exception                   = "exception"
+	ExperienceAdd               = "experience-add"
+	ExperienceQuery             = "experience-query"
+	GameMode                    = "game-mode"
+	GameRule                    = "game-rule"
+	Give                        = "give"
+	InvalidPosition             = "invalid-position"
+	NoEntityFound               = "no-entity-found"
+	NoPlayerFound               = "no-player-found"
+	Operator                    = "operator"
+	Pardon                      = "pardon"
+	PlayerAdvancement           = "player-advancement"
+	PlayerJoined                = "player-joined"
+	PlayerLeft                  = "player-left"
+	PlayerLogin                 = "player-login"
+	PlayerLostConnection        = "player-lost-connection"
+	PlayerRejected              = "player-rejected"
+	PlayerUUID                  = "player-uuid"
+	PlayerSay                   = "player-say"
+	PlayerDied                  = "player-died"
+	Kicked                      = "kicked"
+	List                        = "list"
+	RawLine                     = "raw-line"
+	Seed                        = "seed"
+	Teleport                    = "teleport"
+	ServerOverloaded            = "server-overloaded"
+	TimeIs                      = "time-is"
+	UnknownAdvancement          = "unknown-advancement"
+	UnknownGameRule             = "unknown-game-rule"
+	UnknownItem                 = "unknown-item"
+	Version                     = "version"
+	WhisperTo                   = "whisper-to"
+	Whitelist                   = "whitelist"
 )
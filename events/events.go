@@ -1,7 +1,14 @@
 package events
 
+import (
+	"sync/atomic"
+	"time"
+)
+
 var (
-	gameEventCount int = 0
+	// gameEventCount is incremented by the parser goroutine of every
+	// wrapper, hence atomically.
+	gameEventCount int64 = 0
 )
 
 type Event interface {
@@ -37,6 +44,9 @@ type GameEvent struct {
 	id   int
 	Name string
 	Tick int
+	// Time is the time the event was logged at, only set when the server
+	// logs are read from a structured layout carrying the full date.
+	Time time.Time
 	Data map[string]string
 }
 
@@ -49,20 +59,29 @@ func (ge GameEvent) Is(e Event) bool {
 }
 
 func NewGameEvent(e string) GameEvent {
-	gameEventCount++
 	return GameEvent{
-		id:   gameEventCount,
+		id:   int(atomic.AddInt64(&gameEventCount, 1)),
 		Name: e,
 	}
 }
 
 var (
-	NilGameEvent       = NewGameEvent(Empty)
-	VersionEvent       = NewGameEvent(Version)
-	TimeIsEvent        = NewGameEvent(TimeIs)
-	DataGetEvent       = NewGameEvent(DataGet)
-	NoPlayerFoundEvent = NewGameEvent(NoPlayerFound)
-	UnknownItemEvent   = NewGameEvent(UnknownItem)
-	PlayerLeftEvent    = NewGameEvent(PlayerLeft)
-	PlayerUUIDEvent    = NewGameEvent(PlayerUUID)
+	NilGameEvent              = NewGameEvent(Empty)
+	VersionEvent              = NewGameEvent(Version)
+	TimeIsEvent               = NewGameEvent(TimeIs)
+	DataGetEvent              = NewGameEvent(DataGet)
+	NoPlayerFoundEvent        = NewGameEvent(NoPlayerFound)
+	NoEntityFoundEvent        = NewGameEvent(NoEntityFound)
+	InvalidPositionEvent      = NewGameEvent(InvalidPosition)
+	UnknownItemEvent          = NewGameEvent(UnknownItem)
+	UnknownAdvancementEvent   = NewGameEvent(UnknownAdvancement)
+	UnknownGameRuleEvent      = NewGameEvent(UnknownGameRule)
+	PlayerDiedEvent           = NewGameEvent(PlayerDied)
+	PlayerJoinedEvent         = NewGameEvent(PlayerJoined)
+	PlayerLeftEvent           = NewGameEvent(PlayerLeft)
+	PlayerLoginEvent          = NewGameEvent(PlayerLogin)
+	PlayerLostConnectionEvent = NewGameEvent(PlayerLostConnection)
+	PlayerRejectedEvent       = NewGameEvent(PlayerRejected)
+	PlayerUUIDEvent           = NewGameEvent(PlayerUUID)
+	RawLineEvent              = NewGameEvent(RawLine)
 )
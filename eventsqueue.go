@@ -17,15 +17,30 @@ func newEventsQueue() *eventsQueue {
 	}
 }
 
+// eventsQueueSize is the number of events buffered per event name, so the
+// responses logged in a burst, like the entries of a list, are not dropped
+// while the command reads the first ones.
+const eventsQueueSize = 64
+
 func (eq *eventsQueue) get(e string) <-chan events.GameEvent {
 	eq.mu.Lock()
 	defer eq.mu.Unlock()
 
-	_, ok := eq.q[e]
+	c, ok := eq.q[e]
 	if !ok {
-		eq.q[e] = make(chan events.GameEvent)
+		c = make(chan events.GameEvent, eventsQueueSize)
+		eq.q[e] = c
+	}
+	// Discard the events left over from previous commands, which timed out
+	// before reading them. The commands are serialized by the wrapper, so
+	// none is still waiting on them.
+	for {
+		select {
+		case <-c:
+		default:
+			return c
+		}
 	}
-	return eq.q[e]
 }
 
 func (eq *eventsQueue) push(ev events.GameEvent) {
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"fmt"
 	"log"
 	"os"
 	"os/signal"
@@ -10,34 +9,41 @@ import (
 )
 
 // In this example we are mimicking the "seed" commands, where when a player
-// says "seed" in-game, we are going to capture that message from the GameEvent
-// channel and call the Seed() function from the wrapper and have the wrapper
-// broadcast the return seed value to all players with the following message:
-// "[Server] The world seed is: 9785468184"
+// says "!seed" in-game, we are going to capture that message from the GameEvent
+// channel and dispatch it to the chat router, which calls the Seed() function
+// from the wrapper and answers the player with the following message:
+// "The world seed is: 9785468184"
 
 func main() {
-	c := make(chan os.Signal)
+	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt)
 
 	wpr := wrapper.NewDefaultWrapper("server.jar", 1024, 1024)
 	wpr.Start()
 	defer wpr.Stop()
 
+	router := wrapper.NewChatRouter(wpr, "!")
+	router.Handle(wrapper.ChatCommand{
+		Name:        "seed",
+		Description: "Shows the world seed",
+		Handler: func(c *wrapper.ChatContext) error {
+			seed, err := wpr.Seed()
+			if err != nil {
+				return err
+			}
+			return c.Replyf("The world seed is: %d", seed)
+		},
+	})
+
 	for {
 		select {
 		case e := <-wpr.GameEvents():
-			if e.String() == "player-say" {
-				switch e.Data["player_message"] {
-				case "seed":
-					seed, err := wpr.Seed()
-					if err != nil {
-						log.Println("err getting seed: ", err)
-						continue
-					}
-					wpr.Say(fmt.Sprintf("The world seed is: %d", seed))
-				default:
-					log.Println(e.String(), e.Data)
-				}
+			ok, err := router.Dispatch(e)
+			if err != nil {
+				log.Println("err running chat command: ", err)
+			}
+			if !ok {
+				log.Println(e.String(), e.Data)
 			}
 		case <-c:
 			wpr.Kill()
@@ -35,9 +35,23 @@ func (j *defaultJavaExec) Kill() error {
 	return j.cmd.Process.Kill()
 }
 
-func javaExecCmd(serverPath string, initialHeapSize, maxHeapSize int) *defaultJavaExec {
+// wait releases the resources of the exited java process.
+func (j *defaultJavaExec) wait() error {
+	return j.cmd.Wait()
+}
+
+func javaExecCmd(serverPath string, initialHeapSize, maxHeapSize int, jvmFlags ...string) *defaultJavaExec {
+	return newJavaExec("java", serverPath, initialHeapSize, maxHeapSize, jvmFlags...)
+}
+
+// newJavaExec returns the command running the server jar with the given
+// java executable.
+func newJavaExec(java, serverPath string, initialHeapSize, maxHeapSize int, jvmFlags ...string) *defaultJavaExec {
 	initialHeapFlag := fmt.Sprintf("-Xms%dM", initialHeapSize)
 	maxHeapFlag := fmt.Sprintf("-Xmx%dM", maxHeapSize)
-	cmd := exec.Command("java", initialHeapFlag, maxHeapFlag, "-jar", serverPath, "nogui")
+	args := []string{initialHeapFlag, maxHeapFlag}
+	args = append(args, jvmFlags...)
+	args = append(args, "-jar", serverPath, "nogui")
+	cmd := exec.Command(java, args...)
 	return &defaultJavaExec{cmd: cmd}
 }
@@ -0,0 +1,130 @@
+package wrapper
+
+import (
+	"sync"
+
+	"github.com/wlwanpan/minecraft-wrapper/events"
+)
+
+// feedSize is the buffer size of the subscriptions to a feed, the values of
+// slow subscribers being dropped when full as the game events are.
+const feedSize = 64
+
+// lineFeed broadcasts the console lines read by the wrapper.
+type lineFeed struct {
+	mu   sync.Mutex
+	subs map[chan string]struct{}
+}
+
+func newLineFeed() *lineFeed {
+	return &lineFeed{subs: map[chan string]struct{}{}}
+}
+
+// subscribe returns the lines read from now on, until the returned cancel
+// function is called.
+func (f *lineFeed) subscribe() (<-chan string, func()) {
+	c := make(chan string, feedSize)
+	f.mu.Lock()
+	f.subs[c] = struct{}{}
+	f.mu.Unlock()
+	var once sync.Once
+	return c, func() {
+		once.Do(func() {
+			f.mu.Lock()
+			delete(f.subs, c)
+			f.mu.Unlock()
+			close(c)
+		})
+	}
+}
+
+func (f *lineFeed) publish(line string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for c := range f.subs {
+		select {
+		case c <- line:
+		default:
+		}
+	}
+}
+
+// eventFeed broadcasts the game events handled by the wrapper, apart from
+// the channel returned by 'GameEvents'.
+type eventFeed struct {
+	mu   sync.Mutex
+	subs map[chan events.GameEvent]struct{}
+}
+
+func newEventFeed() *eventFeed {
+	return &eventFeed{subs: map[chan events.GameEvent]struct{}{}}
+}
+
+// subscribe returns the game events handled from now on, until the returned
+// cancel function is called.
+func (f *eventFeed) subscribe() (<-chan events.GameEvent, func()) {
+	c := make(chan events.GameEvent, feedSize)
+	f.mu.Lock()
+	f.subs[c] = struct{}{}
+	f.mu.Unlock()
+	var once sync.Once
+	return c, func() {
+		once.Do(func() {
+			f.mu.Lock()
+			delete(f.subs, c)
+			f.mu.Unlock()
+			close(c)
+		})
+	}
+}
+
+func (f *eventFeed) publish(ev events.GameEvent) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for c := range f.subs {
+		select {
+		case c <- ev:
+		default:
+		}
+	}
+}
+
+// stateFeed broadcasts the state events read by the wrapper, whether they
+// changed its state or not.
+type stateFeed struct {
+	mu   sync.Mutex
+	subs map[chan events.StateEvent]struct{}
+}
+
+func newStateFeed() *stateFeed {
+	return &stateFeed{subs: map[chan events.StateEvent]struct{}{}}
+}
+
+// subscribe returns the state events read from now on, until the returned
+// cancel function is called.
+func (f *stateFeed) subscribe() (<-chan events.StateEvent, func()) {
+	c := make(chan events.StateEvent, feedSize)
+	f.mu.Lock()
+	f.subs[c] = struct{}{}
+	f.mu.Unlock()
+	var once sync.Once
+	return c, func() {
+		once.Do(func() {
+			f.mu.Lock()
+			delete(f.subs, c)
+			f.mu.Unlock()
+			close(c)
+		})
+	}
+}
+
+func (f *stateFeed) publish(ev events.StateEvent) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for c := range f.subs {
+		select {
+		case c <- ev:
+		default:
+		}
+	}
+}
@@ -1,8 +1,10 @@
 module github.com/wlwanpan/minecraft-wrapper
 
-go 1.12
+go 1.14
 
 require (
 	github.com/looplab/fsm v0.1.0
 	github.com/mitchellh/mapstructure v1.4.0
+	github.com/peterh/liner v1.2.2
+	gopkg.in/yaml.v3 v3.0.1
 )
@@ -0,0 +1,342 @@
+package wrapper
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/wlwanpan/minecraft-wrapper/events"
+)
+
+// LogLayout is a structured log4j2 layout the server console output can be
+// configured with, see 'WriteLog4jConfig'. Unlike the text formats, the
+// structured layouts carry the full date, the logger name and the thrown
+// exceptions of each log event.
+type LogLayout string
+
+const (
+	// JSONLogLayout reads one JSON object per line. Both the fields of the
+	// log4j2 JsonLayout (compact, eventEol) and of the JsonTemplateLayout
+	// default ECS template are supported.
+	JSONLogLayout LogLayout = "json"
+	// XMLLogLayout reads the events of the log4j2 XMLLayout, an event
+	// spanning one or several lines.
+	XMLLogLayout LogLayout = "xml"
+)
+
+// maxXMLEventLines bounds the lines buffered for a single XML event, in case
+// the closing tag never makes it to the console.
+const maxXMLEventLines = 1000
+
+// structuredLogProfile dispatches the structured log events on their thread
+// name, which is reported by all the server distributions.
+var structuredLogProfile = &LogProfile{
+	Name: "structured",
+	dispatch: map[logFormat][]logDispatch{
+		structuredLogFormat: threadDispatchTable,
+	},
+}
+
+// NewStructuredLogParser returns a LogParser reading the server output
+// printed with the given structured layout. Lines that are not structured
+// (printed before log4j2 is initialized for example) fall back to the text
+// formats detected by the 'AutoLogProfile'.
+func NewStructuredLogParser(layout LogLayout) LogParser {
+	p := &structuredLogParser{
+		layout: layout,
+		state:  &logParserState{},
+	}
+	return p.parse
+}
+
+type structuredLogParser struct {
+	layout LogLayout
+	state  *logParserState
+	// xmlEvent buffers the lines of the XML event being read.
+	xmlEvent []string
+}
+
+func (p *structuredLogParser) parse(line string, tick int) (events.Event, events.EventType) {
+	switch p.layout {
+	case JSONLogLayout:
+		return p.parseJSON(line, tick)
+	case XMLLogLayout:
+		return p.parseXML(line, tick)
+	}
+	return parseProfileLine(AutoLogProfile, line, p.state, tick)
+}
+
+func (p *structuredLogParser) parseJSON(line string, tick int) (events.Event, events.EventType) {
+	trimmed := strings.TrimSpace(line)
+	if !strings.HasPrefix(trimmed, "{") {
+		return parseProfileLine(AutoLogProfile, line, p.state, tick)
+	}
+	ll, err := decodeJSONLogEvent([]byte(trimmed))
+	if err != nil {
+		return handleRawLine(line, p.state.last, tick)
+	}
+	return parseLogEntry(structuredLogProfile, ll, p.state, tick)
+}
+
+func (p *structuredLogParser) parseXML(line string, tick int) (events.Event, events.EventType) {
+	if p.xmlEvent == nil {
+		start := strings.Index(line, "<Event ")
+		if start < 0 {
+			trimmed := strings.TrimSpace(line)
+			if strings.HasPrefix(trimmed, "<?xml") || strings.HasPrefix(trimmed, "<Events") || strings.HasPrefix(trimmed, "</Events>") {
+				return events.NilEvent, events.TypeNil
+			}
+			return parseProfileLine(AutoLogProfile, line, p.state, tick)
+		}
+		line = line[start:]
+	}
+
+	p.xmlEvent = append(p.xmlEvent, line)
+	if !strings.Contains(line, "</Event>") {
+		if len(p.xmlEvent) < maxXMLEventLines {
+			return events.NilEvent, events.TypeNil
+		}
+		p.xmlEvent = nil
+		return handleRawLine(line, p.state.last, tick)
+	}
+
+	raw := strings.Join(p.xmlEvent, "\n")
+	p.xmlEvent = nil
+	ll, err := decodeXMLLogEvent([]byte(raw[:strings.LastIndex(raw, "</Event>")+len("</Event>")]))
+	if err != nil {
+		return handleRawLine(raw, p.state.last, tick)
+	}
+	return parseLogEntry(structuredLogProfile, ll, p.state, tick)
+}
+
+type jsonLogEvent struct {
+	// JsonLayout fields.
+	TimeMillis int64           `json:"timeMillis"`
+	Instant    *logInstant     `json:"instant"`
+	Thread     string          `json:"thread"`
+	Level      string          `json:"level"`
+	LoggerName string          `json:"loggerName"`
+	Message    string          `json:"message"`
+	Thrown     json.RawMessage `json:"thrown"`
+	// ECS template fields.
+	Timestamp       string `json:"@timestamp"`
+	LogLevel        string `json:"log.level"`
+	LogLogger       string `json:"log.logger"`
+	ThreadName      string `json:"process.thread.name"`
+	ErrorType       string `json:"error.type"`
+	ErrorMessage    string `json:"error.message"`
+	ErrorStackTrace string `json:"error.stack_trace"`
+}
+
+type logInstant struct {
+	EpochSecond  int64 `json:"epochSecond" xml:"epochSecond,attr"`
+	NanoOfSecond int64 `json:"nanoOfSecond" xml:"nanoOfSecond,attr"`
+}
+
+type logThrown struct {
+	Name               string               `json:"name" xml:"name,attr"`
+	Message            string               `json:"message" xml:"message,attr"`
+	ExtendedStackTrace []logStackTraceFrame `json:"extendedStackTrace" xml:"ExtendedStackTrace>ExtendedStackTraceItem"`
+	// StackTrace is the stack trace printed as text, by the log4j2 config
+	// written from 'WriteLog4jConfig'.
+	StackTrace string `json:"-" xml:",chardata"`
+}
+
+type logStackTraceFrame struct {
+	Class  string `json:"class" xml:"class,attr"`
+	Method string `json:"method" xml:"method,attr"`
+	File   string `json:"file" xml:"file,attr"`
+	Line   int    `json:"line" xml:"line,attr"`
+}
+
+// String formats the thrown exception the same way java prints it.
+func (t *logThrown) String() string {
+	if s := strings.TrimSpace(t.StackTrace); s != "" {
+		return s
+	}
+	if t.Name == "" {
+		return ""
+	}
+	b := strings.Builder{}
+	b.WriteString(t.Name)
+	if t.Message != "" {
+		b.WriteString(": " + t.Message)
+	}
+	for _, f := range t.ExtendedStackTrace {
+		fmt.Fprintf(&b, "\n\tat %s.%s(%s:%d)", f.Class, f.Method, f.File, f.Line)
+	}
+	return b.String()
+}
+
+func decodeJSONLogEvent(b []byte) (*logLine, error) {
+	ev := &jsonLogEvent{}
+	if err := json.Unmarshal(b, ev); err != nil {
+		return nil, err
+	}
+
+	ll := &logLine{
+		format:     structuredLogFormat,
+		threadName: firstNonEmpty(ev.Thread, ev.ThreadName),
+		level:      firstNonEmpty(ev.Level, ev.LogLevel),
+		logger:     firstNonEmpty(ev.LoggerName, ev.LogLogger),
+		output:     trimEOL(ev.Message),
+		time:       decodeLogTime(ev.TimeMillis, ev.Instant, ev.Timestamp),
+		thrown:     strings.TrimSpace(ev.ErrorStackTrace),
+	}
+	if ll.thrown == "" && ev.ErrorType != "" {
+		ll.thrown = strings.TrimSuffix(ev.ErrorType+": "+ev.ErrorMessage, ": ")
+	}
+	if len(ev.Thrown) > 0 && ll.thrown == "" {
+		thrown := &logThrown{}
+		if err := json.Unmarshal(ev.Thrown, &thrown.StackTrace); err != nil {
+			if err := json.Unmarshal(ev.Thrown, thrown); err != nil {
+				return nil, err
+			}
+		}
+		ll.thrown = thrown.String()
+	}
+	ll.timestamp = ll.time.Format(time.RFC3339)
+	return ll, nil
+}
+
+type xmlLogEvent struct {
+	TimeMillis int64       `xml:"timeMillis,attr"`
+	Timestamp  string      `xml:"timestamp,attr"`
+	Thread     string      `xml:"thread,attr"`
+	Level      string      `xml:"level,attr"`
+	LoggerName string      `xml:"loggerName,attr"`
+	Instant    *logInstant `xml:"Instant"`
+	Message    string      `xml:"Message"`
+	Thrown     *logThrown  `xml:"Thrown"`
+}
+
+func decodeXMLLogEvent(b []byte) (*logLine, error) {
+	ev := &xmlLogEvent{}
+	if err := xml.Unmarshal(b, ev); err != nil {
+		return nil, err
+	}
+
+	ll := &logLine{
+		format:     structuredLogFormat,
+		threadName: ev.Thread,
+		level:      ev.Level,
+		logger:     ev.LoggerName,
+		output:     trimEOL(ev.Message),
+		time:       decodeLogTime(ev.TimeMillis, ev.Instant, ev.Timestamp),
+	}
+	if ev.Thrown != nil {
+		ll.thrown = ev.Thrown.String()
+	}
+	ll.timestamp = ll.time.Format(time.RFC3339)
+	return ll, nil
+}
+
+// logTimeLayouts are the layouts of the string timestamps supported, the
+// first one being printed by the log4j2 config from 'WriteLog4jConfig'.
+var logTimeLayouts = []string{
+	"2006-01-02T15:04:05.000-0700",
+	time.RFC3339Nano,
+}
+
+func decodeLogTime(millis int64, instant *logInstant, timestamp string) time.Time {
+	if instant != nil && instant.EpochSecond > 0 {
+		return time.Unix(instant.EpochSecond, instant.NanoOfSecond)
+	}
+	if millis > 0 {
+		return time.Unix(0, millis*int64(time.Millisecond))
+	}
+	for _, layout := range logTimeLayouts {
+		if t, err := time.Parse(layout, timestamp); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// log4jPatterns are the console patterns printing the structured layouts.
+// They go through the PatternLayout, since the log4j2 JsonLayout and
+// XMLLayout depend on jackson which is not shipped with the server.
+var log4jPatterns = map[LogLayout]string{
+	JSONLogLayout: `{"@timestamp":"%d{yyyy-MM-dd'T'HH:mm:ss.SSSZ}","log.level":"%level",` +
+		`"process.thread.name":"%enc{%t}{JSON}","log.logger":"%enc{%c}{JSON}",` +
+		`"message":"%enc{%m}{JSON}","error.stack_trace":"%enc{%throwable}{JSON}"}%n`,
+	XMLLogLayout: `<Event timestamp="%d{yyyy-MM-dd'T'HH:mm:ss.SSSZ}" level="%level" ` +
+		`thread="%enc{%t}{XML}" loggerName="%enc{%c}{XML}">` +
+		`<Message>%enc{%m}{XML}</Message><Thrown>%enc{%throwable}{XML}</Thrown></Event>%n`,
+}
+
+const log4jConfigTemplate = `<?xml version="1.0" encoding="UTF-8"?>
+<!-- Generated by minecraft-wrapper: the console prints the %s layout, while
+     logs/latest.log keeps the vanilla format. -->
+<Configuration status="WARN">
+    <Appenders>
+        <Console name="SysOut" target="SYSTEM_OUT">
+            <PatternLayout alwaysWriteExceptions="false">
+                <Pattern>%s</Pattern>
+            </PatternLayout>
+        </Console>
+        <RollingRandomAccessFile name="File" fileName="logs/latest.log" filePattern="logs/%%d{yyyy-MM-dd}-%%i.log.gz">
+            <PatternLayout pattern="[%%d{HH:mm:ss}] [%%t/%%level]: %%msg%%n" />
+            <Policies>
+                <TimeBasedTriggeringPolicy />
+                <OnStartupTriggeringPolicy />
+            </Policies>
+        </RollingRandomAccessFile>
+    </Appenders>
+    <Loggers>
+        <Root level="info">
+            <filters>
+                <MarkerFilter marker="NETWORK_PACKETS" onMatch="DENY" onMismatch="NEUTRAL" />
+            </filters>
+            <AppenderRef ref="SysOut"/>
+            <AppenderRef ref="File"/>
+        </Root>
+    </Loggers>
+</Configuration>
+`
+
+// Log4jConfig returns a log4j2 configuration printing the server console
+// output with the given layout. The '%enc' converter it relies on requires
+// a server shipping log4j 2.12 or later (minecraft 1.17+).
+func Log4jConfig(layout LogLayout) ([]byte, error) {
+	pattern, ok := log4jPatterns[layout]
+	if !ok {
+		return nil, fmt.Errorf("unknown log layout: %s", layout)
+	}
+	escaped := bytes.Buffer{}
+	if err := xml.EscapeText(&escaped, []byte(pattern)); err != nil {
+		return nil, err
+	}
+	return []byte(fmt.Sprintf(log4jConfigTemplate, layout, escaped.String())), nil
+}
+
+// WriteLog4jConfig writes the log4j2 configuration from 'Log4jConfig' to
+// the given path. The server must be started with the JVM flag returned by
+// 'Log4jConfigFlag' for the configuration to be picked up, for example:
+// NewWrapper(NewDefaultConsole(server, 1024, 1024, Log4jConfigFlag(path)), NewStructuredLogParser(JSONLogLayout))
+func WriteLog4jConfig(path string, layout LogLayout) error {
+	config, err := Log4jConfig(layout)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, config, 0644)
+}
+
+// Log4jConfigFlag returns the JVM flag loading the log4j2 configuration
+// from the given path.
+func Log4jConfigFlag(path string) string {
+	return "-Dlog4j.configurationFile=" + path
+}
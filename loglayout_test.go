@@ -0,0 +1,138 @@
+package wrapper
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wlwanpan/minecraft-wrapper/events"
+)
+
+func testStructuredLog(t *testing.T, layout LogLayout, filename string, expected []events.GameEvent) {
+	actual := parseProfileTestLog(t, NewStructuredLogParser(layout), filename)
+	if len(actual) != len(expected) {
+		t.Errorf("%s: wrong event count detected: actual=%d, expected=%d (%v)", filename, len(actual), len(expected), actual)
+		return
+	}
+	for i, ev := range expected {
+		if !ev.Is(actual[i]) {
+			t.Errorf("%s: event mismatched at %d: actual=%s, expected=%s", filename, i, actual[i].String(), ev.String())
+			continue
+		}
+		gev, ok := actual[i].(events.GameEvent)
+		if !ok {
+			continue
+		}
+		if !ev.Time.IsZero() && !ev.Time.Equal(gev.Time) {
+			t.Errorf("%s: time mismatch for event '%s': actual=%s, expected=%s", filename, ev.String(), gev.Time, ev.Time)
+		}
+		for k, v := range ev.Data {
+			if gev.Data[k] != v {
+				t.Errorf("%s: data '%s' mismatch for event '%s': actual=%q, expected=%q", filename, k, ev.String(), gev.Data[k], v)
+			}
+		}
+	}
+}
+
+func TestJSONLogLayout(t *testing.T) {
+	testStructuredLog(t, JSONLogLayout, "testdata/layouts/json_log", []events.GameEvent{
+		{
+			Name: events.Version,
+			Time: time.Date(2021, 7, 21, 10, 15, 34, 254000000, time.UTC),
+			Data: map[string]string{"version": "1.17.1"},
+		},
+		{Name: events.Starting},
+		{Name: events.Started},
+		{
+			Name: events.PlayerUUID,
+			Time: time.Unix(1626862600, 120000000),
+			Data: map[string]string{"player_name": "player1"},
+		},
+		{
+			Name: events.PlayerJoined,
+			Time: time.Unix(0, 1626862600150*int64(time.Millisecond)),
+			Data: map[string]string{"player_name": "player1"},
+		},
+		{
+			Name: events.Exception,
+			Data: map[string]string{
+				"level":       "ERROR",
+				"logger":      "net.minecraft.server.MinecraftServer",
+				"message":     "Encountered an unexpected exception",
+				"exception":   "java.lang.NullPointerException: null",
+				"stack_trace": "java.lang.NullPointerException: null\n\tat net.minecraft.server.MinecraftServer.a(SourceFile:834)\n\tat java.lang.Thread.run(Thread.java:748)",
+			},
+		},
+		{
+			Name: events.Exception,
+			Data: map[string]string{
+				"exception":   "java.io.IOException: No space left on device",
+				"stack_trace": "java.io.IOException: No space left on device\n\tat net.minecraft.world.level.chunk.storage.RegionFile.write(RegionFile.java:212)",
+			},
+		},
+		{Name: events.PlayerLeft, Data: map[string]string{"player_name": "player1"}},
+	})
+}
+
+func TestXMLLogLayout(t *testing.T) {
+	testStructuredLog(t, XMLLogLayout, "testdata/layouts/xml_log", []events.GameEvent{
+		{
+			Name: events.Version,
+			Time: time.Date(2021, 7, 21, 10, 15, 34, 254000000, time.UTC),
+			Data: map[string]string{"version": "1.17.1"},
+		},
+		{Name: events.Starting},
+		{Name: events.Started},
+		{
+			Name: events.PlayerUUID,
+			Time: time.Unix(1626862600, 120000000),
+			Data: map[string]string{"player_name": "player1"},
+		},
+		{
+			Name: events.PlayerSay,
+			Data: map[string]string{"player_name": "player1", "player_message": "hello"},
+		},
+		{
+			Name: events.Exception,
+			Data: map[string]string{
+				"exception": "java.lang.NullPointerException: null",
+				"logger":    "net.minecraft.server.MinecraftServer",
+			},
+		},
+		{
+			Name: events.Exception,
+			Data: map[string]string{
+				"exception": "java.io.IOException: No space left on device",
+			},
+		},
+		{Name: events.PlayerLeft, Data: map[string]string{"player_name": "player1"}},
+	})
+}
+
+func TestLog4jConfig(t *testing.T) {
+	for _, layout := range []LogLayout{JSONLogLayout, XMLLogLayout} {
+		config, err := Log4jConfig(layout)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		cfg := struct {
+			Pattern string `xml:"Appenders>Console>PatternLayout>Pattern"`
+		}{}
+		if err := xml.Unmarshal(config, &cfg); err != nil {
+			t.Errorf("%s: invalid log4j2 config: %s", layout, err)
+			continue
+		}
+		if cfg.Pattern != log4jPatterns[layout] {
+			t.Errorf("%s: console pattern mismatch: %s", layout, cfg.Pattern)
+		}
+	}
+
+	if _, err := Log4jConfig("yaml"); err == nil {
+		t.Error("unknown layouts should error")
+	}
+	if flag := Log4jConfigFlag("log4j2.xml"); !strings.HasPrefix(flag, "-Dlog4j.configurationFile=") {
+		t.Errorf("unexpected log4j config flag: %s", flag)
+	}
+}
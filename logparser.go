@@ -4,6 +4,8 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/wlwanpan/minecraft-wrapper/events"
 )
@@ -16,142 +18,908 @@ import (
 // - Nil: event that hold no value and usually ignored/
 type LogParser func(string, int) (events.Event, events.EventType)
 
-type logLine struct {
-	timestamp  string
-	threadName string
-	level      string
-	output     string
-}
-
-var logRegex = regexp.MustCompile(`(\[[0-9:]*\]) \[([A-z(-| )#0-9]*)\/([A-z #]*)\]: (.*)`)
-
-func parseToLogLine(line string) *logLine {
-	matches := logRegex.FindAllStringSubmatch(line, 4)
-	return &logLine{
-		timestamp:  matches[0][1],
-		threadName: matches[0][2],
-		level:      matches[0][3],
-		output:     matches[0][4],
-	}
-}
-
-var stateEventToRegexp = map[string]*regexp.Regexp{
-	events.Started:  regexp.MustCompile(`^Done (?s)(.*)! For help`),
-	events.Starting: regexp.MustCompile(`^Starting Minecraft server on (.*)`),
-	events.Stopping: regexp.MustCompile(`^Stopping (.*) server`),
-	events.Saving:   regexp.MustCompile(`^Saving the game`),
-	events.Saved:    regexp.MustCompile(`^Saved (?s)(.*)`),
-}
-
-var gameEventToRegex = map[string]*regexp.Regexp{
-	events.Banned:          regexp.MustCompile(`^Banned (?s)(.*): (?s)(.*)`),
-	events.BanList:         regexp.MustCompile(`^There are (no|\d+) bans(:|\z)`),
-	events.BanListEntry:    regexp.MustCompile(`(?s)(.*) was banned by Server: (.*)`),
-	events.DataGet:         regexp.MustCompile(`(?s)(.*) has the following (entity|block|storage) data: (.*)`),
-	events.DataGetNoEntity: regexp.MustCompile(`^No (entity|block|storage) was found`),
-	events.DefaultGameMode: regexp.MustCompile(`^The default game mode is now (Survival|Creative|Adventure|Spectator) Mode`),
-	events.Difficulty:      regexp.MustCompile(`^The difficulty (?s)(.*)`),
-	events.ExperienceAdd:   regexp.MustCompile(`^Gave ([0-9]+) experience (levels|points) to (?s)(.*)`),
-	events.ExperienceQuery: regexp.MustCompile(`(?s)(.*) has ([0-9]+) experience (levels|points)`),
-	events.Give:            regexp.MustCompile(`^Gave ([0-9]+) \[(?s)(.*) (?s)(.*)\] to (?s)(.*)`),
-	events.NoPlayerFound:   regexp.MustCompile(`^No player was found`),
-	// TODO: There is an insane amount of death messages: https://minecraft.gamepedia.com/Death_messages, support all?
-	events.PlayerDied:       regexp.MustCompile(`(?s)(.*) (was shot|was pummeled|drowned|blew up|was blown up|was killed by|hit the ground|fell|was slain|suffocated)(.*)`),
-	events.PlayerJoined:     regexp.MustCompile(`(?s)(.*) joined the game`),
-	events.PlayerLeft:       regexp.MustCompile(`(?s)(.*) left the game`),
-	events.PlayerUUID:       regexp.MustCompile(`^UUID of player (?s)(.*) is (?s)(.*)`),
-	events.PlayerSay:        regexp.MustCompile(`<(?s)(.*)> (?s)(.*)`),
-	events.Kicked:           regexp.MustCompile(`^Kicked (?s)(.*): (.*)`),
-	events.Seed:             regexp.MustCompile(`^Seed: (.*)`),
-	events.ServerOverloaded: regexp.MustCompile(`^Can't keep up! Is the server overloaded\? Running ([0-9]+)ms or ([0-9]+) ticks behind`),
-	events.TimeIs:           regexp.MustCompile(`^The time is (?s)(.*)`),
-	events.UnknownItem:      regexp.MustCompile(`^Unknown item (.*)`),
-	events.Version:          regexp.MustCompile(`^Starting minecraft server version (.*)`),
-	events.WhisperTo:        regexp.MustCompile(`^You whisper to (?s)(.*): (.*)`),
-}
-
-var activeGameEvents = map[string]*regexp.Regexp{
-	events.PlayerDied:       gameEventToRegex[events.PlayerDied],
-	events.PlayerJoined:     gameEventToRegex[events.PlayerJoined],
-	events.PlayerLeft:       gameEventToRegex[events.PlayerLeft],
-	events.PlayerUUID:       gameEventToRegex[events.PlayerUUID],
-	events.PlayerSay:        gameEventToRegex[events.PlayerSay],
-	events.ServerOverloaded: gameEventToRegex[events.ServerOverloaded],
-	events.TimeIs:           gameEventToRegex[events.TimeIs],
-	events.Version:          gameEventToRegex[events.Version],
+// logMatcher decodes a log line output to an event. The literal prefix and
+// substring are checked before running the regex, so most lines are rejected
+// without ever reaching the regex engine.
+type logMatcher struct {
+	// event is the name used to activate the matcher, see 'registerGameEvent'.
+	event    string
+	prefix   string
+	contains string
+	regex    *regexp.Regexp
+	// matchFunc replaces the regex for outputs too varied for a single one.
+	matchFunc func(string) []string
+	handle    logHandler
+	// since and until bound the game versions [since, until) logging the
+	// matched output, the zero value leaving the bound open.
+	since gameVersion
+	until gameVersion
+	// header is kept by the parser once the matcher matched, for the
+	// matchers that follow it to decode the next entry.
+	header string
+	// follows restricts the matcher to the entry following the given
+	// header, for outputs too loosely worded to be told apart on their own.
+	follows string
+}
+
+type logHandler func([]string, int) (events.GameEvent, events.EventType)
+
+func (m *logMatcher) match(output string) []string {
+	if m.prefix != "" && !strings.HasPrefix(output, m.prefix) {
+		return nil
+	}
+	if m.contains != "" && !strings.Contains(output, m.contains) {
+		return nil
+	}
+	if m.matchFunc != nil {
+		return m.matchFunc(output)
+	}
+	return m.regex.FindStringSubmatch(output)
+}
+
+// logDispatch holds the matchers to run, in order of priority, for the lines
+// logged by a given thread.
+type logDispatch struct {
+	thread string
+	state  []*logMatcher
+	game   []*logMatcher
+}
+
+// advancementRegex matches the 'advancement' command successes, the
+// advancements being either a single one, a count or a criterion.
+var advancementRegex = regexp.MustCompile(`^(Granted|Revoked) (the advancement \[(.*)\]|(\d+) advancements|criterion '(.*)' of advancement \[(.*)\]) (to|from) (.*)`)
+
+var stateEventMatchers = []*logMatcher{
+	{event: events.Started, prefix: "Done ", regex: regexp.MustCompile(`^Done (?s)(.*)! For help`)},
+	{event: events.Starting, prefix: "Starting Minecraft server on ", regex: regexp.MustCompile(`^Starting Minecraft server on (.*)`)},
+	{event: events.Stopping, prefix: "Stopping ", regex: regexp.MustCompile(`^Stopping (the )?server`)},
+	{event: events.Saving, prefix: "Saving the game", regex: regexp.MustCompile(`^Saving the game`)},
+	{event: events.Saved, prefix: "Saved ", regex: regexp.MustCompile(`^Saved (?s)(.*)`)},
+}
+
+var gameEventMatchers = []*logMatcher{
+	{
+		event:  events.PlayerSay,
+		prefix: "<",
+		regex:  regexp.MustCompile(`<(?s)(.*)> (?s)(.*)`),
+		handle: handlePlayerSayEvent,
+	},
+	{
+		event:  events.PlayerSay,
+		prefix: "[Not Secure] <",
+		regex:  regexp.MustCompile(`^\[Not Secure\] <(?s)(.*)> (?s)(.*)`),
+		handle: handlePlayerSayEvent,
+		since:  versionSecureChat,
+	},
+	{
+		event:  events.Version,
+		prefix: "Starting minecraft server version ",
+		regex:  regexp.MustCompile(`^Starting minecraft server version (.*)`),
+		handle: handleVersionEvent,
+	},
+	{
+		event:  events.TimeIs,
+		prefix: "The time is ",
+		regex:  regexp.MustCompile(`^The time is (?s)(.*)`),
+		handle: handleTimeEvent,
+	},
+	{
+		event:  events.TimeIs,
+		prefix: "Time is ",
+		regex:  regexp.MustCompile(`^Time is (?s)(.*)`),
+		handle: handleTimeEvent,
+		until:  versionFlattening,
+	},
+	{
+		event:  events.ServerOverloaded,
+		prefix: "Can't keep up!",
+		regex:  regexp.MustCompile(`^Can't keep up! Is the server overloaded\? Running ([0-9]+)ms or ([0-9]+) ticks behind`),
+		handle: handleServerOverloaded,
+	},
+	{
+		event:  events.Seed,
+		prefix: "Seed: ",
+		regex:  regexp.MustCompile(`^Seed: (.*)`),
+		handle: handleSeed,
+	},
+	{
+		event:  events.NoPlayerFound,
+		prefix: "No player was found",
+		regex:  regexp.MustCompile(`^No player was found`),
+		handle: cmdEventHandler(events.NoPlayerFound),
+	},
+	{
+		event:  events.NoPlayerFound,
+		prefix: "That player cannot be found",
+		regex:  regexp.MustCompile(`^That player cannot be found`),
+		handle: cmdEventHandler(events.NoPlayerFound),
+		until:  versionFlattening,
+	},
+	{
+		// The game profile of a player name is unknown to the server.
+		event:  events.NoPlayerFound,
+		prefix: "That player does not exist",
+		regex:  regexp.MustCompile(`^That player does not exist`),
+		handle: cmdEventHandler(events.NoPlayerFound),
+		since:  versionFlattening,
+	},
+	{
+		// Also logged by 'data get entity', which waits on this event too.
+		event:  events.NoEntityFound,
+		prefix: "No entity was found",
+		regex:  regexp.MustCompile(`^No entity was found`),
+		handle: cmdEventHandler(events.NoEntityFound),
+	},
+	{
+		event:  events.NoEntityFound,
+		prefix: "Entity ",
+		regex:  regexp.MustCompile(`^Entity '(.*)' cannot be found`),
+		handle: cmdEventHandler(events.NoEntityFound),
+		until:  versionFlattening,
+	},
+	{
+		// Prior to 1.13, a name matching no online player is parsed as an
+		// entity UUID.
+		event:  events.NoEntityFound,
+		prefix: "The entity UUID provided ",
+		regex:  regexp.MustCompile(`^The entity UUID provided is in an invalid format`),
+		handle: cmdEventHandler(events.NoEntityFound),
+		until:  versionFlattening,
+	},
+	{
+		event:  events.UnknownItem,
+		prefix: "Unknown item ",
+		regex:  regexp.MustCompile(`^Unknown item (.*)`),
+		handle: cmdEventHandler(events.UnknownItem),
+	},
+	{
+		event:  events.UnknownItem,
+		prefix: "There is no such item with name ",
+		regex:  regexp.MustCompile(`^There is no such item with name (.*)`),
+		handle: cmdEventHandler(events.UnknownItem),
+		until:  versionFlattening,
+	},
+	{
+		event:  events.WhisperTo,
+		prefix: "You whisper to ",
+		regex:  regexp.MustCompile(`^You whisper to (?s)(.*): (.*)`),
+		handle: cmdEventHandler(events.WhisperTo),
+	},
+	{
+		event:  events.Kicked,
+		prefix: "Kicked ",
+		regex:  regexp.MustCompile(`^Kicked (?s)(.*): (.*)`),
+		handle: cmdEventHandler(events.Kicked),
+	},
+	{
+		event:  events.Kicked,
+		prefix: "Kicked ",
+		regex:  regexp.MustCompile(`^Kicked (?s)(.*) from the game`),
+		handle: cmdEventHandler(events.Kicked),
+		until:  versionFlattening,
+	},
+	{
+		event:  events.Banned,
+		prefix: "Banned IP ",
+		regex:  regexp.MustCompile(`^Banned IP (\S+): (?s)(.*)`),
+		handle: handleBannedIP,
+		since:  versionFlattening,
+	},
+	{
+		event:  events.Banned,
+		prefix: "Banned IP address ",
+		regex:  regexp.MustCompile(`^Banned IP address (\S+)`),
+		handle: handleLegacyBannedIP,
+		until:  versionFlattening,
+	},
+	{
+		event:  events.Banned,
+		prefix: "Banned ",
+		regex:  regexp.MustCompile(`^Banned (?s)(.*): (?s)(.*)`),
+		handle: handleBanned,
+	},
+	{
+		event:  events.Banned,
+		prefix: "Banned player ",
+		regex:  regexp.MustCompile(`^Banned player (.*)`),
+		handle: handleLegacyBanned,
+		until:  versionFlattening,
+	},
+	{
+		// Bans issued in game by an operator are broadcast to the console
+		// as "[admin: Banned griefer3: Griefing]".
+		event:  events.Banned,
+		prefix: "[",
+		regex:  regexp.MustCompile(`^\[(\S+): Banned (IP )?(\S+): (?s)(.*)\]$`),
+		handle: handleOperatorBanned,
+		since:  versionFlattening,
+	},
+	{
+		event:  events.Banned,
+		prefix: "[",
+		regex:  regexp.MustCompile(`^\[(\S+): Banned (IP address |player )(\S+)\]$`),
+		handle: handleOperatorBanned,
+		until:  versionFlattening,
+	},
+	{
+		event:  events.BanList,
+		prefix: "There are ",
+		regex:  regexp.MustCompile(`^There are (no|\d+) bans(:|\z)`),
+		handle: handleBanList,
+	},
+	{
+		// Ban list entries are only expected while waiting on a 'banlist'
+		// command, hence share the activation of the BanList event.
+		event:    events.BanList,
+		contains: " was banned by ",
+		regex:    regexp.MustCompile(`^(\S+) was banned by (\S+): (.*)`),
+		handle:   handleBanListEntry,
+	},
+	{
+		event:  events.BanList,
+		prefix: "There are 0 ",
+		regex:  regexp.MustCompile(`^There are (0) total banned (players|IP addresses)`),
+		handle: handleLegacyBanList,
+		until:  versionFlattening,
+	},
+	{
+		event:  events.BanList,
+		prefix: "There are ",
+		regex:  regexp.MustCompile(`^There are ([1-9]\d*) total banned (players|IP addresses)`),
+		handle: handleLegacyBanList,
+		until:  versionFlattening,
+		header: legacyBanListHeader,
+	},
+	{
+		// Prior to 1.13, all the ban list entries are logged on a single line
+		// following the header: "player1, player2".
+		event:   events.BanList,
+		regex:   regexp.MustCompile(`^([\w.:]+(, [\w.:]+)*)$`),
+		handle:  handleLegacyBanListEntries,
+		until:   versionFlattening,
+		follows: legacyBanListHeader,
+	},
+	{
+		event:  events.Pardon,
+		prefix: "Unbanned ",
+		regex:  regexp.MustCompile(`^Unbanned (IP )?(\S+)$`),
+		handle: handlePardon,
+		since:  versionFlattening,
+	},
+	{
+		event:  events.Pardon,
+		prefix: "Unbanned ",
+		regex:  regexp.MustCompile(`^Unbanned (IP address |player )(\S+)$`),
+		handle: handlePardon,
+		until:  versionFlattening,
+	},
+	{
+		event:  events.Pardon,
+		prefix: "Nothing changed. ",
+		regex:  regexp.MustCompile(`^Nothing changed\. (The player|That IP) isn't banned`),
+		handle: handlePardonFailed,
+	},
+	{
+		event:  events.Pardon,
+		prefix: "Invalid IP address or unknown player",
+		regex:  regexp.MustCompile(`^Invalid IP address or unknown player`),
+		handle: handlePardonFailed,
+		since:  versionFlattening,
+	},
+	{
+		event:  events.Pardon,
+		prefix: "Could not unban ",
+		regex:  regexp.MustCompile(`^Could not unban player (\S+)`),
+		handle: handlePardonFailed,
+		until:  versionFlattening,
+	},
+	{
+		event:  events.Pardon,
+		prefix: "You have entered an invalid IP address",
+		regex:  regexp.MustCompile(`^You have entered an invalid IP address`),
+		handle: handlePardonFailed,
+		until:  versionFlattening,
+	},
+	{
+		event:  events.DataGet,
+		prefix: "No ",
+		regex:  regexp.MustCompile(`^No (entity|block|storage) was found`),
+		handle: handleDataGetNoEntity,
+	},
+	{
+		event:    events.DataGet,
+		contains: " has the following ",
+		regex:    regexp.MustCompile(`(?s)(.*) has the following (entity|block|storage) data: (.*)`),
+		handle:   handleDataGet,
+	},
+	{
+		event:  events.DefaultGameMode,
+		prefix: "The default game mode is now ",
+		regex:  regexp.MustCompile(`^The default game mode is now (Survival|Creative|Adventure|Spectator) Mode`),
+		handle: handleDefaultGameMode,
+	},
+	{
+		event:  events.DefaultGameMode,
+		prefix: "The world's default game mode is now ",
+		regex:  regexp.MustCompile(`^The world's default game mode is now (Survival|Creative|Adventure|Spectator) Mode`),
+		handle: handleDefaultGameMode,
+		until:  versionFlattening,
+	},
+	{
+		event:  events.Difficulty,
+		prefix: "The difficulty ",
+		regex:  regexp.MustCompile(`^The difficulty (?s)(.*)`),
+		handle: handleDifficulty,
+	},
+	{
+		event:  events.Difficulty,
+		prefix: "Set game difficulty to ",
+		regex:  regexp.MustCompile(`^Set game difficulty to (?s)(.*)`),
+		handle: handleDifficulty,
+		until:  versionFlattening,
+	},
+	{
+		event:  events.GameMode,
+		prefix: "Set ",
+		regex:  regexp.MustCompile(`^Set (\S+)'s game mode to (Survival|Creative|Adventure|Spectator) Mode`),
+		handle: handleGameMode,
+	},
+	{
+		event:  events.GameRule,
+		prefix: "Gamerule ",
+		regex:  regexp.MustCompile(`^Gamerule (\S+) is (currently|now) set to: (.*)`),
+		handle: handleGameRule,
+	},
+	{
+		event:  events.GameRule,
+		prefix: "Game rule ",
+		regex:  regexp.MustCompile(`^Game rule (\S+) (has been updated) to (.*)`),
+		handle: handleGameRule,
+		until:  versionFlattening,
+	},
+	{
+		// Prior to 1.13, a game rule value is logged as "doDaylightCycle = true".
+		event:     events.GameRule,
+		contains:  " = ",
+		matchFunc: matchLegacyGameRule,
+		handle:    handleGameRule,
+		until:     versionFlattening,
+	},
+	{
+		// A command failing to parse is followed by the command, up to the
+		// error: "gamerule doWardenSpawning<--[HERE]".
+		event:  events.UnknownGameRule,
+		prefix: "Unknown or incomplete command",
+		regex:  regexp.MustCompile(`^Unknown or incomplete command`),
+		handle: handleCommandError,
+		since:  versionFlattening,
+		header: commandErrorHeader,
+	},
+	{
+		// An unknown rule fails to parse as a 'gamerule' command argument.
+		event:   events.UnknownGameRule,
+		prefix:  "gamerule ",
+		regex:   regexp.MustCompile(`^gamerule (\S+)<--\[HERE\]$`),
+		handle:  cmdEventHandler(events.UnknownGameRule),
+		since:   versionFlattening,
+		follows: commandErrorHeader,
+	},
+	{
+		event:  events.UnknownGameRule,
+		prefix: "No game rule called ",
+		regex:  regexp.MustCompile(`^No game rule called '(.*)' is available`),
+		handle: cmdEventHandler(events.UnknownGameRule),
+		until:  versionFlattening,
+	},
+	{
+		// The coordinates are formatted with '%f' since 1.13, and as a plain
+		// double before: "Teleported player1 to 10.500000, 64.000000, -3.500000".
+		event:  events.Teleport,
+		prefix: "Teleported ",
+		regex:  regexp.MustCompile(`^Teleported (.+) to (-?[\d.]+(E-?\d+)?), (-?[\d.]+(E-?\d+)?), (-?[\d.]+(E-?\d+)?)$`),
+		handle: handleTeleportToPosition,
+	},
+	{
+		event:  events.Teleport,
+		prefix: "Teleported ",
+		regex:  regexp.MustCompile(`^Teleported (.+) to (.+)$`),
+		handle: handleTeleportToEntity,
+	},
+	{
+		event:  events.InvalidPosition,
+		prefix: "Invalid position for teleport",
+		regex:  regexp.MustCompile(`^Invalid position for teleport`),
+		handle: cmdEventHandler(events.InvalidPosition),
+		since:  versionFlattening,
+	},
+	{
+		event:  events.InvalidPosition,
+		prefix: "The number you have entered ",
+		regex:  regexp.MustCompile(`^The number you have entered \((.*)\) is too (big|small)`),
+		handle: cmdEventHandler(events.InvalidPosition),
+		until:  versionFlattening,
+	},
+	{
+		event:  events.List,
+		prefix: "There are ",
+		regex:  regexp.MustCompile(`^There are (\d+) of a max( of)? (\d+) players online:\s?(.*)`),
+		handle: handleList,
+	},
+	{
+		// Prior to 1.13, the players are logged on the line following the
+		// header, which is only decoded when no player is online.
+		event:  events.List,
+		prefix: "There are ",
+		regex:  regexp.MustCompile(`^There are (0)/(\d+) players online:`),
+		handle: handleLegacyList,
+		until:  versionFlattening,
+	},
+	{
+		event:  events.List,
+		prefix: "There are ",
+		regex:  regexp.MustCompile(`^There are ([1-9]\d*)/(\d+) players online:`),
+		handle: handleLegacyListHeader,
+		until:  versionFlattening,
+		header: legacyListHeader,
+	},
+	{
+		event:   events.List,
+		regex:   regexp.MustCompile(`^(\S+ \([0-9a-fA-F-]+\)(, \S+ \([0-9a-fA-F-]+\))*)$`),
+		handle:  handleLegacyListEntries,
+		until:   versionFlattening,
+		follows: legacyListHeader,
+	},
+	{
+		event:  events.Operator,
+		prefix: "Made ",
+		regex:  regexp.MustCompile(`^Made (\S+) (a|no longer a) server operator`),
+		handle: handleOperator,
+	},
+	{
+		event:  events.Operator,
+		prefix: "Nothing changed. The player ",
+		regex:  regexp.MustCompile(`^Nothing changed\. The player (already is|is not) an operator`),
+		handle: handleOperatorFailed,
+	},
+	{
+		event:  events.Operator,
+		prefix: "Opped ",
+		regex:  regexp.MustCompile(`^(Opped) (\S+)`),
+		handle: handleLegacyOperator,
+		until:  versionFlattening,
+	},
+	{
+		event:  events.Operator,
+		prefix: "De-opped ",
+		regex:  regexp.MustCompile(`^(De-opped) (\S+)`),
+		handle: handleLegacyOperator,
+		until:  versionFlattening,
+	},
+	{
+		event:  events.Operator,
+		prefix: "Could not ",
+		regex:  regexp.MustCompile(`^Could not (op|de-op) (\S+)`),
+		handle: handleOperatorFailed,
+		until:  versionFlattening,
+	},
+	{
+		event:  events.Whitelist,
+		prefix: "Whitelist is now turned ",
+		regex:  regexp.MustCompile(`^Whitelist is now turned (on|off)`),
+		handle: handleWhitelistToggled,
+	},
+	{
+		event:  events.Whitelist,
+		prefix: "Turned ",
+		regex:  regexp.MustCompile(`^Turned (on|off) the whitelist`),
+		handle: handleWhitelistToggled,
+		until:  versionFlattening,
+	},
+	{
+		event:  events.Whitelist,
+		prefix: "Added ",
+		regex:  regexp.MustCompile(`^Added (\S+) to the whitelist`),
+		handle: whitelistPlayerHandler("add"),
+	},
+	{
+		event:  events.Whitelist,
+		prefix: "Removed ",
+		regex:  regexp.MustCompile(`^Removed (\S+) from the whitelist`),
+		handle: whitelistPlayerHandler("remove"),
+	},
+	{
+		event:  events.Whitelist,
+		prefix: "There are ",
+		regex:  regexp.MustCompile(`^There are (no|\d+) whitelisted players(: (.*))?$`),
+		handle: handleWhitelistList,
+		since:  versionFlattening,
+	},
+	{
+		event:  events.Whitelist,
+		prefix: "Reloaded the whitelist",
+		regex:  regexp.MustCompile(`^Reloaded the whitelist`),
+		handle: handleWhitelistReloaded,
+	},
+	{
+		event:  events.Whitelist,
+		prefix: "Whitelist is already turned ",
+		regex:  regexp.MustCompile(`^Whitelist is already turned (on|off)`),
+		handle: handleWhitelistFailed,
+	},
+	{
+		event:  events.Whitelist,
+		prefix: "Player is ",
+		regex:  regexp.MustCompile(`^Player is (already|not) whitelisted`),
+		handle: handleWhitelistFailed,
+	},
+	{
+		event:  events.Whitelist,
+		prefix: "Could not ",
+		regex:  regexp.MustCompile(`^Could not (add|remove) (\S+) (to|from) the whitelist`),
+		handle: handleWhitelistFailed,
+		until:  versionFlattening,
+	},
+	{
+		event:  events.Advancement,
+		prefix: "Granted ",
+		regex:  advancementRegex,
+		handle: handleAdvancement,
+	},
+	{
+		event:  events.Advancement,
+		prefix: "Revoked ",
+		regex:  advancementRegex,
+		handle: handleAdvancement,
+	},
+	{
+		event:  events.Advancement,
+		prefix: "Couldn't ",
+		regex:  regexp.MustCompile(`^Couldn't (grant|revoke) (.*)`),
+		handle: handleAdvancementFailed,
+	},
+	{
+		event:  events.UnknownAdvancement,
+		prefix: "Unknown advancement: ",
+		regex:  regexp.MustCompile(`^Unknown advancement: (.*)`),
+		handle: cmdEventHandler(events.UnknownAdvancement),
+	},
+	{
+		event:  events.UnknownAdvancement,
+		prefix: "No advancement was found by the name ",
+		regex:  regexp.MustCompile(`^No advancement was found by the name '(.*)'`),
+		handle: cmdEventHandler(events.UnknownAdvancement),
+	},
+	{
+		event:  events.ExperienceAdd,
+		prefix: "Gave ",
+		regex:  regexp.MustCompile(`^Gave ([0-9]+) experience (levels|points) to (?s)(.*)`),
+		handle: cmdEventHandler(events.ExperienceAdd),
+	},
+	{
+		event:  events.Give,
+		prefix: "Gave ",
+		regex:  regexp.MustCompile(`^Gave ([0-9]+) \[(?s)(.*)\] to (?s)(.*)`),
+		handle: cmdEventHandler(events.Give),
+	},
+	{
+		event:  events.Give,
+		prefix: "Given ",
+		regex:  regexp.MustCompile(`^Given \[(?s)(.*)\] \* ([0-9]+) to (?s)(.*)`),
+		handle: cmdEventHandler(events.Give),
+		until:  versionFlattening,
+	},
+	{
+		event:    events.ExperienceQuery,
+		contains: " experience ",
+		regex:    regexp.MustCompile(`(?s)(.*) has ([0-9]+) experience (levels|points)`),
+		handle:   handleExperienceQuery,
+	},
+	{
+		// Bukkit servers prefix the coordinates with the world name:
+		// "player1[/127.0.0.1:50120] logged in with entity id 212 at ([world]10.5, 64.0, -3.5)".
+		event:    events.PlayerLogin,
+		contains: "] logged in with entity id ",
+		regex:    regexp.MustCompile(`^(\S+)\[/(.+):(\d+)\] logged in with entity id (\d+) at \((\[(.*)\])?([^,]+), ([^,]+), ([^)]+)\)`),
+		handle:   handlePlayerLogin,
+	},
+	{
+		// Connections dropped before joining are logged with the game profile
+		// or the address of the client, instead of the player name:
+		// "com.mojang.authlib.GameProfile@7c2b[id=...,name=player3,...] (/127.0.0.1:51334) lost connection: ..."
+		event:    events.PlayerRejected,
+		contains: " lost connection: ",
+		regex:    regexp.MustCompile(`^(.*name=([^,\]}]+).*|/.*|\S+ \(/.*\)) lost connection: (.*)`),
+		handle:   handlePlayerRejected,
+	},
+	{
+		event:    events.PlayerLostConnection,
+		contains: " lost connection: ",
+		regex:    regexp.MustCompile(`^(\S+) lost connection: (.*)`),
+		handle:   handlePlayerLostConnection,
+	},
+	{
+		event:    events.PlayerJoined,
+		contains: " joined the game",
+		regex:    regexp.MustCompile(`(?s)(.*) joined the game`),
+		handle:   handlePlayerJoined,
+	},
+	{
+		event:    events.PlayerLeft,
+		contains: " left the game",
+		regex:    regexp.MustCompile(`(?s)(.*) left the game`),
+		handle:   handlePlayerLeft,
+	},
+	{
+		event:    events.PlayerAdvancement,
+		contains: " has ",
+		regex:    regexp.MustCompile(`^(\S+) has (made the advancement|completed the challenge|reached the goal) \[(.*)\]$`),
+		handle:   handlePlayerAdvancement,
+	},
+	{
+		event:     events.PlayerDied,
+		matchFunc: matchDeathMessage,
+		handle:    handlePlayerDied,
+	},
+}
+
+var authEventMatchers = []*logMatcher{
+	{
+		event:  events.PlayerUUID,
+		prefix: "UUID of player ",
+		regex:  regexp.MustCompile(`^UUID of player (?s)(.*) is (?s)(.*)`),
+		handle: handlePlayerUUIDEvent,
+	},
+}
+
+// threadDispatchTable maps the thread logging a line to the matchers that
+// can decode its output. Lines from any other thread (main, Worker-Main...)
+// carry no events and are skipped without running a single regex.
+var threadDispatchTable = []logDispatch{
+	{thread: "Server thread", state: stateEventMatchers, game: gameEventMatchers},
+	{thread: "User Authenticator", game: authEventMatchers},
+}
+
+// bukkitDispatchTable runs all the matchers on every line, since the thread
+// is not part of the Spigot and Paper log format.
+var bukkitDispatchTable = []logDispatch{
+	{state: stateEventMatchers, game: append(authEventMatchers[:len(authEventMatchers):len(authEventMatchers)], gameEventMatchers...)},
+}
+
+// The headers of the entries decoded by the matchers following them.
+const (
+	legacyBanListHeader = "legacy-ban-list"
+	legacyListHeader    = "legacy-list"
+	commandErrorHeader  = "command-error"
+)
+
+var (
+	activeGameEventsMu sync.Mutex
+	// activeGameEvents holds a map[string]bool of the game events being
+	// parsed. It is replaced as a whole on register so the log goroutine
+	// can read it without locking.
+	activeGameEvents atomic.Value
+)
+
+func init() {
+	activeGameEvents.Store(map[string]bool{
+		events.PlayerAdvancement:    true,
+		events.PlayerDied:           true,
+		events.PlayerJoined:         true,
+		events.PlayerLeft:           true,
+		events.PlayerLogin:          true,
+		events.PlayerLostConnection: true,
+		events.PlayerRejected:       true,
+		events.PlayerUUID:           true,
+		events.PlayerSay:            true,
+		events.ServerOverloaded:     true,
+		events.TimeIs:               true,
+		events.Version:              true,
+	})
 }
 
 func registerGameEvent(ev string) {
-	_, ok := activeGameEvents[ev]
-	if !ok {
-		activeGameEvents[ev] = gameEventToRegex[ev]
+	activeGameEventsMu.Lock()
+	defer activeGameEventsMu.Unlock()
+
+	active := activeGameEvents.Load().(map[string]bool)
+	if active[ev] {
+		return
 	}
+	updated := make(map[string]bool, len(active)+1)
+	for e := range active {
+		updated[e] = true
+	}
+	updated[ev] = true
+	activeGameEvents.Store(updated)
+}
+
+// EmitRawLines activates the RawLine events, which are not emitted by
+// default: the output that does not follow the log format, like the stack
+// traces of exceptions, is otherwise dropped.
+func EmitRawLines() {
+	registerGameEvent(events.RawLine)
 }
 
 func logParserFunc(line string, tick int) (events.Event, events.EventType) {
-	ll := parseToLogLine(line)
+	return parseProfileLine(AutoLogProfile, line, nil, tick)
+}
+
+// logParserState is the state kept by a LogParser across lines.
+type logParserState struct {
+	// last is the last log entry parsed, to attach continuation lines to.
+	last *logLine
+	// version is the game version detected from the logs, used to pick the
+	// matchers of the messages logged by that version.
+	version gameVersion
+	// lostConnection holds the reason of the players that lost connection,
+	// until their leave message is logged.
+	lostConnection map[string]string
+	// header is the header matched by the last entry, see 'logMatcher'.
+	header string
+}
+
+// NewLogParser returns a LogParser decoding the log output of the given
+// profile. The parser keeps track of the last log entry, so that lines that
+// are not log entries (java stack traces, multi-line messages...) can be
+// attached to the entry preceding them. It also detects the game version
+// on start, since the wording of the logs changes across versions.
+func NewLogParser(profile *LogProfile) LogParser {
+	state := &logParserState{}
+	return func(line string, tick int) (events.Event, events.EventType) {
+		return parseProfileLine(profile, line, state, tick)
+	}
+}
+
+func parseProfileLine(profile *LogProfile, line string, state *logParserState, tick int) (events.Event, events.EventType) {
+	ll, ok := parseToLogLine(line)
+	if !ok || !profile.supports(ll.format) {
+		if state == nil {
+			return handleRawLine(line, nil, tick)
+		}
+		return handleRawLine(line, state.last, tick)
+	}
+
+	return parseLogEntry(profile, ll, state, tick)
+}
+
+// parseLogEntry decodes a log entry, whether it was split from a text line
+// or decoded from a structured layout.
+func parseLogEntry(profile *LogProfile, ll *logLine, state *logParserState, tick int) (events.Event, events.EventType) {
+	version, header := gameVersion{}, ""
+	if state != nil {
+		state.last = ll
+		version, header = state.version, state.header
+	}
+	ev, t, next := parseLogLine(profile, ll, version, header, tick)
+	if state != nil {
+		state.header = next
+	}
+	if t == events.TypeNil && ll.thrown != "" {
+		ev, t = handleException(ll, tick)
+	}
+
+	gev, ok := ev.(events.GameEvent)
+	if !ok {
+		return ev, t
+	}
+	if state != nil {
+		state.track(gev)
+	}
+	if !ll.time.IsZero() {
+		gev.Time = ll.time
+	}
+	return gev, t
+}
+
+// track updates the parser state from a parsed game event, attaching the
+// reason a player lost connection to its following leave event.
+func (s *logParserState) track(gev events.GameEvent) {
+	switch {
+	case gev.Is(events.VersionEvent):
+		s.version, _ = parseGameVersion(gev.Data["version"])
+	case gev.Is(events.PlayerLostConnectionEvent):
+		if s.lostConnection == nil {
+			s.lostConnection = map[string]string{}
+		}
+		s.lostConnection[gev.Data["player_name"]] = gev.Data["reason"]
+	case gev.Is(events.PlayerLeftEvent):
+		name := gev.Data["player_name"]
+		if reason, ok := s.lostConnection[name]; ok {
+			gev.Data["reason"] = reason
+			delete(s.lostConnection, name)
+		}
+	}
+}
+
+// parseLogLine decodes the output of a log entry, following the given
+// header. It returns the header matched by the entry, if any.
+func parseLogLine(profile *LogProfile, ll *logLine, version gameVersion, header string, tick int) (events.Event, events.EventType, string) {
 	if ll.output == "" {
-		return events.NilEvent, events.TypeNil
+		return events.NilEvent, events.TypeNil, ""
+	}
+	d := profile.dispatchFor(ll)
+	if d == nil {
+		return events.NilEvent, events.TypeNil, ""
 	}
 
-	for e, reg := range stateEventToRegexp {
-		if reg.MatchString(ll.output) {
-			return events.NewStateEvent(e), events.TypeState
+	for _, m := range d.state {
+		if m.match(ll.output) != nil {
+			return events.NewStateEvent(m.event), events.TypeState, ""
 		}
 	}
-	for e, reg := range activeGameEvents {
-		matches := reg.FindStringSubmatch(ll.output)
-		if matches == nil {
+	active := activeGameEvents.Load().(map[string]bool)
+	for _, m := range d.game {
+		if !active[m.event] || !version.within(m.since, m.until) {
 			continue
 		}
-		switch e {
-		case events.BanList:
-			return handleBanList(matches)
-		case events.BanListEntry:
-			return handleBanListEntry(matches)
-		case events.Difficulty:
-			return handleDifficulty(matches)
-		case events.ExperienceQuery:
-			return handleExperienceQuery(matches)
-		case events.PlayerJoined:
-			return handlePlayerJoined(matches, tick)
-		case events.PlayerLeft:
-			return handlePlayerLeft(matches, tick)
-		case events.PlayerDied:
-			return handlePlayerDied(matches, tick)
-		case events.PlayerUUID:
-			return handlePlayerUUIDEvent(matches, tick)
-		case events.PlayerSay:
-			return handlePlayerSayEvent(matches, tick)
-		case events.Version:
-			return handleVersionEvent(matches)
-		case events.TimeIs:
-			return handleTimeEvent(matches)
-		case events.DataGet:
-			return handleDataGet(matches)
-		case events.DataGetNoEntity:
-			return handleDataGetNoEntity(matches)
-		case events.Seed:
-			return handleSeed(matches)
-		case events.ServerOverloaded:
-			return handleServerOverloaded(matches, tick)
-		case events.DefaultGameMode:
-			return handleDefaultGameMode(matches)
-		case events.Banned:
-			return handleBanned(matches)
-		case events.WhisperTo, events.ExperienceAdd, events.Give, events.NoPlayerFound,
-			events.Kicked, events.UnknownItem:
-			return events.NewGameEvent(e), events.TypeCmd
-		default:
-			gameEvent := events.NewGameEvent(e)
-			gameEvent.Tick = tick
-			return gameEvent, events.TypeGame
+		if m.follows != "" && m.follows != header {
+			continue
+		}
+		matches := m.match(ll.output)
+		if matches == nil {
+			continue
 		}
+		ev, t := m.handle(matches, tick)
+		return ev, t, m.header
+	}
+	return events.NilEvent, events.TypeNil, ""
+}
+
+// continuationRegex matches the lines continuing a log entry, which are
+// the lines of a stack trace: indented frames, exception headers, and the
+// 'Caused by' and '... n more' lines.
+var continuationRegex = regexp.MustCompile(`^(\s|Caused by: |Suppressed: |\.\.\. \d+ more|([a-zA-Z_$][\w$]*\.)+[\w$]*(Exception|Error|Throwable)\b)`)
+
+// handleRawLine decodes a line that does not follow the log format to a
+// RawLine event, if activated. When the line follows a log entry and looks
+// like a stack trace line, it is considered to be a continuation of that
+// entry and carries the entry details.
+func handleRawLine(line string, last *logLine, tick int) (events.Event, events.EventType) {
+	line = trimEOL(line)
+	if strings.TrimSpace(line) == "" {
+		return events.NilEvent, events.TypeNil
+	}
+	if !activeGameEvents.Load().(map[string]bool)[events.RawLine] {
+		return events.NilEvent, events.TypeNil
+	}
+	rlEvent := events.NewGameEvent(events.RawLine)
+	rlEvent.Tick = tick
+	rlEvent.Data = map[string]string{
+		"line": line,
+	}
+	if last != nil && continuationRegex.MatchString(line) {
+		rlEvent.Data["continuation"] = "true"
+		rlEvent.Data["entry_timestamp"] = last.timestamp
+		rlEvent.Data["entry_thread"] = last.threadName
+		rlEvent.Data["entry_level"] = last.level
+		rlEvent.Data["entry_output"] = last.output
+	}
+	return rlEvent, events.TypeGame
+}
+
+// handleException decodes a log entry carrying a thrown exception, which is
+// only reported by structured layouts.
+func handleException(ll *logLine, tick int) (events.GameEvent, events.EventType) {
+	exEvent := events.NewGameEvent(events.Exception)
+	exEvent.Tick = tick
+	exEvent.Data = map[string]string{
+		"thread":      ll.threadName,
+		"level":       ll.level,
+		"logger":      ll.logger,
+		"message":     ll.output,
+		"exception":   strings.SplitN(ll.thrown, "\n", 2)[0],
+		"stack_trace": ll.thrown,
+	}
+	return exEvent, events.TypeGame
+}
+
+func cmdEventHandler(e string) logHandler {
+	return func(matches []string, tick int) (events.GameEvent, events.EventType) {
+		return events.NewGameEvent(e), events.TypeCmd
 	}
-	return events.NilEvent, events.TypeNil
 }
 
-func handleBanList(matches []string) (events.GameEvent, events.EventType) {
+func handleBanList(matches []string, tick int) (events.GameEvent, events.EventType) {
 	blEvent := events.NewGameEvent(events.BanList)
 	blEvent.Data = map[string]string{
 		"entry_type": "header",
@@ -163,17 +931,60 @@ func handleBanList(matches []string) (events.GameEvent, events.EventType) {
 	return blEvent, events.TypeCmd
 }
 
-func handleBanListEntry(matches []string) (events.GameEvent, events.EventType) {
+func handleLegacyBanList(matches []string, tick int) (events.GameEvent, events.EventType) {
+	blEvent := events.NewGameEvent(events.BanList)
+	blEvent.Data = map[string]string{
+		"entry_type": "header",
+	}
+	if matches[1] != "0" {
+		// All the entries are reported back on a single line.
+		blEvent.Data["entry_count"] = "1"
+	}
+	return blEvent, events.TypeCmd
+}
+
+func handleLegacyBanListEntries(matches []string, tick int) (events.GameEvent, events.EventType) {
+	bleEvent := events.NewGameEvent(events.BanList)
+	bleEvent.Data = map[string]string{
+		"entry_type":  "item",
+		"entry_names": matches[1],
+	}
+	return bleEvent, events.TypeCmd
+}
+
+func handleBanListEntry(matches []string, tick int) (events.GameEvent, events.EventType) {
 	bleEvent := events.NewGameEvent(events.BanList)
 	bleEvent.Data = map[string]string{
 		"entry_type": "item",
 		"entry_name": matches[1],
-		"reason":     matches[2],
+		"source":     matches[2],
+		"reason":     matches[3],
 	}
 	return bleEvent, events.TypeCmd
 }
 
-func handleDifficulty(matches []string) (events.GameEvent, events.EventType) {
+func handlePardon(matches []string, tick int) (events.GameEvent, events.EventType) {
+	pdEvent := events.NewGameEvent(events.Pardon)
+	action := "pardon"
+	if strings.HasPrefix(matches[1], "IP") {
+		action = "pardon-ip"
+	}
+	pdEvent.Data = map[string]string{
+		"action": action,
+		"target": matches[2],
+	}
+	return pdEvent, events.TypeCmd
+}
+
+func handlePardonFailed(matches []string, tick int) (events.GameEvent, events.EventType) {
+	pdEvent := events.NewGameEvent(events.Pardon)
+	pdEvent.Data = map[string]string{
+		"error_message": matches[0],
+	}
+	return pdEvent, events.TypeCmd
+}
+
+func handleDifficulty(matches []string, tick int) (events.GameEvent, events.EventType) {
 	dfEvent := events.NewGameEvent(events.Difficulty)
 	dfEvent.Data = map[string]string{}
 	if strings.Contains(matches[1], "did not change") {
@@ -182,7 +993,186 @@ func handleDifficulty(matches []string) (events.GameEvent, events.EventType) {
 	return dfEvent, events.TypeCmd
 }
 
-func handleExperienceQuery(matches []string) (events.GameEvent, events.EventType) {
+func handleGameMode(matches []string, tick int) (events.GameEvent, events.EventType) {
+	gmEvent := events.NewGameEvent(events.GameMode)
+	gmEvent.Data = map[string]string{
+		"player_name": matches[1],
+		"game_mode":   strings.ToLower(matches[2]),
+	}
+	return gmEvent, events.TypeCmd
+}
+
+// legacyGameRules are the game rules of the versions prior to 1.13, whose
+// values are logged as "rule = value" with nothing else to tell them apart.
+var legacyGameRules = map[string]bool{
+	"announceAdvancements":       true,
+	"commandBlockOutput":         true,
+	"disableElytraMovementCheck": true,
+	"doDaylightCycle":            true,
+	"doEntityDrops":              true,
+	"doFireTick":                 true,
+	"doLimitedCrafting":          true,
+	"doMobLoot":                  true,
+	"doMobSpawning":              true,
+	"doTileDrops":                true,
+	"doWeatherCycle":             true,
+	"gameLoopFunction":           true,
+	"keepInventory":              true,
+	"logAdminCommands":           true,
+	"maxCommandChainLength":      true,
+	"maxEntityCramming":          true,
+	"mobGriefing":                true,
+	"naturalRegeneration":        true,
+	"randomTickSpeed":            true,
+	"reducedDebugInfo":           true,
+	"sendCommandFeedback":        true,
+	"showDeathMessages":          true,
+	"spawnRadius":                true,
+	"spectatorsGenerateChunks":   true,
+}
+
+var legacyGameRuleRegex = regexp.MustCompile(`^([a-zA-Z]+) (=) (\S*)$`)
+
+func matchLegacyGameRule(output string) []string {
+	matches := legacyGameRuleRegex.FindStringSubmatch(output)
+	if matches == nil || !legacyGameRules[matches[1]] {
+		return nil
+	}
+	return matches
+}
+
+// handleCommandError only sets the header of the following entry, holding
+// the command that failed to parse.
+func handleCommandError(matches []string, tick int) (events.GameEvent, events.EventType) {
+	return events.NilGameEvent, events.TypeNil
+}
+
+func handleGameRule(matches []string, tick int) (events.GameEvent, events.EventType) {
+	grEvent := events.NewGameEvent(events.GameRule)
+	grEvent.Data = map[string]string{
+		"rule":  matches[1],
+		"value": matches[3],
+	}
+	return grEvent, events.TypeCmd
+}
+
+// teleportedCountRegex matches the entities teleported by a selector
+// matching more than one: "Teleported 3 entities to player1".
+var teleportedCountRegex = regexp.MustCompile(`^(\d+) entities$`)
+
+func handleTeleportToPosition(matches []string, tick int) (events.GameEvent, events.EventType) {
+	tpEvent := events.NewGameEvent(events.Teleport)
+	tpEvent.Data = map[string]string{
+		"x": matches[2],
+		"y": matches[4],
+		"z": matches[6],
+	}
+	setTeleportedTarget(tpEvent, matches[1])
+	return tpEvent, events.TypeCmd
+}
+
+func handleTeleportToEntity(matches []string, tick int) (events.GameEvent, events.EventType) {
+	tpEvent := events.NewGameEvent(events.Teleport)
+	tpEvent.Data = map[string]string{
+		"destination": matches[2],
+	}
+	setTeleportedTarget(tpEvent, matches[1])
+	return tpEvent, events.TypeCmd
+}
+
+func setTeleportedTarget(ev events.GameEvent, target string) {
+	if m := teleportedCountRegex.FindStringSubmatch(target); m != nil {
+		ev.Data["count"] = m[1]
+		return
+	}
+	ev.Data["target"] = target
+	ev.Data["count"] = "1"
+}
+
+func handleOperator(matches []string, tick int) (events.GameEvent, events.EventType) {
+	opEvent := events.NewGameEvent(events.Operator)
+	action := "op"
+	if matches[2] != "a" {
+		action = "deop"
+	}
+	opEvent.Data = map[string]string{
+		"action":      action,
+		"player_name": matches[1],
+	}
+	return opEvent, events.TypeCmd
+}
+
+func handleLegacyOperator(matches []string, tick int) (events.GameEvent, events.EventType) {
+	opEvent := events.NewGameEvent(events.Operator)
+	action := "op"
+	if matches[1] == "De-opped" {
+		action = "deop"
+	}
+	opEvent.Data = map[string]string{
+		"action":      action,
+		"player_name": matches[2],
+	}
+	return opEvent, events.TypeCmd
+}
+
+func handleOperatorFailed(matches []string, tick int) (events.GameEvent, events.EventType) {
+	opEvent := events.NewGameEvent(events.Operator)
+	opEvent.Data = map[string]string{
+		"error_message": matches[0],
+	}
+	return opEvent, events.TypeCmd
+}
+
+func handleWhitelistToggled(matches []string, tick int) (events.GameEvent, events.EventType) {
+	wlEvent := events.NewGameEvent(events.Whitelist)
+	wlEvent.Data = map[string]string{
+		"action": matches[1],
+	}
+	return wlEvent, events.TypeCmd
+}
+
+func whitelistPlayerHandler(action string) logHandler {
+	return func(matches []string, tick int) (events.GameEvent, events.EventType) {
+		wlEvent := events.NewGameEvent(events.Whitelist)
+		wlEvent.Data = map[string]string{
+			"action":      action,
+			"player_name": matches[1],
+		}
+		return wlEvent, events.TypeCmd
+	}
+}
+
+func handleWhitelistList(matches []string, tick int) (events.GameEvent, events.EventType) {
+	wlEvent := events.NewGameEvent(events.Whitelist)
+	count := matches[1]
+	if count == "no" {
+		count = "0"
+	}
+	wlEvent.Data = map[string]string{
+		"action":       "list",
+		"player_count": count,
+		"players":      matches[3],
+	}
+	return wlEvent, events.TypeCmd
+}
+
+func handleWhitelistReloaded(matches []string, tick int) (events.GameEvent, events.EventType) {
+	wlEvent := events.NewGameEvent(events.Whitelist)
+	wlEvent.Data = map[string]string{
+		"action": "reload",
+	}
+	return wlEvent, events.TypeCmd
+}
+
+func handleWhitelistFailed(matches []string, tick int) (events.GameEvent, events.EventType) {
+	wlEvent := events.NewGameEvent(events.Whitelist)
+	wlEvent.Data = map[string]string{
+		"error_message": matches[0],
+	}
+	return wlEvent, events.TypeCmd
+}
+
+func handleExperienceQuery(matches []string, tick int) (events.GameEvent, events.EventType) {
 	xqEvent := events.NewGameEvent(events.ExperienceQuery)
 	xqEvent.Data = map[string]string{
 		"amount": matches[2],
@@ -199,6 +1189,77 @@ func handlePlayerJoined(matches []string, tick int) (events.GameEvent, events.Ev
 	return pjEvent, events.TypeGame
 }
 
+func handlePlayerLogin(matches []string, tick int) (events.GameEvent, events.EventType) {
+	plEvent := events.NewGameEvent(events.PlayerLogin)
+	plEvent.Tick = tick
+	plEvent.Data = map[string]string{
+		"player_name": matches[1],
+		"ip":          matches[2],
+		"port":        matches[3],
+		"entity_id":   matches[4],
+		"x":           matches[7],
+		"y":           matches[8],
+		"z":           matches[9],
+	}
+	if matches[6] != "" {
+		plEvent.Data["world"] = matches[6]
+	}
+	return plEvent, events.TypeGame
+}
+
+func handlePlayerLostConnection(matches []string, tick int) (events.GameEvent, events.EventType) {
+	plEvent := events.NewGameEvent(events.PlayerLostConnection)
+	plEvent.Tick = tick
+	plEvent.Data = map[string]string{
+		"player_name": matches[1],
+		"reason":      matches[2],
+	}
+	return plEvent, events.TypeGame
+}
+
+// rejectionReasons maps the disconnect messages of the rejected logins to
+// the 'rejection' data of the PlayerRejected event.
+var rejectionReasons = []struct {
+	prefix    string
+	rejection string
+}{
+	{"You are not white-listed on this server", "not_whitelisted"},
+	{"You are banned from this server", "banned"},
+	{"Your IP address is banned from this server", "ip_banned"},
+	{"The server is full", "server_full"},
+	{"Outdated client", "outdated_client"},
+	{"Outdated server", "outdated_server"},
+	{"Failed to verify username", "unverified"},
+}
+
+var clientAddressRegex = regexp.MustCompile(`/([^\s()]+):(\d+)\)?$`)
+
+func handlePlayerRejected(matches []string, tick int) (events.GameEvent, events.EventType) {
+	prEvent := events.NewGameEvent(events.PlayerRejected)
+	prEvent.Tick = tick
+	prEvent.Data = map[string]string{
+		"reason":    matches[3],
+		"rejection": "other",
+	}
+	client := matches[1]
+	if matches[2] != "" {
+		prEvent.Data["player_name"] = matches[2]
+	} else if i := strings.Index(client, " (/"); i > 0 {
+		prEvent.Data["player_name"] = client[:i]
+	}
+	if addr := clientAddressRegex.FindStringSubmatch(client); addr != nil {
+		prEvent.Data["ip"] = addr[1]
+		prEvent.Data["port"] = addr[2]
+	}
+	for _, r := range rejectionReasons {
+		if strings.HasPrefix(matches[3], r.prefix) {
+			prEvent.Data["rejection"] = r.rejection
+			break
+		}
+	}
+	return prEvent, events.TypeGame
+}
+
 func handlePlayerLeft(matches []string, tick int) (events.GameEvent, events.EventType) {
 	plEvent := events.NewGameEvent(events.PlayerLeft)
 	plEvent.Tick = tick
@@ -208,20 +1269,52 @@ func handlePlayerLeft(matches []string, tick int) (events.GameEvent, events.Even
 	return plEvent, events.TypeGame
 }
 
+// legacyDeathRegex splits the death messages into the 'death_by' and
+// 'death_details' data decoded before the death message catalogue, kept as
+// deprecated aliases of 'death_cause', 'killer' and 'weapon'.
+var legacyDeathRegex = regexp.MustCompile(`^(?s)(.*) (was shot|was pummeled|drowned|blew up|was blown up|was killed by|hit the ground|fell|was slain|suffocated)(.*)`)
+
 func handlePlayerDied(matches []string, tick int) (events.GameEvent, events.EventType) {
 	pdEvent := events.NewGameEvent(events.PlayerDied)
 	pdEvent.Tick = tick
 	pdEvent.Data = map[string]string{
 		"player_name":   matches[1],
-		"death_by":      matches[2],
+		"death_message": matches[0],
+		"death_cause":   matches[4],
+		"death_key":     matches[5],
+		"death_by":      strings.TrimPrefix(matches[0], matches[1]+" "),
 		"death_details": "",
 	}
-	if len(matches) >= 4 {
-		pdEvent.Data["death_details"] = matches[3]
+	if legacy := legacyDeathRegex.FindStringSubmatch(matches[0]); legacy != nil {
+		pdEvent.Data["death_by"] = legacy[2]
+		pdEvent.Data["death_details"] = legacy[3]
+	}
+	if matches[2] != "" {
+		pdEvent.Data["killer"] = matches[2]
+	}
+	if matches[3] != "" {
+		pdEvent.Data["weapon"] = matches[3]
 	}
 	return pdEvent, events.TypeGame
 }
 
+func handlePlayerAdvancement(matches []string, tick int) (events.GameEvent, events.EventType) {
+	paEvent := events.NewGameEvent(events.PlayerAdvancement)
+	paEvent.Tick = tick
+	paEvent.Data = map[string]string{
+		"player_name":      matches[1],
+		"advancement":      matches[3],
+		"advancement_type": "task",
+	}
+	switch matches[2] {
+	case "completed the challenge":
+		paEvent.Data["advancement_type"] = "challenge"
+	case "reached the goal":
+		paEvent.Data["advancement_type"] = "goal"
+	}
+	return paEvent, events.TypeGame
+}
+
 func handlePlayerUUIDEvent(matches []string, tick int) (events.GameEvent, events.EventType) {
 	puEvent := events.NewGameEvent(events.PlayerUUID)
 	puEvent.Tick = tick
@@ -242,7 +1335,7 @@ func handlePlayerSayEvent(matches []string, tick int) (events.GameEvent, events.
 	return psEvent, events.TypeGame
 }
 
-func handleVersionEvent(matches []string) (events.GameEvent, events.EventType) {
+func handleVersionEvent(matches []string, tick int) (events.GameEvent, events.EventType) {
 	versionEvent := events.NewGameEvent(events.Version)
 	versionEvent.Data = map[string]string{
 		"version": matches[1],
@@ -250,7 +1343,7 @@ func handleVersionEvent(matches []string) (events.GameEvent, events.EventType) {
 	return versionEvent, events.TypeCmd
 }
 
-func handleTimeEvent(matches []string) (events.GameEvent, events.EventType) {
+func handleTimeEvent(matches []string, _ int) (events.GameEvent, events.EventType) {
 	tickStr := matches[1]
 	tick, _ := strconv.Atoi(tickStr)
 	timeEvent := events.NewGameEvent(events.TimeIs)
@@ -258,7 +1351,7 @@ func handleTimeEvent(matches []string) (events.GameEvent, events.EventType) {
 	return timeEvent, events.TypeCmd
 }
 
-func handleDataGet(matches []string) (events.GameEvent, events.EventType) {
+func handleDataGet(matches []string, tick int) (events.GameEvent, events.EventType) {
 	dgEvent := events.NewGameEvent(events.DataGet)
 	dgEvent.Data = map[string]string{
 		"player_name": matches[1],
@@ -268,7 +1361,7 @@ func handleDataGet(matches []string) (events.GameEvent, events.EventType) {
 	return dgEvent, events.TypeCmd
 }
 
-func handleDataGetNoEntity(matches []string) (events.GameEvent, events.EventType) {
+func handleDataGetNoEntity(matches []string, tick int) (events.GameEvent, events.EventType) {
 	dgEvent := events.NewGameEvent(events.DataGet)
 	dgEvent.Data = map[string]string{
 		"error_message": matches[0],
@@ -276,7 +1369,69 @@ func handleDataGetNoEntity(matches []string) (events.GameEvent, events.EventType
 	return dgEvent, events.TypeCmd
 }
 
-func handleSeed(matches []string) (events.GameEvent, events.EventType) {
+func handleList(matches []string, tick int) (events.GameEvent, events.EventType) {
+	lsEvent := events.NewGameEvent(events.List)
+	lsEvent.Data = map[string]string{
+		"online":  matches[1],
+		"max":     matches[3],
+		"players": matches[4],
+	}
+	return lsEvent, events.TypeCmd
+}
+
+func handleLegacyList(matches []string, tick int) (events.GameEvent, events.EventType) {
+	lsEvent := events.NewGameEvent(events.List)
+	lsEvent.Data = map[string]string{
+		"online":  matches[1],
+		"max":     matches[2],
+		"players": "",
+	}
+	return lsEvent, events.TypeCmd
+}
+
+// handleLegacyListHeader only sets the header of the following entry, holding
+// the online players.
+func handleLegacyListHeader(matches []string, tick int) (events.GameEvent, events.EventType) {
+	return events.NilGameEvent, events.TypeNil
+}
+
+func handleLegacyListEntries(matches []string, tick int) (events.GameEvent, events.EventType) {
+	lsEvent := events.NewGameEvent(events.List)
+	lsEvent.Data = map[string]string{
+		"online":  strconv.Itoa(strings.Count(matches[1], ", ") + 1),
+		"players": matches[1],
+	}
+	return lsEvent, events.TypeCmd
+}
+
+func handleAdvancement(matches []string, tick int) (events.GameEvent, events.EventType) {
+	adEvent := events.NewGameEvent(events.Advancement)
+	adEvent.Data = map[string]string{
+		"action": strings.ToLower(matches[1]),
+		"count":  "1",
+		"target": matches[8],
+	}
+	switch {
+	case matches[3] != "":
+		adEvent.Data["advancement"] = matches[3]
+	case matches[4] != "":
+		adEvent.Data["count"] = matches[4]
+	default:
+		adEvent.Data["criterion"] = matches[5]
+		adEvent.Data["advancement"] = matches[6]
+	}
+	return adEvent, events.TypeCmd
+}
+
+func handleAdvancementFailed(matches []string, tick int) (events.GameEvent, events.EventType) {
+	adEvent := events.NewGameEvent(events.Advancement)
+	adEvent.Data = map[string]string{
+		"error_message": matches[0],
+	}
+	return adEvent, events.TypeCmd
+}
+
+func handleSeed(matches []string, tick int) (events.GameEvent, events.EventType) {
 	sdEvent := events.NewGameEvent(events.Seed)
 	sdEvent.Data = map[string]string{
 		"data_raw": matches[1],
@@ -294,7 +1449,7 @@ func handleServerOverloaded(matches []string, tick int) (events.GameEvent, event
 	return soEvent, events.TypeGame
 }
 
-func handleDefaultGameMode(matches []string) (events.GameEvent, events.EventType) {
+func handleDefaultGameMode(matches []string, tick int) (events.GameEvent, events.EventType) {
 	gmEvent := events.NewGameEvent(events.DefaultGameMode)
 	gmEvent.Data = map[string]string{
 		"default_game_mode": matches[1],
@@ -302,7 +1457,7 @@ func handleDefaultGameMode(matches []string) (events.GameEvent, events.EventType
 	return gmEvent, events.TypeGame
 }
 
-func handleBanned(matches []string) (events.GameEvent, events.EventType) {
+func handleBanned(matches []string, tick int) (events.GameEvent, events.EventType) {
 	bnEvent := events.NewGameEvent(events.Banned)
 	bnEvent.Data = map[string]string{
 		"player_name": matches[1],
@@ -310,3 +1465,49 @@ func handleBanned(matches []string) (events.GameEvent, events.EventType) {
 	}
 	return bnEvent, events.TypeGame
 }
+
+func handleLegacyBanned(matches []string, tick int) (events.GameEvent, events.EventType) {
+	bnEvent := events.NewGameEvent(events.Banned)
+	bnEvent.Data = map[string]string{
+		"player_name": matches[1],
+		"reason":      "",
+	}
+	return bnEvent, events.TypeGame
+}
+
+func handleBannedIP(matches []string, tick int) (events.GameEvent, events.EventType) {
+	bnEvent := events.NewGameEvent(events.Banned)
+	bnEvent.Data = map[string]string{
+		"ip":     matches[1],
+		"reason": matches[2],
+	}
+	return bnEvent, events.TypeGame
+}
+
+func handleLegacyBannedIP(matches []string, tick int) (events.GameEvent, events.EventType) {
+	bnEvent := events.NewGameEvent(events.Banned)
+	bnEvent.Data = map[string]string{
+		"ip":     matches[1],
+		"reason": "",
+	}
+	return bnEvent, events.TypeGame
+}
+
+// handleOperatorBanned decodes the bans issued in game, the source being
+// the operator banning the target.
+func handleOperatorBanned(matches []string, tick int) (events.GameEvent, events.EventType) {
+	bnEvent := events.NewGameEvent(events.Banned)
+	key := "player_name"
+	if strings.HasPrefix(matches[2], "IP") {
+		key = "ip"
+	}
+	bnEvent.Data = map[string]string{
+		key:      matches[3],
+		"reason": "",
+		"source": matches[1],
+	}
+	if len(matches) > 4 {
+		bnEvent.Data["reason"] = matches[4]
+	}
+	return bnEvent, events.TypeGame
+}
@@ -0,0 +1,121 @@
+package wrapper
+
+import (
+	"bufio"
+	"os"
+	"regexp"
+	"testing"
+
+	"github.com/wlwanpan/minecraft-wrapper/events"
+)
+
+// legacyLogParserFunc is the previous parser implementation, which runs
+// every state and active game regex in map order against each line. It is
+// only kept around as a baseline for the parser benchmarks.
+func legacyLogParserFunc(line string, tick int) (events.Event, events.EventType) {
+	matches := legacyLogRegex.FindAllStringSubmatch(line, 4)
+	if matches == nil || matches[0][4] == "" {
+		return events.NilEvent, events.TypeNil
+	}
+	output := matches[0][4]
+
+	for e, reg := range legacyStateEventToRegexp {
+		if reg.MatchString(output) {
+			return events.NewStateEvent(e), events.TypeState
+		}
+	}
+	for e, reg := range legacyActiveGameEvents {
+		matches := reg.FindStringSubmatch(output)
+		if matches == nil {
+			continue
+		}
+		switch e {
+		case events.PlayerJoined:
+			return handlePlayerJoined(matches, tick)
+		case events.PlayerLeft:
+			return handlePlayerLeft(matches, tick)
+		case events.PlayerDied:
+			pdEvent := events.NewGameEvent(events.PlayerDied)
+			pdEvent.Tick = tick
+			pdEvent.Data = map[string]string{
+				"player_name":   matches[1],
+				"death_by":      matches[2],
+				"death_details": matches[3],
+			}
+			return pdEvent, events.TypeGame
+		case events.PlayerUUID:
+			return handlePlayerUUIDEvent(matches, tick)
+		case events.PlayerSay:
+			return handlePlayerSayEvent(matches, tick)
+		case events.Version:
+			return handleVersionEvent(matches, tick)
+		case events.TimeIs:
+			return handleTimeEvent(matches, tick)
+		case events.ServerOverloaded:
+			return handleServerOverloaded(matches, tick)
+		}
+	}
+	return events.NilEvent, events.TypeNil
+}
+
+var legacyLogRegex = regexp.MustCompile(`(\[[0-9:]*\]) \[([A-z(-| )#0-9]*)\/([A-z #]*)\]: (.*)`)
+
+var legacyStateEventToRegexp = map[string]*regexp.Regexp{
+	events.Started:  regexp.MustCompile(`^Done (?s)(.*)! For help`),
+	events.Starting: regexp.MustCompile(`^Starting Minecraft server on (.*)`),
+	events.Stopping: regexp.MustCompile(`^Stopping (.*) server`),
+	events.Saving:   regexp.MustCompile(`^Saving the game`),
+	events.Saved:    regexp.MustCompile(`^Saved (?s)(.*)`),
+}
+
+var legacyActiveGameEvents = map[string]*regexp.Regexp{
+	events.PlayerDied:       regexp.MustCompile(`(?s)(.*) (was shot|was pummeled|drowned|blew up|was blown up|was killed by|hit the ground|fell|was slain|suffocated)(.*)`),
+	events.PlayerJoined:     regexp.MustCompile(`(?s)(.*) joined the game`),
+	events.PlayerLeft:       regexp.MustCompile(`(?s)(.*) left the game`),
+	events.PlayerUUID:       regexp.MustCompile(`^UUID of player (?s)(.*) is (?s)(.*)`),
+	events.PlayerSay:        regexp.MustCompile(`<(?s)(.*)> (?s)(.*)`),
+	events.ServerOverloaded: regexp.MustCompile(`^Can't keep up! Is the server overloaded\? Running ([0-9]+)ms or ([0-9]+) ticks behind`),
+	events.TimeIs:           regexp.MustCompile(`^The time is (?s)(.*)`),
+	events.Version:          regexp.MustCompile(`^Starting minecraft server version (.*)`),
+}
+
+var benchLogFiles = []string{
+	"testdata/server_start_log",
+	"testdata/server_overloaded_log",
+	"testdata/player_basic_log",
+}
+
+func loadBenchLines(b *testing.B) []string {
+	lines := []string{}
+	for _, filename := range benchLogFiles {
+		file, err := os.Open(filename)
+		if err != nil {
+			b.Fatalf("failed to load test file: %s", err)
+		}
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		file.Close()
+	}
+	return lines
+}
+
+func benchmarkParser(b *testing.B, parser LogParser) {
+	lines := loadBenchLines(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, line := range lines {
+			parser(line, 0)
+		}
+	}
+}
+
+func BenchmarkLogParser(b *testing.B) {
+	benchmarkParser(b, logParserFunc)
+}
+
+func BenchmarkLegacyLogParser(b *testing.B) {
+	benchmarkParser(b, legacyLogParserFunc)
+}
@@ -3,6 +3,7 @@ package wrapper
 import (
 	"bufio"
 	"os"
+	"reflect"
 	"testing"
 
 	"github.com/wlwanpan/minecraft-wrapper/events"
@@ -116,6 +117,18 @@ func TestPlayerBasicLog(t *testing.T) {
 				"player_uuid": "player-1-uuid",
 			},
 		},
+		{
+			Name: events.PlayerLogin,
+			Data: map[string]string{
+				"player_name": "player1",
+				"ip":          "192.168.1.77",
+				"port":        "48490",
+				"entity_id":   "246",
+				"x":           "181.8583533939447",
+				"y":           "79.0",
+				"z":           "122.5012226438664",
+			},
+		},
 		{
 			Name: events.PlayerJoined,
 			Data: map[string]string{
@@ -129,6 +142,15 @@ func TestPlayerBasicLog(t *testing.T) {
 				"player_uuid": "player-2-uuid",
 			},
 		},
+		{
+			Name: events.PlayerLogin,
+			Data: map[string]string{
+				"player_name": "player2",
+				"ip":          "192.168.1.69",
+				"port":        "50090",
+				"entity_id":   "286",
+			},
+		},
 		{
 			Name: events.PlayerJoined,
 			Data: map[string]string{
@@ -138,17 +160,25 @@ func TestPlayerBasicLog(t *testing.T) {
 		{
 			Name: events.PlayerDied,
 			Data: map[string]string{
-				"player_name":   "player2",
-				"death_by":      "fell",
-				"death_details": " from a high place",
+				"player_name": "player2",
+				"death_cause": "fall",
+				"death_key":   "death.fell.accident.generic",
 			},
 		},
 		{
 			Name: events.PlayerDied,
 			Data: map[string]string{
-				"player_name":   "player1",
-				"death_by":      "was killed by",
-				"death_details": " Witch using magic",
+				"player_name": "player1",
+				"death_cause": "indirect_magic",
+				"death_key":   "death.attack.indirectMagic",
+				"killer":      "Witch",
+			},
+		},
+		{
+			Name: events.PlayerLostConnection,
+			Data: map[string]string{
+				"player_name": "player1",
+				"reason":      "Disconnected",
 			},
 		},
 		{
@@ -157,6 +187,13 @@ func TestPlayerBasicLog(t *testing.T) {
 				"player_name": "player1",
 			},
 		},
+		{
+			Name: events.PlayerLostConnection,
+			Data: map[string]string{
+				"player_name": "player2",
+				"reason":      "Disconnected",
+			},
+		},
 		{
 			Name: events.PlayerLeft,
 			Data: map[string]string{
@@ -166,3 +203,573 @@ func TestPlayerBasicLog(t *testing.T) {
 	}
 	testParsedGameEvents(t, gevs, "testdata/player_basic_log")
 }
+
+func TestLogParserPriority(t *testing.T) {
+	// A player saying a death message in chat should not be decoded as a death.
+	line := "[17:24:27] [Server thread/INFO]: <player1> I fell from a high place"
+	for i := 0; i < 20; i++ {
+		ev, _ := logParserFunc(line, 0)
+		if ev.String() != events.PlayerSay {
+			t.Errorf("line should be parsed as %s, got %s", events.PlayerSay, ev.String())
+			return
+		}
+	}
+}
+
+func TestLogParserThreadDispatch(t *testing.T) {
+	line := "[16:10:00] [main/INFO]: Done (2.500s)! For help, type \"help\""
+	if _, et := logParserFunc(line, 0); et != events.TypeNil {
+		t.Errorf("lines from the main thread should be skipped, got event type %d", et)
+	}
+}
+
+func TestServerExceptionLog(t *testing.T) {
+	testfile, err := os.Open("testdata/server_exception_log")
+	if err != nil {
+		t.Errorf("failed to load test file: %s", err)
+		return
+	}
+
+	activateGameEvents(t, events.RawLine)
+	parser := NewLogParser(AutoLogProfile)
+	actualEvents := []events.GameEvent{}
+	scanner := bufio.NewScanner(testfile)
+	for scanner.Scan() {
+		ev, t := parser(scanner.Text(), 0)
+		if t == events.TypeNil {
+			continue
+		}
+		actualEvents = append(actualEvents, ev.(events.GameEvent))
+	}
+
+	expected := []string{
+		events.RawLine,
+		events.Version,
+		events.RawLine,
+		events.RawLine,
+		events.RawLine,
+		events.RawLine,
+		events.PlayerJoined,
+		events.PlayerLeft,
+		events.RawLine,
+	}
+	if len(expected) != len(actualEvents) {
+		t.Errorf("wrong event count detected: actual=%d, expected=%d", len(actualEvents), len(expected))
+		return
+	}
+	for i, e := range expected {
+		if actualEvents[i].String() != e {
+			t.Errorf("event mismatched at %d: actual=%s, expected=%s", i, actualEvents[i].String(), e)
+		}
+	}
+
+	if _, ok := actualEvents[0].Data["continuation"]; ok {
+		t.Error("lines before any log entry should not be a continuation")
+	}
+	trace := actualEvents[3]
+	if trace.Data["continuation"] != "true" {
+		t.Error("stack trace lines should be a continuation of the previous entry")
+	}
+	if trace.Data["entry_level"] != "ERROR" || trace.Data["entry_output"] != "Encountered an unexpected exception" {
+		t.Errorf("stack trace attached to the wrong entry: %v", trace.Data)
+	}
+}
+
+func TestRawLineContinuation(t *testing.T) {
+	activateGameEvents(t, events.RawLine)
+	parser := NewLogParser(AutoLogProfile)
+	parser("[16:12:41] [Server thread/ERROR]: Encountered an unexpected exception", 0)
+	lines := []struct {
+		line         string
+		continuation bool
+	}{
+		{"java.lang.IllegalStateException: Lock held", true},
+		{"\tat net.minecraft.server.MinecraftServer.run(SourceFile:663)", true},
+		{"Caused by: java.io.IOException: No space left on device", true},
+		{"\t... 3 more", true},
+		{"[MyPlugin] Loading v1.2.0", false},
+		{"Done loading 3 mods", false},
+	}
+	for _, tt := range lines {
+		ev, _ := parser(tt.line, 0)
+		gev, ok := ev.(events.GameEvent)
+		if !ok || gev.Name != events.RawLine {
+			t.Errorf("%q should be a raw line, got %s", tt.line, ev)
+			continue
+		}
+		if actual := gev.Data["continuation"] == "true"; actual != tt.continuation {
+			t.Errorf("%q: wrong continuation: actual=%t, expected=%t", tt.line, actual, tt.continuation)
+		}
+	}
+}
+
+func TestRawLineInactive(t *testing.T) {
+	if _, et := NewLogParser(AutoLogProfile)("java.lang.NullPointerException: null", 0); et != events.TypeNil {
+		t.Errorf("raw lines should not be emitted unless activated, got event type %d", et)
+	}
+}
+
+var profileTestLogs = []struct {
+	profile  *LogProfile
+	filename string
+	version  string
+}{
+	{VanillaLogProfile, "testdata/profiles/vanilla_log", "1.16.5"},
+	{PaperLogProfile, "testdata/profiles/paper_log", "1.16.5"},
+	{SpigotLogProfile, "testdata/profiles/spigot_log", "1.12.2"},
+	{FabricLogProfile, "testdata/profiles/fabric_log", "1.18.1"},
+	{ForgeLogProfile, "testdata/profiles/forge_log", "1.16.5"},
+}
+
+func parseProfileTestLog(t *testing.T, parser LogParser, filename string) []events.Event {
+	testfile, err := os.Open(filename)
+	if err != nil {
+		t.Errorf("failed to load test file: %s", err)
+		return nil
+	}
+	defer testfile.Close()
+
+	actualEvents := []events.Event{}
+	scanner := bufio.NewScanner(testfile)
+	for scanner.Scan() {
+		ev, t := parser(scanner.Text(), 0)
+		if t == events.TypeNil || ev.Is(events.RawLineEvent) {
+			continue
+		}
+		actualEvents = append(actualEvents, ev)
+	}
+	return actualEvents
+}
+
+func TestLogProfiles(t *testing.T) {
+	expected := []string{
+		events.Version,
+		events.Starting,
+		events.Started,
+		events.PlayerUUID,
+		events.PlayerLogin,
+		events.PlayerJoined,
+		events.PlayerUUID,
+		events.PlayerLogin,
+		events.PlayerJoined,
+		events.PlayerSay,
+		events.PlayerLostConnection,
+		events.PlayerLeft,
+		events.Stopping,
+		events.Stopping,
+		events.PlayerLostConnection,
+		events.PlayerLeft,
+	}
+	expectedPlayers := []string{"player1", "player1", "player1", "player2", "player2", "player2", "player1", "player1", "player1", "player2", "player2"}
+
+	for _, tl := range profileTestLogs {
+		for _, profile := range []*LogProfile{tl.profile, AutoLogProfile} {
+			evs := parseProfileTestLog(t, NewLogParser(profile), tl.filename)
+			if len(evs) != len(expected) {
+				t.Errorf("%s (%s): wrong event count detected: actual=%d, expected=%d", tl.filename, profile.Name, len(evs), len(expected))
+				continue
+			}
+			players := []string{}
+			for i, e := range expected {
+				// Paper logs the login details after the join message.
+				if tl.profile == PaperLogProfile && (e == events.PlayerLogin || e == events.PlayerJoined) {
+					e = map[string]string{events.PlayerLogin: events.PlayerJoined, events.PlayerJoined: events.PlayerLogin}[e]
+				}
+				if evs[i].String() != e {
+					t.Errorf("%s (%s): event mismatched at %d: actual=%s, expected=%s", tl.filename, profile.Name, i, evs[i].String(), e)
+				}
+				if gev, ok := evs[i].(events.GameEvent); ok && gev.Data["player_name"] != "" {
+					players = append(players, gev.Data["player_name"])
+				}
+			}
+			for i, p := range expectedPlayers {
+				if i >= len(players) || players[i] != p {
+					t.Errorf("%s (%s): player mismatched at %d: actual=%v", tl.filename, profile.Name, i, players)
+					break
+				}
+			}
+		}
+	}
+}
+
+func TestLogProfileMismatch(t *testing.T) {
+	evs := parseProfileTestLog(t, NewLogParser(VanillaLogProfile), "testdata/profiles/paper_log")
+	if len(evs) != 0 {
+		t.Errorf("vanilla profile should not decode paper logs, got %d events", len(evs))
+	}
+}
+
+func TestParseToLogLineFormats(t *testing.T) {
+	tests := []struct {
+		line   string
+		format logFormat
+		thread string
+		level  string
+		logger string
+		output string
+	}{
+		{"[12:00:00] [Server thread/INFO]: Done", vanillaLogFormat, "Server thread", "INFO", "", "Done"},
+		{"[12:00:00 WARN]: Done", bukkitLogFormat, "", "WARN", "", "Done"},
+		{"[12:00:00] [Server thread/INFO] [minecraft/DedicatedServer]: Done", forgeLogFormat, "Server thread", "INFO", "minecraft/DedicatedServer", "Done"},
+		{"[21Jul2021 10:15:34.254] [Server thread/INFO] [net.minecraft.server.dedicated.DedicatedServer/]: Done", forgeLogFormat, "Server thread", "INFO", "net.minecraft.server.dedicated.DedicatedServer/", "Done"},
+		{"[12:00:00] [Server thread/INFO] (Minecraft) Done", fabricLogFormat, "Server thread", "INFO", "Minecraft", "Done"},
+	}
+	for _, tt := range tests {
+		ll, ok := parseToLogLine(tt.line)
+		if !ok {
+			t.Errorf("failed to parse line: %s", tt.line)
+			continue
+		}
+		if ll.format != tt.format || ll.threadName != tt.thread || ll.level != tt.level || ll.logger != tt.logger || ll.output != tt.output {
+			t.Errorf("line parsed incorrectly: %s, got %+v", tt.line, ll)
+		}
+	}
+}
+
+// activateGameEvents activates the parsing of the given game events for
+// the test, the events active before being restored once done.
+func activateGameEvents(t *testing.T, evs ...string) {
+	activeGameEventsMu.Lock()
+	active := activeGameEvents.Load()
+	activeGameEventsMu.Unlock()
+	t.Cleanup(func() {
+		activeGameEventsMu.Lock()
+		defer activeGameEventsMu.Unlock()
+		activeGameEvents.Store(active)
+	})
+	for _, ev := range evs {
+		registerGameEvent(ev)
+	}
+}
+
+func TestVersionGoldenLogs(t *testing.T) {
+	activateGameEvents(t,
+		events.Seed, events.Difficulty, events.DefaultGameMode, events.BanList,
+		events.Give, events.NoPlayerFound, events.UnknownItem, events.Kicked, events.Banned,
+	)
+
+	legacy := []events.GameEvent{
+		{Name: events.Version, Data: map[string]string{"version": "1.12.2"}},
+		{Name: events.Starting},
+		{Name: events.Started},
+		{Name: events.TimeIs},
+		{Name: events.Seed, Data: map[string]string{"data_raw": "-6812893516224813946"}},
+		{Name: events.Difficulty},
+		{Name: events.DefaultGameMode, Data: map[string]string{"default_game_mode": "Creative"}},
+		{Name: events.BanList, Data: map[string]string{"entry_type": "header", "entry_count": "1"}},
+		{Name: events.BanList, Data: map[string]string{"entry_type": "item", "entry_names": "griefer1, griefer2"}},
+		{Name: events.PlayerUUID, Data: map[string]string{"player_name": "player1"}},
+		{Name: events.PlayerLogin, Data: map[string]string{"player_name": "player1", "ip": "127.0.0.1", "x": "10.5", "z": "-3.5"}},
+		{Name: events.PlayerJoined, Data: map[string]string{"player_name": "player1"}},
+		{Name: events.PlayerSay, Data: map[string]string{"player_name": "player1", "player_message": "hello"}},
+		{Name: events.Give},
+		{Name: events.NoPlayerFound},
+		{Name: events.UnknownItem},
+		{Name: events.PlayerDied, Data: map[string]string{"player_name": "player1"}},
+		{Name: events.Kicked},
+		{Name: events.PlayerLostConnection, Data: map[string]string{"player_name": "player1", "reason": "afk"}},
+		{Name: events.PlayerLeft, Data: map[string]string{"player_name": "player1", "reason": "afk"}},
+		{Name: events.Banned, Data: map[string]string{"player_name": "griefer3"}},
+	}
+	modern := func(version string) []events.GameEvent {
+		return []events.GameEvent{
+			{Name: events.Version, Data: map[string]string{"version": version}},
+			{Name: events.Starting},
+			{Name: events.Started},
+			{Name: events.TimeIs},
+			{Name: events.Seed, Data: map[string]string{"data_raw": "[-6812893516224813946]"}},
+			{Name: events.Difficulty},
+			{Name: events.DefaultGameMode, Data: map[string]string{"default_game_mode": "Creative"}},
+			{Name: events.BanList, Data: map[string]string{"entry_type": "header", "entry_count": "2"}},
+			{Name: events.BanList, Data: map[string]string{"entry_type": "item", "entry_name": "griefer1", "source": "Server", "reason": "Banned by an operator."}},
+			{Name: events.BanList, Data: map[string]string{"entry_type": "item", "entry_name": "griefer2"}},
+			{Name: events.PlayerUUID, Data: map[string]string{"player_name": "player1"}},
+			{Name: events.PlayerLogin, Data: map[string]string{"player_name": "player1", "ip": "127.0.0.1", "x": "10.5", "z": "-3.5"}},
+			{Name: events.PlayerJoined, Data: map[string]string{"player_name": "player1"}},
+			{Name: events.PlayerSay, Data: map[string]string{"player_name": "player1", "player_message": "hello"}},
+			{Name: events.Give},
+			{Name: events.NoPlayerFound},
+			{Name: events.UnknownItem},
+			{Name: events.PlayerDied, Data: map[string]string{"player_name": "player1"}},
+			{Name: events.Kicked},
+			{Name: events.PlayerLostConnection, Data: map[string]string{"player_name": "player1", "reason": "afk"}},
+			{Name: events.PlayerLeft, Data: map[string]string{"player_name": "player1", "reason": "afk"}},
+			{Name: events.Banned, Data: map[string]string{"player_name": "griefer3", "reason": "Banned by an operator."}},
+		}
+	}
+
+	goldenLogs := map[string][]events.GameEvent{
+		"testdata/versions/1.12.2_log": legacy,
+		"testdata/versions/1.14.4_log": modern("1.14.4"),
+		"testdata/versions/1.16.5_log": modern("1.16.5"),
+		"testdata/versions/1.20.4_log": modern("1.20.4"),
+	}
+	for filename, expected := range goldenLogs {
+		evs := parseProfileTestLog(t, NewLogParser(VanillaLogProfile), filename)
+		if len(evs) != len(expected) {
+			t.Errorf("%s: wrong event count detected: actual=%d, expected=%d", filename, len(evs), len(expected))
+			continue
+		}
+		for i, ev := range expected {
+			if !ev.Is(evs[i]) {
+				t.Errorf("%s: event mismatched at %d: actual=%s, expected=%s", filename, i, evs[i].String(), ev.String())
+				continue
+			}
+			gev, _ := evs[i].(events.GameEvent)
+			for k, v := range ev.Data {
+				if gev.Data[k] != v {
+					t.Errorf("%s: data '%s' mismatch for event '%s': actual=%s, expected=%s", filename, k, ev.String(), gev.Data[k], v)
+				}
+			}
+		}
+	}
+}
+
+func TestPlayerAdvancementLog(t *testing.T) {
+	activateGameEvents(t, events.Advancement, events.UnknownAdvancement)
+
+	gevs := []events.GameEvent{
+		{
+			Name: events.PlayerAdvancement,
+			Data: map[string]string{
+				"player_name":      "player1",
+				"advancement":      "Stone Age",
+				"advancement_type": "task",
+			},
+		},
+		{
+			Name: events.PlayerAdvancement,
+			Data: map[string]string{
+				"player_name":      "player2",
+				"advancement":      "Monsters Hunted",
+				"advancement_type": "challenge",
+			},
+		},
+		{
+			Name: events.PlayerAdvancement,
+			Data: map[string]string{
+				"player_name":      "player1",
+				"advancement":      "The End?",
+				"advancement_type": "goal",
+			},
+		},
+		{
+			Name: events.PlayerSay,
+			Data: map[string]string{
+				"player_name": "player2",
+			},
+		},
+		{
+			Name: events.Advancement,
+			Data: map[string]string{
+				"action":      "granted",
+				"advancement": "Stone Age",
+				"count":       "1",
+				"target":      "player2",
+			},
+		},
+		{
+			Name: events.Advancement,
+			Data: map[string]string{
+				"action": "revoked",
+				"count":  "12",
+				"target": "player1",
+			},
+		},
+		{
+			Name: events.Advancement,
+			Data: map[string]string{
+				"action":      "granted",
+				"advancement": "Stone Age",
+				"criterion":   "mine_stone",
+				"target":      "3 players",
+			},
+		},
+		{
+			Name: events.Advancement,
+			Data: map[string]string{
+				"error_message": "Couldn't grant advancement [Stone Age] to player2 as they already have it",
+			},
+		},
+		{Name: events.UnknownAdvancement},
+	}
+	testParsedGameEvents(t, gevs, "testdata/player_advancement_log")
+}
+
+func TestPlayerDisconnectLog(t *testing.T) {
+	expected := []events.GameEvent{
+		{Name: events.PlayerUUID, Data: map[string]string{"player_name": "player1"}},
+		{Name: events.PlayerLogin, Data: map[string]string{"player_name": "player1"}},
+		{Name: events.PlayerJoined, Data: map[string]string{"player_name": "player1"}},
+		{Name: events.PlayerLostConnection, Data: map[string]string{"player_name": "player1", "reason": "Flying is not enabled on this server"}},
+		{Name: events.PlayerLeft, Data: map[string]string{"player_name": "player1", "reason": "Flying is not enabled on this server"}},
+		{Name: events.PlayerUUID, Data: map[string]string{"player_name": "player3"}},
+		{
+			Name: events.PlayerRejected,
+			Data: map[string]string{
+				"player_name": "player3",
+				"ip":          "192.168.1.80",
+				"port":        "51334",
+				"reason":      "You are not white-listed on this server!",
+				"rejection":   "not_whitelisted",
+			},
+		},
+		{Name: events.PlayerRejected, Data: map[string]string{"player_name": "griefer1", "ip": "10.0.0.12", "rejection": "banned"}},
+		{Name: events.PlayerRejected, Data: map[string]string{"player_name": "player5", "ip": "192.168.1.81", "rejection": "server_full"}},
+		{Name: events.PlayerRejected, Data: map[string]string{"ip": "192.168.1.82", "port": "49876", "rejection": "outdated_client"}},
+		{Name: events.PlayerUUID, Data: map[string]string{"player_name": "player2"}},
+		{Name: events.PlayerLogin, Data: map[string]string{"player_name": "player2"}},
+		{Name: events.PlayerJoined, Data: map[string]string{"player_name": "player2"}},
+		{Name: events.PlayerLostConnection, Data: map[string]string{"player_name": "player2", "reason": "Timed out"}},
+		{Name: events.PlayerLeft, Data: map[string]string{"player_name": "player2", "reason": "Timed out"}},
+	}
+
+	evs := parseProfileTestLog(t, NewLogParser(VanillaLogProfile), "testdata/player_disconnect_log")
+	if len(evs) != len(expected) {
+		t.Fatalf("wrong event count detected: actual=%d, expected=%d", len(evs), len(expected))
+	}
+	for i, ev := range expected {
+		aEv := evs[i].(events.GameEvent)
+		if !ev.Is(aEv) {
+			t.Errorf("event mismatched at %d: actual=%s, expected=%s", i, aEv.String(), ev.String())
+			continue
+		}
+		for k, v := range ev.Data {
+			if aEv.Data[k] != v {
+				t.Errorf("data '%s' mismatch for event '%s' at %d: actual=%s, expected=%s", k, ev.String(), i, aEv.Data[k], v)
+			}
+		}
+	}
+	if name, ok := evs[9].(events.GameEvent).Data["player_name"]; ok {
+		t.Errorf("address only rejected login should carry no player name, got %s", name)
+	}
+}
+
+func TestBannedLog(t *testing.T) {
+	activateGameEvents(t, events.Banned)
+	tests := []struct {
+		version  string
+		line     string
+		expected map[string]string
+	}{
+		{"1.16.5", "Banned griefer1: Griefing", map[string]string{"player_name": "griefer1", "reason": "Griefing"}},
+		{"1.16.5", "Banned IP 203.0.113.7: Banned by an operator.", map[string]string{"ip": "203.0.113.7", "reason": "Banned by an operator."}},
+		{"1.16.5", "[admin: Banned griefer3: Griefing]", map[string]string{"player_name": "griefer3", "reason": "Griefing", "source": "admin"}},
+		{"1.16.5", "[admin: Banned IP 203.0.113.8: Spam]", map[string]string{"ip": "203.0.113.8", "reason": "Spam", "source": "admin"}},
+		{"1.12.2", "Banned player griefer1", map[string]string{"player_name": "griefer1", "reason": ""}},
+		{"1.12.2", "Banned IP address 203.0.113.7", map[string]string{"ip": "203.0.113.7", "reason": ""}},
+		{"1.12.2", "[admin: Banned player griefer3]", map[string]string{"player_name": "griefer3", "reason": "", "source": "admin"}},
+		{"1.12.2", "[admin: Banned IP address 203.0.113.8]", map[string]string{"ip": "203.0.113.8", "reason": "", "source": "admin"}},
+	}
+	for _, tt := range tests {
+		parser := NewLogParser(VanillaLogProfile)
+		parser("[12:00:00] [Server thread/INFO]: Starting minecraft server version "+tt.version, 0)
+		ev, _ := parser("[12:01:00] [Server thread/INFO]: "+tt.line, 0)
+		gev, ok := ev.(events.GameEvent)
+		if !ok || gev.Name != events.Banned {
+			t.Errorf("%s: %q should be parsed as %s, got %s", tt.version, tt.line, events.Banned, ev)
+			continue
+		}
+		if !reflect.DeepEqual(gev.Data, tt.expected) {
+			t.Errorf("%s: %q parsed incorrectly: %v", tt.version, tt.line, gev.Data)
+		}
+	}
+}
+
+func TestLegacyBanListEntriesLog(t *testing.T) {
+	activateGameEvents(t, events.BanList)
+	parser := NewLogParser(VanillaLogProfile)
+	parser("[12:00:00] [Server thread/INFO]: Starting minecraft server version 1.12.2", 0)
+	lines := []struct {
+		line    string
+		entries string
+	}{
+		// Only the line following a ban list header holds its entries.
+		{"There are 2 total banned players:", ""},
+		{"griefer1, griefer2", "griefer1, griefer2"},
+		{"There are 2 (out of 3 seen) whitelisted players:", ""},
+		{"player1, player2", ""},
+		{"Stopping", ""},
+		{"There are 0 total banned IP addresses:", ""},
+		{"player1", ""},
+	}
+	for _, tt := range lines {
+		ev, _ := parser("[12:01:00] [Server thread/INFO]: "+tt.line, 0)
+		gev, ok := ev.(events.GameEvent)
+		isEntries := ok && gev.Name == events.BanList && gev.Data["entry_type"] == "item"
+		if tt.entries == "" && isEntries {
+			t.Errorf("%q should not be parsed as ban list entries", tt.line)
+		}
+		if tt.entries != "" && (!isEntries || gev.Data["entry_names"] != tt.entries) {
+			t.Errorf("%q should be parsed as ban list entries, got %s", tt.line, ev)
+		}
+	}
+}
+
+func TestLegacyListEntriesLog(t *testing.T) {
+	activateGameEvents(t, events.List)
+	parser := NewLogParser(VanillaLogProfile)
+	parser("[12:00:00] [Server thread/INFO]: Starting minecraft server version 1.12.2", 0)
+	lines := []struct {
+		line    string
+		players string
+	}{
+		// Only the line following a list header holds the online players.
+		{"There are 2/20 players online:", ""},
+		{"player1 (7d3b5c1e-2f6a-4f8e-9b0a-1c2d3e4f5a6b), player4 (0c1d2e3f-4a5b-4c6d-8e7f-9a0b1c2d3e4f)", "player1 (7d3b5c1e-2f6a-4f8e-9b0a-1c2d3e4f5a6b), player4 (0c1d2e3f-4a5b-4c6d-8e7f-9a0b1c2d3e4f)"},
+		{"player1 (7d3b5c1e-2f6a-4f8e-9b0a-1c2d3e4f5a6b)", ""},
+		{"There are 0/20 players online:", ""},
+		{"player4 (0c1d2e3f-4a5b-4c6d-8e7f-9a0b1c2d3e4f)", ""},
+	}
+	for _, tt := range lines {
+		ev, _ := parser("[12:01:00] [Server thread/INFO]: "+tt.line, 0)
+		gev, ok := ev.(events.GameEvent)
+		isEntries := ok && gev.Name == events.List && gev.Data["players"] != ""
+		if tt.players == "" && isEntries {
+			t.Errorf("%q should not be parsed as list entries", tt.line)
+		}
+		if tt.players != "" && (!isEntries || gev.Data["players"] != tt.players) {
+			t.Errorf("%q should be parsed as list entries, got %s", tt.line, ev)
+		}
+	}
+}
+
+func TestGameRuleLog(t *testing.T) {
+	activateGameEvents(t, events.GameRule, events.UnknownGameRule)
+	tests := []struct {
+		version string
+		lines   []string
+		// expected holds the event name parsed from each line, if any.
+		expected []string
+	}{
+		{"1.16.5", []string{
+			"Unknown or incomplete command, see below for error",
+			"gamerule doWardenSpawning<--[HERE]",
+			// A mistyped command is not an unknown game rule.
+			"Unknown or incomplete command, see below for error",
+			"gamemod<--[HERE]",
+			"gamerule doWardenSpawning<--[HERE]",
+		}, []string{"", events.UnknownGameRule, "", "", ""}},
+		{"1.12.2", []string{
+			"keepInventory = false",
+			"randomTickSpeed = 3",
+			"gameLoopFunction = -",
+			"player1 = griefer",
+			"pvp = true",
+		}, []string{events.GameRule, events.GameRule, events.GameRule, "", ""}},
+	}
+	for _, tt := range tests {
+		parser := NewLogParser(VanillaLogProfile)
+		parser("[12:00:00] [Server thread/INFO]: Starting minecraft server version "+tt.version, 0)
+		for i, line := range tt.lines {
+			ev, _ := parser("[12:01:00] [Server thread/INFO]: "+line, 0)
+			name := ""
+			if gev, ok := ev.(events.GameEvent); ok && gev.Name != events.Empty {
+				name = gev.Name
+			}
+			if name != tt.expected[i] {
+				t.Errorf("%s: %q should be parsed as %q, got %q", tt.version, line, tt.expected[i], name)
+			}
+		}
+	}
+}
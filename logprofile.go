@@ -0,0 +1,208 @@
+package wrapper
+
+import (
+	"strings"
+	"time"
+)
+
+// logFormat is the layout of a log line prefix, before the actual output.
+type logFormat int
+
+const (
+	// vanillaLogFormat: "[HH:MM:SS] [thread/LEVEL]: output"
+	vanillaLogFormat logFormat = iota
+	// bukkitLogFormat: "[HH:MM:SS LEVEL]: output", printed by Spigot and Paper.
+	bukkitLogFormat
+	// forgeLogFormat: "[HH:MM:SS] [thread/LEVEL] [logger/]: output", since
+	// 1.17 the timestamp also carries the date: "[21Jul2021 10:15:34.254]".
+	forgeLogFormat
+	// fabricLogFormat: "[HH:MM:SS] [thread/LEVEL] (logger) output"
+	fabricLogFormat
+	// structuredLogFormat: an event decoded from a structured log4j2 layout,
+	// see 'LogLayout'.
+	structuredLogFormat
+)
+
+// LogProfile describes the log output of a server distribution: the formats
+// of the log lines it prints and the matchers used to decode them.
+type LogProfile struct {
+	Name     string
+	dispatch map[logFormat][]logDispatch
+}
+
+var (
+	// VanillaLogProfile decodes the logs of the official minecraft server.
+	VanillaLogProfile = &LogProfile{
+		Name: "vanilla",
+		dispatch: map[logFormat][]logDispatch{
+			vanillaLogFormat: threadDispatchTable,
+		},
+	}
+	// PaperLogProfile decodes the console output of a Paper server.
+	PaperLogProfile = &LogProfile{
+		Name: "paper",
+		dispatch: map[logFormat][]logDispatch{
+			bukkitLogFormat: bukkitDispatchTable,
+		},
+	}
+	// SpigotLogProfile decodes the console output of a Spigot (or CraftBukkit)
+	// server, which shares its format with Paper.
+	SpigotLogProfile = &LogProfile{
+		Name: "spigot",
+		dispatch: map[logFormat][]logDispatch{
+			bukkitLogFormat: bukkitDispatchTable,
+		},
+	}
+	// FabricLogProfile decodes the logs of a Fabric server. Loader versions
+	// prior to 0.12 print the vanilla format.
+	FabricLogProfile = &LogProfile{
+		Name: "fabric",
+		dispatch: map[logFormat][]logDispatch{
+			fabricLogFormat:  threadDispatchTable,
+			vanillaLogFormat: threadDispatchTable,
+		},
+	}
+	// ForgeLogProfile decodes the logs of a Forge server.
+	ForgeLogProfile = &LogProfile{
+		Name: "forge",
+		dispatch: map[logFormat][]logDispatch{
+			forgeLogFormat:   threadDispatchTable,
+			vanillaLogFormat: threadDispatchTable,
+		},
+	}
+	// AutoLogProfile detects the distribution from the format of each line,
+	// it is the profile used by 'NewDefaultWrapper'.
+	AutoLogProfile = &LogProfile{
+		Name: "auto",
+		dispatch: map[logFormat][]logDispatch{
+			vanillaLogFormat: threadDispatchTable,
+			bukkitLogFormat:  bukkitDispatchTable,
+			forgeLogFormat:   threadDispatchTable,
+			fabricLogFormat:  threadDispatchTable,
+		},
+	}
+)
+
+// LogProfiles lists the available profiles by name.
+var LogProfiles = map[string]*LogProfile{
+	VanillaLogProfile.Name: VanillaLogProfile,
+	PaperLogProfile.Name:   PaperLogProfile,
+	SpigotLogProfile.Name:  SpigotLogProfile,
+	FabricLogProfile.Name:  FabricLogProfile,
+	ForgeLogProfile.Name:   ForgeLogProfile,
+	AutoLogProfile.Name:    AutoLogProfile,
+}
+
+// dispatchFor returns the matchers to run on a log line, nil if the line
+// carries no events for this profile.
+func (p *LogProfile) dispatchFor(ll *logLine) *logDispatch {
+	table := p.dispatch[ll.format]
+	for i := range table {
+		if strings.HasPrefix(ll.threadName, table[i].thread) {
+			return &table[i]
+		}
+	}
+	return nil
+}
+
+// supports returns whether the log line format is printed by the profile.
+func (p *LogProfile) supports(f logFormat) bool {
+	_, ok := p.dispatch[f]
+	return ok
+}
+
+type logLine struct {
+	format     logFormat
+	timestamp  string
+	threadName string
+	level      string
+	logger     string
+	output     string
+	// time and thrown are only decoded from structured layouts, the text
+	// formats lacking the date and printing exceptions over several lines.
+	time   time.Time
+	thrown string
+}
+
+// parseToLogLine splits a server log line to its prefix and output without
+// going through a regex, since it runs for every single line printed by the
+// server. The line format is detected from the prefix layout.
+func parseToLogLine(line string) (*logLine, bool) {
+	start := strings.IndexByte(line, '[')
+	if start < 0 || start+1 >= len(line) || !isDigit(line[start+1]) {
+		return nil, false
+	}
+	line = line[start:]
+	tsEnd := strings.IndexByte(line, ']')
+	if tsEnd < 0 {
+		return nil, false
+	}
+	rest := line[tsEnd+1:]
+
+	if strings.HasPrefix(rest, ": ") {
+		sep := strings.LastIndexByte(line[:tsEnd], ' ')
+		if sep < 0 {
+			return nil, false
+		}
+		return &logLine{
+			format:    bukkitLogFormat,
+			timestamp: line[1:sep],
+			level:     line[sep+1 : tsEnd],
+			output:    trimEOL(rest[2:]),
+		}, true
+	}
+	if !strings.HasPrefix(rest, " [") {
+		return nil, false
+	}
+
+	rest = rest[2:]
+	headerEnd := strings.IndexByte(rest, ']')
+	if headerEnd < 0 {
+		return nil, false
+	}
+	header := rest[:headerEnd]
+	sep := strings.LastIndexByte(header, '/')
+	if sep < 0 {
+		return nil, false
+	}
+	ll := &logLine{
+		timestamp:  line[1:tsEnd],
+		threadName: header[:sep],
+		level:      header[sep+1:],
+	}
+
+	rest = rest[headerEnd+1:]
+	switch {
+	case strings.HasPrefix(rest, ": "):
+		ll.format = vanillaLogFormat
+		ll.output = rest[2:]
+	case strings.HasPrefix(rest, " ["):
+		loggerEnd := strings.Index(rest, "]: ")
+		if loggerEnd < 0 {
+			return nil, false
+		}
+		ll.format = forgeLogFormat
+		ll.logger = rest[2:loggerEnd]
+		ll.output = rest[loggerEnd+3:]
+	case strings.HasPrefix(rest, " ("):
+		loggerEnd := strings.Index(rest, ") ")
+		if loggerEnd < 0 {
+			return nil, false
+		}
+		ll.format = fabricLogFormat
+		ll.logger = rest[2:loggerEnd]
+		ll.output = rest[loggerEnd+2:]
+	default:
+		return nil, false
+	}
+	ll.output = trimEOL(ll.output)
+	return ll, true
+}
+
+func isDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
+
+func trimEOL(s string) string {
+	return strings.TrimRight(s, "\r\n")
+}
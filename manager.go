@@ -0,0 +1,362 @@
+package wrapper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wlwanpan/minecraft-wrapper/events"
+)
+
+var (
+	// ErrServerExists is returned when adding a server to a Manager under
+	// a name already taken.
+	ErrServerExists = errors.New("server already exists")
+	// ErrUnknownServer is returned when looking up a server not run by a
+	// Manager.
+	ErrUnknownServer = errors.New("unknown server")
+)
+
+// managerEventsSize is the buffer size of the merged event stream of a
+// Manager, events being dropped when full as the wrapper does.
+const managerEventsSize = 64
+
+// managerPollInterval is the interval the state of a server is checked at,
+// while waiting on it to start or stop.
+const managerPollInterval = 100 * time.Millisecond
+
+// ServerErrors holds the failures of a Manager operation, by server name.
+type ServerErrors map[string]error
+
+func (e ServerErrors) Error() string {
+	names := make([]string, 0, len(e))
+	for name := range e {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	msgs := make([]string, len(names))
+	for i, name := range names {
+		msgs[i] = fmt.Sprintf("%s: %s", name, e[name])
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// ServerEvent is a game event tagged with the server it was logged by.
+type ServerEvent struct {
+	Server string
+	events.GameEvent
+}
+
+// ServerHealth is the health of a server run by a Manager.
+type ServerHealth struct {
+	State   string
+	Version string
+	// Players is the count of players online.
+	Players int
+	// LastEvent is the time the last game event of the server was read.
+	LastEvent time.Time
+}
+
+// Online returns whether the server is up and running.
+func (h ServerHealth) Online() bool {
+	return h.State == WrapperOnline
+}
+
+type managedServer struct {
+	name      string
+	wrapper   *Wrapper
+	lastEvent time.Time
+	// timeouts bound the start and stop of the server, on top of the
+	// context of the Manager operation.
+	timeouts TimeoutsConfig
+}
+
+// Manager runs many named wrappers in one process. It observes the game
+// events of its wrappers, merging them in a single stream tagged with the
+// server names, see 'Events'.
+type Manager struct {
+	mu          sync.Mutex
+	servers     map[string]*managedServer
+	concurrency int
+	events      chan ServerEvent
+	done        chan struct{}
+	closeOnce   sync.Once
+}
+
+// NewManager returns a Manager starting and stopping at most concurrency
+// servers at once, all of them if 0.
+func NewManager(concurrency int) *Manager {
+	return &Manager{
+		servers:     map[string]*managedServer{},
+		concurrency: concurrency,
+		events:      make(chan ServerEvent, managerEventsSize),
+		done:        make(chan struct{}),
+	}
+}
+
+// NewManagerFromConfig returns a Manager running the servers of the config.
+func NewManagerFromConfig(cfg *ManagerConfig) (*Manager, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	m := NewManager(cfg.Concurrency)
+	for _, s := range cfg.Servers {
+		w, err := NewConfiguredWrapper(s)
+		if err == nil {
+			err = m.add(s.Name, w, s.Timeouts)
+		}
+		if err != nil {
+			m.Close()
+			return nil, fmt.Errorf("server %s: %w", s.Name, err)
+		}
+	}
+	return m, nil
+}
+
+// Add adds a wrapper to the Manager, which then merges its game events
+// into 'Events'.
+func (m *Manager) Add(name string, w *Wrapper) error {
+	return m.add(name, w, TimeoutsConfig{})
+}
+
+func (m *Manager) add(name string, w *Wrapper, timeouts TimeoutsConfig) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.servers[name]; ok {
+		return fmt.Errorf("%w: %s", ErrServerExists, name)
+	}
+	s := &managedServer{name: name, wrapper: w, timeouts: timeouts}
+	m.servers[name] = s
+	go m.forward(s)
+	return nil
+}
+
+// Server returns the wrapper of a server.
+func (m *Manager) Server(name string) (*Wrapper, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.servers[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownServer, name)
+	}
+	return s.wrapper, nil
+}
+
+// Names returns the names of the servers, sorted.
+func (m *Manager) Names() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	names := make([]string, 0, len(m.servers))
+	for name := range m.servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Events returns the game events of all the servers, tagged with their
+// name. The 'GameEvents' of the wrappers are left to their own readers.
+func (m *Manager) Events() <-chan ServerEvent {
+	return m.events
+}
+
+// Close stops consuming the game events of the servers, leaving them
+// running.
+func (m *Manager) Close() {
+	m.closeOnce.Do(func() {
+		close(m.done)
+	})
+}
+
+func (m *Manager) forward(s *managedServer) {
+	evs, cancel := s.wrapper.events.subscribe()
+	defer cancel()
+	for {
+		select {
+		case <-m.done:
+			return
+		case ev := <-evs:
+			m.mu.Lock()
+			s.lastEvent = time.Now()
+			m.mu.Unlock()
+			select {
+			case m.events <- ServerEvent{Server: s.name, GameEvent: ev}:
+			default:
+			}
+		}
+	}
+}
+
+// Health returns the health of a server.
+func (m *Manager) Health(name string) (ServerHealth, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.servers[name]
+	if !ok {
+		return ServerHealth{}, fmt.Errorf("%w: %s", ErrUnknownServer, name)
+	}
+	return s.health(), nil
+}
+
+// HealthAll returns the health of all the servers, by name.
+func (m *Manager) HealthAll() map[string]ServerHealth {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	health := make(map[string]ServerHealth, len(m.servers))
+	for name, s := range m.servers {
+		health[name] = s.health()
+	}
+	return health
+}
+
+func (s *managedServer) health() ServerHealth {
+	return ServerHealth{
+		State:     s.wrapper.State(),
+		Version:   s.wrapper.GameVersion(),
+		Players:   len(s.wrapper.List()),
+		LastEvent: s.lastEvent,
+	}
+}
+
+// StartAll starts the servers not running yet and waits for them to load,
+// starting at most the Manager concurrency at once. The context bounds the
+// whole operation, the failures being returned as ServerErrors.
+func (m *Manager) StartAll(ctx context.Context) error {
+	return m.forEach(m.concurrency, func(s *managedServer) error {
+		ctx, cancel := withTimeout(ctx, s.timeouts.Start)
+		defer cancel()
+		return startServer(ctx, s.wrapper)
+	})
+}
+
+// StopAll stops the running servers and waits for them to go offline,
+// stopping at most the Manager concurrency at once. The servers still up
+// once the context is done can be killed with 'Kill'.
+func (m *Manager) StopAll(ctx context.Context) error {
+	return m.forEach(m.concurrency, func(s *managedServer) error {
+		ctx, cancel := withTimeout(ctx, s.timeouts.Stop)
+		defer cancel()
+		return stopServer(ctx, s.wrapper)
+	})
+}
+
+// Say broadcasts a message to the players of every online server.
+func (m *Manager) Say(msg string) error {
+	return m.forEach(0, func(s *managedServer) error {
+		if err := s.wrapper.Say(msg); err != nil && !errors.Is(err, ErrWrapperNotOnline) {
+			return err
+		}
+		return nil
+	})
+}
+
+// forEach runs f on every server, limit at once, all of them if 0.
+func (m *Manager) forEach(limit int, f func(s *managedServer) error) error {
+	m.mu.Lock()
+	servers := make([]*managedServer, 0, len(m.servers))
+	for _, s := range m.servers {
+		servers = append(servers, s)
+	}
+	m.mu.Unlock()
+	if limit <= 0 || limit > len(servers) {
+		limit = len(servers)
+	}
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs = ServerErrors{}
+		sem  = make(chan struct{}, limit)
+	)
+	for _, s := range servers {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(s *managedServer) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := f(s); err != nil {
+				mu.Lock()
+				errs[s.name] = err
+				mu.Unlock()
+			}
+		}(s)
+	}
+	wg.Wait()
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// withTimeout bounds the context with the timeout, if not 0.
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+func startServer(ctx context.Context, w *Wrapper) error {
+	if w.State() != WrapperOffline {
+		return nil
+	}
+	if err := w.Start(); err != nil {
+		return err
+	}
+	ticker := time.NewTicker(managerPollInterval)
+	defer ticker.Stop()
+	// The wrapper stays offline until the server logs its start.
+	started := false
+	for {
+		select {
+		case <-w.Loaded():
+			return nil
+		case err := <-w.Exited():
+			// The server went down before logging its start, leaving the
+			// wrapper offline.
+			if err == nil {
+				err = ErrServerExited
+			}
+			return fmt.Errorf("server exited while starting: %w", err)
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			// The loaded signal may have been taken by another reader.
+			switch w.State() {
+			case WrapperOnline:
+				return nil
+			case WrapperOffline:
+				if started {
+					return errors.New("server stopped while starting")
+				}
+			default:
+				started = true
+			}
+		}
+	}
+}
+
+func stopServer(ctx context.Context, w *Wrapper) error {
+	if w.State() == WrapperOffline {
+		return nil
+	}
+	if err := w.Stop(); err != nil && !errors.Is(err, ErrWrapperNotOnline) {
+		return err
+	}
+	ticker := time.NewTicker(managerPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if w.State() == WrapperOffline {
+				return nil
+			}
+		}
+	}
+}
@@ -0,0 +1,263 @@
+package wrapper
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wlwanpan/minecraft-wrapper/events"
+)
+
+// managerTestConsole is a console of a server that starts on 'Start' and
+// stops on the 'stop' command, recording the commands written.
+type managerTestConsole struct {
+	mu      sync.Mutex
+	cmds    []string
+	lines   chan string
+	stopped bool
+	// starting is incremented while the server loads, to check the
+	// concurrency of the starts.
+	starting *startGauge
+}
+
+// startGauge tracks the peak of a count shared by the test consoles, the
+// loads being held until the peak reaches the given hold, so that the
+// servers start concurrently.
+type startGauge struct {
+	mu       sync.Mutex
+	cond     *sync.Cond
+	cur, max int
+	hold     int
+}
+
+func newStartGauge(hold int) *startGauge {
+	g := &startGauge{hold: hold}
+	g.cond = sync.NewCond(&g.mu)
+	return g
+}
+
+func (g *startGauge) start() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.cur++
+	if g.cur > g.max {
+		g.max = g.cur
+	}
+	g.cond.Broadcast()
+}
+
+func (g *startGauge) done() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for g.max < g.hold {
+		g.cond.Wait()
+	}
+	g.cur--
+}
+
+func (g *startGauge) peak() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.max
+}
+
+func newManagerTestConsole(starting *startGauge) *managerTestConsole {
+	return &managerTestConsole{lines: make(chan string, 16), starting: starting}
+}
+
+func (tc *managerTestConsole) Start() error {
+	tc.starting.start()
+	go func() {
+		tc.lines <- "[12:00:00] [Server thread/INFO]: Starting minecraft server version 1.16.5"
+		tc.lines <- "[12:00:00] [Server thread/INFO]: Starting Minecraft server on *:25565"
+		tc.starting.done()
+		tc.lines <- "[12:00:01] [Server thread/INFO]: Done (1.000s)! For help, type \"help\""
+	}()
+	return nil
+}
+
+func (tc *managerTestConsole) Kill() error {
+	return nil
+}
+
+func (tc *managerTestConsole) WriteCmd(c string) error {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	tc.cmds = append(tc.cmds, c)
+	if c == "stop" && !tc.stopped {
+		tc.stopped = true
+		tc.lines <- "[12:05:00] [Server thread/INFO]: Stopping the server"
+		close(tc.lines)
+	}
+	return nil
+}
+
+func (tc *managerTestConsole) ReadLine() (string, error) {
+	line, ok := <-tc.lines
+	if !ok {
+		return "", io.EOF
+	}
+	return line, nil
+}
+
+func (tc *managerTestConsole) written() []string {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	return append([]string{}, tc.cmds...)
+}
+
+// exitingTestConsole is a console of a server exiting before logging its
+// start, like a JVM failing on its flags.
+type exitingTestConsole struct {
+	*managerTestConsole
+}
+
+func (tc exitingTestConsole) Start() error {
+	tc.crash()
+	return nil
+}
+
+func newTestManager(t *testing.T, concurrency int, names ...string) (*Manager, map[string]*managerTestConsole, *startGauge) {
+	m := NewManager(concurrency)
+	starting := newStartGauge(concurrency)
+	consoles := map[string]*managerTestConsole{}
+	for _, name := range names {
+		consoles[name] = newManagerTestConsole(starting)
+		if err := m.Add(name, NewWrapper(consoles[name], NewLogParser(VanillaLogProfile))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return m, consoles, starting
+}
+
+func TestManagerStartStopAll(t *testing.T) {
+	m, consoles, starting := newTestManager(t, 2, "a", "b", "c", "d")
+	defer m.Close()
+	if err := m.Add("a", NewWrapper(nil, nil)); !errors.Is(err, ErrServerExists) {
+		t.Errorf("adding a server twice should fail, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := m.StartAll(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if peak := starting.peak(); peak != 2 {
+		t.Errorf("servers should start 2 at once, got %d", peak)
+	}
+	for name, h := range m.HealthAll() {
+		if !h.Online() || h.Version != "1.16.5" {
+			t.Errorf("%s: server should be online, got %+v", name, h)
+		}
+	}
+
+	if err := m.Say("restarting soon"); err != nil {
+		t.Fatal(err)
+	}
+	for name, tc := range consoles {
+		if cmds := tc.written(); !reflect.DeepEqual(cmds, []string{"say restarting soon"}) {
+			t.Errorf("%s: message should be broadcast, got %v", name, cmds)
+		}
+	}
+
+	if err := m.StopAll(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for name, h := range m.HealthAll() {
+		if h.State != WrapperOffline {
+			t.Errorf("%s: server should be offline, got %s", name, h.State)
+		}
+	}
+	if err := m.Say("nobody listens"); err != nil {
+		t.Errorf("broadcast should skip the offline servers, got %v", err)
+	}
+}
+
+func TestManagerStartTimeout(t *testing.T) {
+	m := NewManager(0)
+	defer m.Close()
+	// A server never done loading.
+	tc := &cmdTestConsole{lines: make(chan string, 1)}
+	if err := m.Add("stuck", NewWrapper(tc, NewLogParser(VanillaLogProfile))); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	err := m.StartAll(ctx)
+	errs, ok := err.(ServerErrors)
+	if !ok || !errors.Is(errs["stuck"], context.DeadlineExceeded) {
+		t.Errorf("start should time out, got %v", err)
+	}
+	if _, err := m.Health("missing"); !errors.Is(err, ErrUnknownServer) {
+		t.Errorf("health of a missing server should fail, got %v", err)
+	}
+}
+
+func TestManagerStartExited(t *testing.T) {
+	m := NewManager(0)
+	defer m.Close()
+	tc := exitingTestConsole{newManagerTestConsole(nil)}
+	if err := m.Add("broken", NewWrapper(tc, NewLogParser(VanillaLogProfile))); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := m.StartAll(ctx)
+	errs, ok := err.(ServerErrors)
+	if !ok || !errors.Is(errs["broken"], ErrServerExited) {
+		t.Errorf("start should fail on the server exit, got %v", err)
+	}
+	if ctx.Err() != nil {
+		t.Error("start should not wait for the timeout")
+	}
+}
+
+func TestManagerEvents(t *testing.T) {
+	m, consoles, _ := newTestManager(t, 0, "a", "b")
+	defer m.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := m.StartAll(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	consoles["a"].lines <- "[12:01:00] [Server thread/INFO]: <player1> hello from a"
+	consoles["b"].lines <- "[12:01:00] [Server thread/INFO]: <player2> hello from b"
+	servers := []string{}
+	for i := 0; i < 2; i++ {
+		select {
+		case ev := <-m.Events():
+			if ev.Name != events.PlayerSay {
+				t.Errorf("unexpected event %s", ev.Name)
+			}
+			servers = append(servers, ev.Server+":"+ev.Data["player_name"])
+		case <-time.After(time.Second):
+			t.Fatal("timeout waiting for the merged events")
+		}
+	}
+	sort.Strings(servers)
+	if !reflect.DeepEqual(servers, []string{"a:player1", "b:player2"}) {
+		t.Errorf("events should be tagged with their server, got %v", servers)
+	}
+	// The wrapper events are still read by their own readers.
+	wpr, _ := m.Server("a")
+	select {
+	case ev := <-wpr.GameEvents():
+		if ev.Name != events.PlayerSay {
+			t.Errorf("unexpected wrapper event %s", ev.Name)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("wrapper events should not be taken by the manager")
+	}
+	if h, _ := m.Health("a"); h.LastEvent.IsZero() {
+		t.Error("health should carry the time of the last event")
+	}
+	if err := m.StopAll(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,412 @@
+package wrapper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrModerationDisabled is returned by the temporary bans and mutes of a
+// wrapper with no Moderation set, see 'Moderate'.
+var ErrModerationDisabled = errors.New("moderation not enabled")
+
+// TempBanEntry is a temporary ban, the player being pardoned on expiry.
+type TempBanEntry struct {
+	Player  string    `json:"player"`
+	Reason  string    `json:"reason"`
+	Created time.Time `json:"created"`
+	Expires time.Time `json:"expires"`
+}
+
+// MuteEntry is a player muted until its expiry.
+type MuteEntry struct {
+	Player  string    `json:"player"`
+	Expires time.Time `json:"expires"`
+	// Offences is the count of messages sent by the player while muted.
+	Offences int `json:"offences"`
+}
+
+// ModerationState is the state of a Moderation, saved to its store.
+type ModerationState struct {
+	TempBans []TempBanEntry `json:"temp_bans"`
+	Mutes    []MuteEntry    `json:"mutes"`
+}
+
+// ModerationStore persists the state of a Moderation.
+type ModerationStore interface {
+	Load() (ModerationState, error)
+	Save(ModerationState) error
+}
+
+// JSONModerationStore is the default ModerationStore, saving the state to
+// a JSON file.
+type JSONModerationStore struct {
+	path string
+}
+
+// NewJSONModerationStore returns a ModerationStore reading and writing the
+// state to the JSON file at the given path.
+func NewJSONModerationStore(path string) *JSONModerationStore {
+	return &JSONModerationStore{path: path}
+}
+
+// Load returns the state saved, or an empty one if the file does not exist.
+func (s *JSONModerationStore) Load() (ModerationState, error) {
+	state := ModerationState{}
+	if err := readJSONFile(s.path, &state); err != nil {
+		return ModerationState{}, err
+	}
+	return state, nil
+}
+
+// Save replaces the file with the given state.
+func (s *JSONModerationStore) Save(state ModerationState) error {
+	return writeJSONFile(s.path, state)
+}
+
+// MutePolicy is how the chat messages of muted players are answered. The
+// server broadcasts the messages before the wrapper reads them, so muted
+// players are warned, and kicked if they keep on talking.
+type MutePolicy struct {
+	// Warning is told to a muted player on each message, none if empty.
+	Warning string
+	// KickAfter is the count of messages a muted player is kicked on, the
+	// player never being kicked if 0.
+	KickAfter  int
+	KickReason string
+}
+
+// DefaultMutePolicy warns the muted players, and kicks them on their third
+// message.
+var DefaultMutePolicy = MutePolicy{
+	Warning:    "You are muted.",
+	KickAfter:  3,
+	KickReason: "Talking while muted.",
+}
+
+type muteAction int
+
+const (
+	muteIgnore muteAction = iota
+	muteWarn
+	muteKick
+)
+
+// Moderation holds the temporary bans and the mutes of a server, which the
+// vanilla server does not support, and persists them to its store so they
+// survive wrapper restarts. Players are keyed by name, case-insensitively
+// as the server does.
+type Moderation struct {
+	mu     sync.Mutex
+	store  ModerationStore
+	policy MutePolicy
+	bans   map[string]*TempBanEntry
+	mutes  map[string]*MuteEntry
+	// pending holds the temporary bans whose Banned event is awaited, any
+	// other Banned event replacing the temporary ban of the player.
+	pending map[string]bool
+	now     func() time.Time
+}
+
+// NewModeration returns a Moderation loading and persisting its state to
+// the given store, and answering the muted players with the given policy.
+func NewModeration(store ModerationStore, policy MutePolicy) (*Moderation, error) {
+	state, err := store.Load()
+	if err != nil {
+		return nil, err
+	}
+	m := &Moderation{
+		store:   store,
+		policy:  policy,
+		bans:    map[string]*TempBanEntry{},
+		mutes:   map[string]*MuteEntry{},
+		pending: map[string]bool{},
+		now:     time.Now,
+	}
+	for i := range state.TempBans {
+		b := state.TempBans[i]
+		m.bans[strings.ToLower(b.Player)] = &b
+	}
+	for i := range state.Mutes {
+		mt := state.Mutes[i]
+		m.mutes[strings.ToLower(mt.Player)] = &mt
+	}
+	return m, nil
+}
+
+// TempBans returns the temporary bans not pardoned yet, expired ones
+// included, soonest to expire first.
+func (m *Moderation) TempBans() []TempBanEntry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	bans := make([]TempBanEntry, 0, len(m.bans))
+	for _, b := range m.bans {
+		bans = append(bans, *b)
+	}
+	sort.Slice(bans, func(i, j int) bool {
+		return bans[i].Expires.Before(bans[j].Expires)
+	})
+	return bans
+}
+
+// Mutes returns the players muted, soonest to expire first.
+func (m *Moderation) Mutes() []MuteEntry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	mutes := make([]MuteEntry, 0, len(m.mutes))
+	for _, mt := range m.mutes {
+		if now.Before(mt.Expires) {
+			mutes = append(mutes, *mt)
+		}
+	}
+	sort.Slice(mutes, func(i, j int) bool {
+		return mutes[i].Expires.Before(mutes[j].Expires)
+	})
+	return mutes
+}
+
+// Muted returns whether a player is muted.
+func (m *Moderation) Muted(player string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	mt, ok := m.mutes[strings.ToLower(player)]
+	return ok && m.now().Before(mt.Expires)
+}
+
+func (m *Moderation) addBan(player, reason string, d time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	m.pending[strings.ToLower(player)] = true
+	m.bans[strings.ToLower(player)] = &TempBanEntry{
+		Player:  player,
+		Reason:  reason,
+		Created: now,
+		Expires: now.Add(d),
+	}
+	return m.save()
+}
+
+// banned handles the Banned event of a player, returning the expiry of the
+// temporary ban it was issued for, if any. A player banned otherwise is not
+// temporarily banned anymore, the temporary ban being dropped.
+func (m *Moderation) banned(player string) (time.Time, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := strings.ToLower(player)
+	b, ok := m.bans[key]
+	if !ok {
+		return time.Time{}, false, nil
+	}
+	if m.pending[key] {
+		delete(m.pending, key)
+		return b.Expires, true, nil
+	}
+	delete(m.bans, key)
+	return time.Time{}, false, m.save()
+}
+
+// removeBan drops the temporary ban of a player, if any.
+func (m *Moderation) removeBan(player string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := strings.ToLower(player)
+	delete(m.pending, key)
+	if _, ok := m.bans[key]; !ok {
+		return nil
+	}
+	delete(m.bans, key)
+	return m.save()
+}
+
+// expiredBans returns the temporary bans to pardon.
+func (m *Moderation) expiredBans() []TempBanEntry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	expired := []TempBanEntry{}
+	for _, b := range m.bans {
+		if !now.Before(b.Expires) {
+			expired = append(expired, *b)
+		}
+	}
+	return expired
+}
+
+func (m *Moderation) mute(player string, d time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.mutes[strings.ToLower(player)] = &MuteEntry{
+		Player:  player,
+		Expires: m.now().Add(d),
+	}
+	return m.save()
+}
+
+// unmute drops the mute of a player, returning whether it was muted.
+func (m *Moderation) unmute(player string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := strings.ToLower(player)
+	mt, ok := m.mutes[key]
+	if !ok {
+		return false, nil
+	}
+	delete(m.mutes, key)
+	return m.now().Before(mt.Expires), m.save()
+}
+
+// offence counts a chat message sent by a player, returning how to answer
+// it if the player is muted. Expired mutes are dropped on the way.
+func (m *Moderation) offence(player string) (muteAction, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := strings.ToLower(player)
+	mt, ok := m.mutes[key]
+	if !ok {
+		return muteIgnore, nil
+	}
+	if !m.now().Before(mt.Expires) {
+		delete(m.mutes, key)
+		return muteIgnore, m.save()
+	}
+	mt.Offences++
+	action := muteWarn
+	if m.policy.KickAfter > 0 && mt.Offences >= m.policy.KickAfter {
+		action = muteKick
+	}
+	return action, m.save()
+}
+
+func (m *Moderation) save() error {
+	state := ModerationState{
+		TempBans: make([]TempBanEntry, 0, len(m.bans)),
+		Mutes:    make([]MuteEntry, 0, len(m.mutes)),
+	}
+	for _, b := range m.bans {
+		state.TempBans = append(state.TempBans, *b)
+	}
+	for _, mt := range m.mutes {
+		state.Mutes = append(state.Mutes, *mt)
+	}
+	sort.Slice(state.TempBans, func(i, j int) bool {
+		return state.TempBans[i].Player < state.TempBans[j].Player
+	})
+	sort.Slice(state.Mutes, func(i, j int) bool {
+		return state.Mutes[i].Player < state.Mutes[j].Player
+	})
+	return m.store.Save(state)
+}
+
+// moderationInterval is the interval the expired temporary bans are
+// pardoned at.
+const moderationInterval = 1 * time.Second
+
+// Moderate enforces the temporary bans and mutes of the given Moderation.
+// It should be set before starting the wrapper.
+func (w *Wrapper) Moderate(m *Moderation) {
+	w.moderation = m
+}
+
+// TempBan bans a player for the given duration, the wrapper pardoning it
+// once expired. The ban is saved to the Moderation store, and pardoned on
+// the next start if it expired while the wrapper was down. Its Banned event
+// carries the expiry as 'expires', in the RFC 3339 format.
+func (w *Wrapper) TempBan(player string, d time.Duration, reason string) error {
+	if w.moderation == nil {
+		return ErrModerationDisabled
+	}
+	if !playerNameRegex.MatchString(player) {
+		return fmt.Errorf("%w: invalid player name %q", ErrInvalidTarget, player)
+	}
+	if d <= 0 {
+		return fmt.Errorf("invalid ban duration %s", d)
+	}
+	// The ban is saved first, for its Banned event to carry the expiry.
+	if err := w.moderation.addBan(player, reason, d); err != nil {
+		return err
+	}
+	if err := w.ban("ban", PlayerName(player), reason); err != nil {
+		w.moderation.removeBan(player)
+		return err
+	}
+	return nil
+}
+
+// Mute mutes a player for the given duration. The chat messages of a muted
+// player are answered following the MutePolicy of the Moderation.
+func (w *Wrapper) Mute(player string, d time.Duration) error {
+	if w.moderation == nil {
+		return ErrModerationDisabled
+	}
+	if !playerNameRegex.MatchString(player) {
+		return fmt.Errorf("%w: invalid player name %q", ErrInvalidTarget, player)
+	}
+	if d <= 0 {
+		return fmt.Errorf("invalid mute duration %s", d)
+	}
+	return w.moderation.mute(player, d)
+}
+
+// Unmute lifts the mute of a player, returning ErrPlayerNotFound if the
+// player is not muted.
+func (w *Wrapper) Unmute(player string) error {
+	if w.moderation == nil {
+		return ErrModerationDisabled
+	}
+	muted, err := w.moderation.unmute(player)
+	if err != nil {
+		return err
+	}
+	if !muted {
+		return ErrPlayerNotFound
+	}
+	return nil
+}
+
+func (w *Wrapper) processModeration(ctx context.Context) {
+	ticker := time.NewTicker(moderationInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if w.machine.Is(WrapperOnline) {
+				w.pardonExpiredBans()
+			}
+		}
+	}
+}
+
+// pardonExpiredBans pardons the expired temporary bans, the ones failing
+// for the server being unavailable being retried on the next call.
+func (w *Wrapper) pardonExpiredBans() {
+	for _, b := range w.moderation.expiredBans() {
+		err := w.Pardon(PlayerName(b.Player))
+		if errors.Is(err, ErrWrapperNotOnline) || errors.Is(err, ErrWrapperResponseTimeout) {
+			continue
+		}
+		// The player was pardoned, or was not banned anymore.
+		w.moderation.removeBan(b.Player)
+	}
+}
+
+// moderateChat answers the chat message of a muted player. It runs apart
+// from the log goroutine, which has to read the command responses.
+func (w *Wrapper) moderateChat(player string, action muteAction) {
+	policy := w.moderation.policy
+	switch action {
+	case muteWarn:
+		if policy.Warning != "" {
+			w.Tell(PlayerName(player), policy.Warning)
+		}
+	case muteKick:
+		w.Kick(PlayerName(player), policy.KickReason)
+	}
+}
@@ -0,0 +1,254 @@
+package wrapper
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wlwanpan/minecraft-wrapper/events"
+)
+
+// moderationClock is the time of a Moderation under test, read by the
+// wrapper goroutines.
+type moderationClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func (c *moderationClock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *moderationClock) add(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = c.t.Add(d)
+}
+
+func newTestModeration(t *testing.T) (*Moderation, *moderationClock, string) {
+	path := filepath.Join(testDir(t), "moderation.json")
+	m, err := NewModeration(NewJSONModerationStore(path), DefaultMutePolicy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clk := &moderationClock{t: time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC)}
+	m.now = clk.now
+	return m, clk, path
+}
+
+func TestModerationStore(t *testing.T) {
+	m, _, path := newTestModeration(t)
+
+	if err := m.addBan("griefer1", "Griefing", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.mute("Player1", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if action, err := m.offence("player1"); err != nil || action != muteWarn {
+		t.Errorf("muted player should be warned, got %v, %v", action, err)
+	}
+
+	// The state survives a restart.
+	loaded, err := NewModeration(NewJSONModerationStore(path), DefaultMutePolicy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded.now = m.now
+	if !reflect.DeepEqual(loaded.TempBans(), m.TempBans()) {
+		t.Errorf("wrong temp bans loaded:\nactual  : %+v\nexpected: %+v", loaded.TempBans(), m.TempBans())
+	}
+	mutes := loaded.Mutes()
+	if len(mutes) != 1 || mutes[0].Player != "Player1" || mutes[0].Offences != 1 {
+		t.Errorf("wrong mutes loaded: %+v", mutes)
+	}
+	if !loaded.Muted("player1") {
+		t.Error("player should still be muted")
+	}
+}
+
+func TestModerationMuteExpiry(t *testing.T) {
+	m, clk, _ := newTestModeration(t)
+
+	if err := m.mute("player1", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	expected := []muteAction{muteWarn, muteWarn, muteKick, muteKick}
+	for i, e := range expected {
+		if action, _ := m.offence("player1"); action != e {
+			t.Errorf("wrong action on offence %d: actual=%d, expected=%d", i+1, action, e)
+		}
+	}
+	if action, _ := m.offence("player2"); action != muteIgnore {
+		t.Errorf("player not muted should be ignored, got %d", action)
+	}
+
+	clk.add(time.Minute)
+	if action, _ := m.offence("player1"); action != muteIgnore {
+		t.Errorf("player should be unmuted on expiry, got %d", action)
+	}
+	if mutes := m.Mutes(); len(mutes) != 0 {
+		t.Errorf("expired mute should be dropped, got %+v", mutes)
+	}
+}
+
+func TestWrapperTempBan(t *testing.T) {
+	if err := NewWrapper(nil, nil).TempBan("griefer1", time.Hour, ""); !errors.Is(err, ErrModerationDisabled) {
+		t.Errorf("temp ban with no moderation should fail, got %v", err)
+	}
+
+	m, clk, _ := newTestModeration(t)
+	wpr, tc := newCmdTestWrapper(t, "1.16.5", map[string][]string{
+		"ban griefer1 Griefing": {"[12:01:00] [Server thread/INFO]: Banned griefer1: Griefing"},
+		"pardon griefer1":       {"[12:01:00] [Server thread/INFO]: Unbanned griefer1"},
+	}, func(w *Wrapper) {
+		w.Moderate(m)
+	})
+
+	evs, cancel := wpr.events.subscribe()
+	defer cancel()
+	if err := wpr.TempBan("griefer1", 0, "Griefing"); err == nil {
+		t.Error("temp ban with no duration should fail")
+	}
+	if err := wpr.TempBan("griefer1", time.Hour, "Griefing"); err != nil {
+		t.Fatal(err)
+	}
+	if bans := m.TempBans(); len(bans) != 1 || bans[0].Player != "griefer1" {
+		t.Fatalf("temp ban should be recorded, got %+v", bans)
+	}
+	select {
+	case ev := <-evs:
+		if expires := clk.now().Add(time.Hour).Format(time.RFC3339); ev.Name != events.Banned || ev.Data["expires"] != expires {
+			t.Errorf("banned event should carry the expiry %s, got %s %v", expires, ev.Name, ev.Data)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for the banned event")
+	}
+
+	clk.add(time.Hour)
+	wpr.pardonExpiredBans()
+	if cmds := tc.written(); cmds[len(cmds)-1] != "pardon griefer1" {
+		t.Fatalf("expired ban should be pardoned, got %v", cmds)
+	}
+	if bans := m.TempBans(); len(bans) != 0 {
+		t.Errorf("pardoned ban should be dropped, got %+v", bans)
+	}
+}
+
+func TestWrapperBanReplacesTempBan(t *testing.T) {
+	m, clk, _ := newTestModeration(t)
+	wpr, tc := newCmdTestWrapper(t, "1.16.5", map[string][]string{
+		"ban griefer1 Griefing":          {"[12:01:00] [Server thread/INFO]: Banned griefer1: Griefing"},
+		"ban griefer1 Cheating":          {"[12:01:00] [Server thread/INFO]: Banned griefer1: Cheating"},
+		"ban griefer2 Griefing":          {"[12:01:00] [Server thread/INFO]: Banned griefer2: Griefing"},
+		"ban @a[name=griefer2] Cheating": {"[12:01:00] [Server thread/INFO]: Banned griefer2: Cheating"},
+	}, func(w *Wrapper) {
+		w.Moderate(m)
+	})
+
+	evs, cancel := wpr.events.subscribe()
+	defer cancel()
+	nextBanned := func() events.GameEvent {
+		t.Helper()
+		for {
+			select {
+			case ev := <-evs:
+				if ev.Name == events.Banned {
+					return ev
+				}
+			case <-time.After(time.Second):
+				t.Fatal("timeout waiting for the banned event")
+			}
+		}
+	}
+	for _, p := range []string{"griefer1", "griefer2"} {
+		if err := wpr.TempBan(p, time.Hour, "Griefing"); err != nil {
+			t.Fatal(err)
+		}
+		if ev := nextBanned(); ev.Data["expires"] == "" {
+			t.Errorf("temp ban event should carry the expiry, got %v", ev.Data)
+		}
+	}
+
+	// A permanent ban replaces the temporary one, whether banned by name
+	// or by a selector.
+	if err := wpr.Ban(PlayerName("griefer1"), "Cheating"); err != nil {
+		t.Fatal(err)
+	}
+	if err := wpr.Ban(AllPlayers().Name("griefer2"), "Cheating"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if ev := nextBanned(); ev.Data["expires"] != "" {
+			t.Errorf("permanent ban event should carry no expiry, got %v", ev.Data)
+		}
+	}
+	if bans := m.TempBans(); len(bans) != 0 {
+		t.Errorf("temp bans should be dropped, got %+v", bans)
+	}
+
+	clk.add(time.Hour)
+	wpr.pardonExpiredBans()
+	for _, cmd := range tc.written() {
+		if strings.HasPrefix(cmd, "pardon") {
+			t.Errorf("permanent ban should not be pardoned, got %s", cmd)
+		}
+	}
+}
+
+func TestWrapperMute(t *testing.T) {
+	m, _, _ := newTestModeration(t)
+	wpr, tc := newCmdTestWrapper(t, "1.16.5", map[string][]string{
+		"tell player1 You are muted.":       {"[12:01:00] [Server thread/INFO]: You whisper to player1: You are muted."},
+		"kick player1 Talking while muted.": {"[12:01:00] [Server thread/INFO]: Kicked player1: Talking while muted."},
+	}, func(w *Wrapper) {
+		w.Moderate(m)
+	})
+
+	if err := wpr.Mute("player1", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		tc.lines <- "[12:02:00] [Server thread/INFO]: <player1> hello"
+		ev := <-wpr.GameEvents()
+		if ev.Name != events.PlayerSay || ev.Data["muted"] != "true" {
+			t.Errorf("chat of a muted player should be flagged, got %s %v", ev.Name, ev.Data)
+		}
+	}
+	// The warnings are told apart from the log goroutine, possibly after
+	// the kick.
+	warnings, kicked := 0, false
+	for warnings < 2 || !kicked {
+		select {
+		case c := <-tc.notify:
+			switch c {
+			case "tell player1 You are muted.":
+				warnings++
+			case "kick player1 Talking while muted.":
+				kicked = true
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("muted player should be warned twice then kicked, got %v", tc.written())
+		}
+	}
+	if warnings != 2 {
+		t.Errorf("muted player should be warned twice, got %d", warnings)
+	}
+
+	if err := wpr.Unmute("player1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := wpr.Unmute("player1"); !errors.Is(err, ErrPlayerNotFound) {
+		t.Errorf("unmuting a player not muted should fail, got %v", err)
+	}
+	tc.lines <- "[12:03:00] [Server thread/INFO]: <player1> hello"
+	if ev := <-wpr.GameEvents(); ev.Data["muted"] != "" {
+		t.Errorf("chat of an unmuted player should not be flagged, got %v", ev.Data)
+	}
+}
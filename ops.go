@@ -0,0 +1,93 @@
+package wrapper
+
+import (
+	"fmt"
+	"strings"
+)
+
+// OpEntry is a server operator, as saved in 'ops.json'.
+type OpEntry struct {
+	UUID string `json:"uuid"`
+	Name string `json:"name"`
+	// Level is the permission level of the operator, from 1 to 4, see:
+	// https://minecraft.gamepedia.com/Permission_level.
+	Level int `json:"level"`
+	// BypassesPlayerLimit lets the operator join a full server.
+	BypassesPlayerLimit bool `json:"bypassesPlayerLimit"`
+}
+
+func validateOps(entries []OpEntry) error {
+	seen := map[string]bool{}
+	for _, e := range entries {
+		if err := validateProfile(e.UUID, e.Name); err != nil {
+			return err
+		}
+		if e.Level < 1 || e.Level > 4 {
+			return fmt.Errorf("%w: invalid level %d for %s, expected 1 to 4", ErrInvalidEntry, e.Level, e.Name)
+		}
+		uuid := strings.ToLower(e.UUID)
+		if seen[uuid] {
+			return fmt.Errorf("%w: duplicated uuid %s", ErrInvalidEntry, e.UUID)
+		}
+		seen[uuid] = true
+	}
+	return nil
+}
+
+// ReadOpsFile returns the operators of an 'ops.json' file, or none if it
+// does not exist.
+func ReadOpsFile(path string) ([]OpEntry, error) {
+	entries := []OpEntry{}
+	if err := readJSONFile(path, &entries); err != nil {
+		return nil, err
+	}
+	if err := validateOps(entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// WriteOpsFile validates and writes the operators to an 'ops.json' file.
+// The server only reads the file on start and overwrites it on any 'op' or
+// 'deop' command: it should be written while the server is offline.
+func WriteOpsFile(path string, entries []OpEntry) error {
+	if err := validateOps(entries); err != nil {
+		return err
+	}
+	if entries == nil {
+		entries = []OpEntry{}
+	}
+	return writeJSONFile(path, entries)
+}
+
+// ReconcileOps brings the server operators in line with the given players,
+// opping the missing ones and deopping the others, and returns the players
+// opped and deopped. The current operators are read from the 'ops.json'
+// file of the running server, there is no command listing them. Players
+// opped by command get the level set by 'op-permission-level' in the
+// server properties, use WriteOpsFile while offline to set other levels.
+// It stops on the first command failing.
+func (w *Wrapper) ReconcileOps(opsFile string, players []string) (opped, deopped []string, err error) {
+	ops, err := ReadOpsFile(opsFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	current := make([]string, len(ops))
+	for i, op := range ops {
+		current[i] = op.Name
+	}
+	toOp, toDeop := diffNames(players, current)
+	for _, p := range toOp {
+		if err := w.Op(PlayerName(p)); err != nil {
+			return opped, deopped, fmt.Errorf("op %s: %w", p, err)
+		}
+		opped = append(opped, p)
+	}
+	for _, p := range toDeop {
+		if err := w.DeOp(PlayerName(p)); err != nil {
+			return opped, deopped, fmt.Errorf("deop %s: %w", p, err)
+		}
+		deopped = append(deopped, p)
+	}
+	return opped, deopped, nil
+}
@@ -0,0 +1,95 @@
+package wrapper
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestOpsFile(t *testing.T) {
+	ops, err := ReadOpsFile("testdata/server_files/ops.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []OpEntry{
+		{UUID: "7d3b5c1e-2f6a-4f8e-9b0a-1c2d3e4f5a6b", Name: "player1", Level: 4, BypassesPlayerLimit: true},
+		{UUID: "0c1d2e3f-4a5b-4c6d-8e7f-9a0b1c2d3e4f", Name: "player2", Level: 2},
+	}
+	if !reflect.DeepEqual(ops, expected) {
+		t.Fatalf("wrong ops: %+v", ops)
+	}
+
+	dir := testDir(t)
+	path := filepath.Join(dir, "ops.json")
+
+	ops[1].Level = 3
+	if err := WriteOpsFile(path, ops); err != nil {
+		t.Fatal(err)
+	}
+	if read, err := ReadOpsFile(path); err != nil || !reflect.DeepEqual(read, ops) {
+		t.Errorf("wrong ops read back: %+v, %v", read, err)
+	}
+	ops[1].Level = 5
+	if err := WriteOpsFile(path, ops); !errors.Is(err, ErrInvalidEntry) {
+		t.Errorf("level 5 should be invalid, got %v", err)
+	}
+	ops[1].Level = 0
+	if err := WriteOpsFile(path, ops); !errors.Is(err, ErrInvalidEntry) {
+		t.Errorf("level 0 should be invalid, got %v", err)
+	}
+}
+
+func TestWrapperOp(t *testing.T) {
+	tests := []struct {
+		version   string
+		responses map[string][]string
+	}{
+		{"1.16.5", map[string][]string{
+			"op player3":   {"[12:01:00] [Server thread/INFO]: Made player3 a server operator"},
+			"op player1":   {"[12:01:00] [Server thread/INFO]: Nothing changed. The player already is an operator"},
+			"deop player2": {"[12:01:00] [Server thread/INFO]: Made player2 no longer a server operator"},
+			"op player9":   {"[12:01:00] [Server thread/INFO]: That player does not exist"},
+		}},
+		{"1.12.2", map[string][]string{
+			"op player3":   {"[12:01:00] [Server thread/INFO]: Opped player3"},
+			"op player1":   {"[12:01:00] [Server thread/INFO]: Could not op player1"},
+			"deop player2": {"[12:01:00] [Server thread/INFO]: De-opped player2"},
+		}},
+	}
+	for _, tt := range tests {
+		wpr, _ := newCmdTestWrapper(t, tt.version, tt.responses)
+		if err := wpr.Op(PlayerName("player3")); err != nil {
+			t.Errorf("%s: %s", tt.version, err)
+		}
+		if err := wpr.Op(PlayerName("player1")); err == nil {
+			t.Errorf("%s: opping an operator should fail", tt.version)
+		}
+		if err := wpr.DeOp(PlayerName("player2")); err != nil {
+			t.Errorf("%s: %s", tt.version, err)
+		}
+		if tt.version == "1.16.5" {
+			if err := wpr.Op(PlayerName("player9")); err != ErrPlayerNotFound {
+				t.Errorf("%s: expected ErrPlayerNotFound, got %v", tt.version, err)
+			}
+		}
+	}
+}
+
+func TestWrapperReconcileOps(t *testing.T) {
+	wpr, tc := newCmdTestWrapper(t, "1.16.5", map[string][]string{
+		"op player3":   {"[12:01:00] [Server thread/INFO]: Made player3 a server operator"},
+		"deop player2": {"[12:01:00] [Server thread/INFO]: Made player2 no longer a server operator"},
+	})
+	opped, deopped, err := wpr.ReconcileOps("testdata/server_files/ops.json", []string{"PLAYER1", "player3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(opped, []string{"player3"}) || !reflect.DeepEqual(deopped, []string{"player2"}) {
+		t.Errorf("wrong reconcile: opped=%v, deopped=%v", opped, deopped)
+	}
+	expected := []string{"op player3", "deop player2"}
+	if cmds := tc.written(); !reflect.DeepEqual(cmds, expected) {
+		t.Errorf("wrong commands written:\nactual  : %v\nexpected: %v", cmds, expected)
+	}
+}
@@ -1,5 +1,7 @@
 package wrapper
 
+import "time"
+
 // DataGetOutput represents the structured data logged from the
 // '/data get entity' command. Some fields might not be of the
 // right or precise type since the decoder will coerse any value
@@ -70,7 +72,15 @@ type RecipeBook struct {
 	IsSmokerFilteringCraftable          int
 }
 
+// Player represents a player connected to the server.
 type Player struct {
 	Name string
 	UUID string
+	// IP is the address the player connected from.
+	IP string
+	// LoginTime is the time the player logged in at.
+	LoginTime time.Time
+	// Position is the last known x, y, z position of the player, as logged
+	// on login.
+	Position []float64
 }
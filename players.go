@@ -0,0 +1,76 @@
+package wrapper
+
+import (
+	"sync"
+)
+
+// playerList holds the players online, keyed by name. It is updated from
+// the log goroutine and read by the wrapper callers.
+type playerList struct {
+	mu sync.RWMutex
+	m  map[string]Player
+}
+
+func newPlayerList() *playerList {
+	return &playerList{
+		m: make(map[string]Player),
+	}
+}
+
+func (pl *playerList) get(name string) (Player, bool) {
+	pl.mu.RLock()
+	defer pl.mu.RUnlock()
+
+	p, ok := pl.m[name]
+	return p, ok
+}
+
+// update applies f to the player with the given name, adding the player
+// to the list if missing.
+func (pl *playerList) update(name string, f func(*Player)) {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+
+	p := pl.m[name]
+	p.Name = name
+	f(&p)
+	pl.m[name] = p
+}
+
+func (pl *playerList) remove(name string) {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+
+	delete(pl.m, name)
+}
+
+func (pl *playerList) list() []Player {
+	pl.mu.RLock()
+	defer pl.mu.RUnlock()
+
+	players := []Player{}
+	for _, p := range pl.m {
+		players = append(players, p)
+	}
+	return players
+}
+
+// resync replaces the players online with the given ones, keeping the
+// details logged on login of the players still online.
+func (pl *playerList) resync(players []Player) []Player {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+
+	m := make(map[string]Player, len(players))
+	synced := make([]Player, len(players))
+	for i, p := range players {
+		if known, ok := pl.m[p.Name]; ok && (known.UUID == "" || known.UUID == p.UUID) {
+			known.UUID = p.UUID
+			p = known
+		}
+		m[p.Name] = p
+		synced[i] = p
+	}
+	pl.m = m
+	return synced
+}
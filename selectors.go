@@ -0,0 +1,572 @@
+package wrapper
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ErrInvalidTarget is returned by the commands given a target that is
+// neither a player name nor a valid target selector.
+var ErrInvalidTarget = errors.New("invalid target")
+
+// Target is the target of a command, either a PlayerName or a Selector, or
+// an IPAddress for the IP bans.
+type Target interface {
+	// String returns the target in the command syntax.
+	String() string
+	// Validate returns an error if the target would be rejected by the game.
+	Validate() error
+}
+
+// PlayerName targets a single player by their name, or their UUID.
+type PlayerName string
+
+func (p PlayerName) String() string {
+	return string(p)
+}
+
+// Validate returns an error if the name is empty, contains spaces or would
+// be read as a selector.
+func (p PlayerName) Validate() error {
+	if strings.HasPrefix(string(p), "@") {
+		return fmt.Errorf("%w: %s should be a player name", ErrInvalidTarget, string(p))
+	}
+	return validateTarget(string(p))
+}
+
+// IPAddress targets an IP address, banned or pardoned by the IP bans.
+type IPAddress string
+
+func (ip IPAddress) String() string {
+	return string(ip)
+}
+
+// Validate returns an error if the address is not a valid IPv4 or IPv6
+// address.
+func (ip IPAddress) Validate() error {
+	if net.ParseIP(string(ip)) == nil {
+		return fmt.Errorf("%w: %q should be an IP address", ErrInvalidTarget, string(ip))
+	}
+	return nil
+}
+
+// SelectorSort is the order in which the entities matched by a selector are
+// picked, when limited.
+type SelectorSort string
+
+const (
+	SortNearest   SelectorSort = "nearest"
+	SortFurthest  SelectorSort = "furthest"
+	SortRandom    SelectorSort = "random"
+	SortArbitrary SelectorSort = "arbitrary"
+)
+
+// Range is a range of values matched by a selector argument, like the
+// distance or the score of an entity.
+type Range struct {
+	min, max *float64
+}
+
+// Exactly returns the range matching the given value only.
+func Exactly(v float64) Range {
+	return Range{min: &v, max: &v}
+}
+
+// AtLeast returns the range matching the values greater or equal to min.
+func AtLeast(min float64) Range {
+	return Range{min: &min}
+}
+
+// AtMost returns the range matching the values lesser or equal to max.
+func AtMost(max float64) Range {
+	return Range{max: &max}
+}
+
+// Between returns the range matching the values from min to max, inclusive.
+func Between(min, max float64) Range {
+	return Range{min: &min, max: &max}
+}
+
+// String returns the range in the selector syntax, like "1..5" or "..10".
+func (r Range) String() string {
+	if r.min != nil && r.max != nil && *r.min == *r.max {
+		return formatFloat(*r.min)
+	}
+	s := ""
+	if r.min != nil {
+		s += formatFloat(*r.min)
+	}
+	s += ".."
+	if r.max != nil {
+		s += formatFloat(*r.max)
+	}
+	return s
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+type selectorArg struct {
+	key, value string
+}
+
+// Selector is a target selector, like "@a[tag=vip,limit=3]", matching
+// entities by their type, position, tags, scores... see:
+// https://minecraft.gamepedia.com/Commands#Target_selectors. Selectors are
+// built by chaining their methods, each returning a modified copy, and are
+// passed to the commands as their Target:
+//
+//	wpr.Give(wrapper.AllPlayers().Tag("vip"), "minecraft:diamond", 1)
+type Selector struct {
+	variable string
+	args     []selectorArg
+}
+
+// NearestPlayer returns the '@p' selector, targeting the nearest player.
+func NearestPlayer() Selector {
+	return Selector{variable: "@p"}
+}
+
+// AllPlayers returns the '@a' selector, targeting every player.
+func AllPlayers() Selector {
+	return Selector{variable: "@a"}
+}
+
+// RandomPlayer returns the '@r' selector, targeting a random player.
+func RandomPlayer() Selector {
+	return Selector{variable: "@r"}
+}
+
+// Self returns the '@s' selector, targeting the entity running the command.
+func Self() Selector {
+	return Selector{variable: "@s"}
+}
+
+// AllEntities returns the '@e' selector, targeting every entity.
+func AllEntities() Selector {
+	return Selector{variable: "@e"}
+}
+
+func (s Selector) with(key, value string) Selector {
+	s.args = append(s.args[:len(s.args):len(s.args)], selectorArg{key: key, value: value})
+	return s
+}
+
+// Type limits the selector to the entities of a type, like
+// "minecraft:zombie", or of an entity type tag prefixed by '#'.
+func (s Selector) Type(t string) Selector {
+	return s.with("type", t)
+}
+
+// NotType excludes the entities of a type from the selector.
+func (s Selector) NotType(t string) Selector {
+	return s.with("type", "!"+t)
+}
+
+// Tag limits the selector to the entities with a tag, or to the entities
+// without any tag if empty.
+func (s Selector) Tag(tag string) Selector {
+	return s.with("tag", tag)
+}
+
+// NotTag excludes the entities with a tag from the selector, or the
+// entities without any tag if empty.
+func (s Selector) NotTag(tag string) Selector {
+	return s.with("tag", "!"+tag)
+}
+
+// Team limits the selector to the entities of a team, or to the entities
+// without team if empty.
+func (s Selector) Team(team string) Selector {
+	return s.with("team", team)
+}
+
+// NotTeam excludes the entities of a team from the selector.
+func (s Selector) NotTeam(team string) Selector {
+	return s.with("team", "!"+team)
+}
+
+// Name limits the selector to the entities with a name.
+func (s Selector) Name(name string) Selector {
+	return s.with("name", quoteSelectorValue(name))
+}
+
+// NotName excludes the entities with a name from the selector.
+func (s Selector) NotName(name string) Selector {
+	return s.with("name", "!"+quoteSelectorValue(name))
+}
+
+// GameMode limits the selector to the players in a game mode.
+func (s Selector) GameMode(mode GameMode) Selector {
+	return s.with("gamemode", string(mode))
+}
+
+// NotGameMode excludes the players in a game mode from the selector.
+func (s Selector) NotGameMode(mode GameMode) Selector {
+	return s.with("gamemode", "!"+string(mode))
+}
+
+// Limit limits the number of entities targeted by the selector.
+func (s Selector) Limit(n int) Selector {
+	return s.with("limit", strconv.Itoa(n))
+}
+
+// Sort sets the order in which the entities are picked when limited.
+func (s Selector) Sort(sort SelectorSort) Selector {
+	return s.with("sort", string(sort))
+}
+
+// At sets the position from which the distance and volume are measured,
+// the position running the command by default.
+func (s Selector) At(x, y, z float64) Selector {
+	return s.with("x", formatFloat(x)).with("y", formatFloat(y)).with("z", formatFloat(z))
+}
+
+// Volume limits the selector to the entities in the box spanning from the
+// 'At' position to the position offset by dx, dy and dz.
+func (s Selector) Volume(dx, dy, dz float64) Selector {
+	return s.with("dx", formatFloat(dx)).with("dy", formatFloat(dy)).with("dz", formatFloat(dz))
+}
+
+// Distance limits the selector to the entities at a distance in the range
+// from the 'At' position.
+func (s Selector) Distance(r Range) Selector {
+	return s.with("distance", r.String())
+}
+
+// Level limits the selector to the players with an experience level in the
+// range.
+func (s Selector) Level(r Range) Selector {
+	return s.with("level", r.String())
+}
+
+// Score limits the selector to the entities with a score in the range for
+// the objective.
+func (s Selector) Score(objective string, r Range) Selector {
+	return s.with("scores", objective+"="+r.String())
+}
+
+// String returns the selector in the command syntax.
+func (s Selector) String() string {
+	if len(s.args) == 0 {
+		return s.variable
+	}
+	args := []string{}
+	scores := []string{}
+	for _, arg := range s.args {
+		if arg.key == "scores" {
+			if len(scores) == 0 {
+				args = append(args, "")
+			}
+			scores = append(scores, arg.value)
+			continue
+		}
+		args = append(args, arg.key+"="+arg.value)
+	}
+	for i, arg := range args {
+		if arg == "" {
+			args[i] = "scores={" + strings.Join(scores, ",") + "}"
+		}
+	}
+	return s.variable + "[" + strings.Join(args, ",") + "]"
+}
+
+// Validate returns an error if the selector would be rejected by the game.
+func (s Selector) Validate() error {
+	return validateTarget(s.String())
+}
+
+var unquotedValueRegex = regexp.MustCompile(`^[0-9A-Za-z_\-.+]*$`)
+
+func quoteSelectorValue(v string) string {
+	if unquotedValueRegex.MatchString(v) {
+		return v
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	return `"` + r.Replace(v) + `"`
+}
+
+// checkTarget returns ErrInvalidTarget if the target of a command is
+// missing or invalid.
+func checkTarget(target Target) error {
+	if target == nil {
+		return fmt.Errorf("%w: no target", ErrInvalidTarget)
+	}
+	return target.Validate()
+}
+
+// validateTarget returns ErrInvalidTarget if the target is neither a player
+// name, nor a target selector the game would accept.
+func validateTarget(target string) error {
+	if !strings.HasPrefix(target, "@") {
+		if target == "" || strings.IndexFunc(target, unicode.IsSpace) >= 0 {
+			return fmt.Errorf("%w: %q", ErrInvalidTarget, target)
+		}
+		return nil
+	}
+	if err := parseSelector(target); err != nil {
+		return fmt.Errorf("%w %s: %v", ErrInvalidTarget, target, err)
+	}
+	return nil
+}
+
+func parseSelector(s string) error {
+	if len(s) < 2 {
+		return errors.New("missing selector variable")
+	}
+	variable, rest := s[:2], s[2:]
+	switch variable {
+	case "@p", "@a", "@r", "@s", "@e":
+	default:
+		return errors.New("unknown selector variable")
+	}
+	if rest == "" {
+		return nil
+	}
+	if rest[0] != '[' || rest[len(rest)-1] != ']' {
+		return errors.New("arguments should be enclosed in brackets")
+	}
+	args, err := splitSelectorArgs(rest[1 : len(rest)-1])
+	if err != nil {
+		return err
+	}
+
+	seen := map[string]bool{}
+	for _, arg := range args {
+		i := strings.Index(arg, "=")
+		if i < 0 {
+			return fmt.Errorf("expected a value for '%s'", arg)
+		}
+		key, value := strings.TrimSpace(arg[:i]), strings.TrimSpace(arg[i+1:])
+		check, ok := selectorArgCheckers[key]
+		if !ok {
+			return fmt.Errorf("unknown argument '%s'", key)
+		}
+		if err := check(value); err != nil {
+			return fmt.Errorf("invalid '%s' value '%s': %v", key, value, err)
+		}
+		negated := strings.HasPrefix(value, "!")
+		if seen[key] && !repeatableSelectorArgs[key] && !(negated && negatableSelectorArgs[key]) {
+			return fmt.Errorf("argument '%s' is not repeatable", key)
+		}
+		seen[key] = true
+
+		switch {
+		case (key == "limit" || key == "sort") && variable == "@s":
+			return fmt.Errorf("argument '%s' is not applicable to @s", key)
+		case key == "type" && variable != "@e" && variable != "@s":
+			return fmt.Errorf("argument 'type' is not applicable to %s", variable)
+		}
+	}
+	return nil
+}
+
+// splitSelectorArgs splits the selector arguments on the commas, except for
+// those quoted or nested in braces, like in 'scores={a=1,b=2}'.
+func splitSelectorArgs(s string) ([]string, error) {
+	args := []string{}
+	if strings.TrimSpace(s) == "" {
+		return args, nil
+	}
+	depth, start := 0, 0
+	quoted, escaped := false, false
+	for i, r := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\' && quoted:
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+		case quoted:
+		case r == '{' || r == '[':
+			depth++
+		case r == '}' || r == ']':
+			depth--
+			if depth < 0 {
+				return nil, errors.New("unbalanced braces")
+			}
+		case r == ',' && depth == 0:
+			args = append(args, s[start:i])
+			start = i + 1
+		}
+	}
+	if quoted || depth != 0 {
+		return nil, errors.New("unterminated quote or braces")
+	}
+	return append(args, s[start:]), nil
+}
+
+var (
+	resourceRegex = regexp.MustCompile(`^#?([a-z0-9_\-.]+:)?[a-z0-9_\-./]+$`)
+	quotedRegex   = regexp.MustCompile(`^"(\\.|[^"\\])*"$`)
+
+	// repeatableSelectorArgs can be given several times, and the
+	// negatableSelectorArgs only when negated.
+	repeatableSelectorArgs = map[string]bool{"tag": true, "nbt": true, "predicate": true}
+	negatableSelectorArgs  = map[string]bool{"type": true, "gamemode": true, "name": true, "team": true}
+
+	selectorArgCheckers = map[string]func(string) error{
+		"x":            checkFloat,
+		"y":            checkFloat,
+		"z":            checkFloat,
+		"dx":           checkFloat,
+		"dy":           checkFloat,
+		"dz":           checkFloat,
+		"distance":     checkRange(false, true),
+		"level":        checkRange(true, true),
+		"x_rotation":   checkRange(false, false),
+		"y_rotation":   checkRange(false, false),
+		"limit":        checkLimit,
+		"sort":         checkSort,
+		"gamemode":     negatable(checkGameMode),
+		"type":         negatable(checkResource),
+		"predicate":    negatable(checkResource),
+		"tag":          negatable(checkUnquoted),
+		"team":         negatable(checkUnquoted),
+		"name":         negatable(checkString),
+		"scores":       checkScores,
+		"nbt":          negatable(checkBraces),
+		"advancements": checkBraces,
+	}
+)
+
+func negatable(check func(string) error) func(string) error {
+	return func(v string) error {
+		return check(strings.TrimPrefix(v, "!"))
+	}
+}
+
+func checkFloat(v string) error {
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+		return errors.New("expected a number")
+	}
+	return nil
+}
+
+func checkRange(integer, positive bool) func(string) error {
+	parse := func(v string) (float64, error) {
+		if integer {
+			i, err := strconv.Atoi(v)
+			return float64(i), err
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		if err == nil && (math.IsInf(f, 0) || math.IsNaN(f)) {
+			err = errors.New("not finite")
+		}
+		return f, err
+	}
+	return func(v string) error {
+		bounds := []string{v}
+		if i := strings.Index(v, ".."); i >= 0 {
+			bounds = []string{v[:i], v[i+2:]}
+		}
+		values := []float64{}
+		for _, b := range bounds {
+			if b == "" {
+				continue
+			}
+			f, err := parse(b)
+			if err != nil {
+				return errors.New("expected a range like '1..5'")
+			}
+			if positive && f < 0 {
+				return errors.New("range can not be negative")
+			}
+			values = append(values, f)
+		}
+		if len(values) == 0 {
+			return errors.New("empty range")
+		}
+		if len(values) == 2 && values[0] > values[1] {
+			return errors.New("range min is greater than its max")
+		}
+		return nil
+	}
+}
+
+func checkLimit(v string) error {
+	if n, err := strconv.Atoi(v); err != nil || n < 1 {
+		return errors.New("expected a positive number")
+	}
+	return nil
+}
+
+func checkSort(v string) error {
+	switch SelectorSort(v) {
+	case SortNearest, SortFurthest, SortRandom, SortArbitrary:
+		return nil
+	}
+	return errors.New("unknown sort")
+}
+
+func checkGameMode(v string) error {
+	switch GameMode(v) {
+	case Survival, Creative, Adventure, Spectator:
+		return nil
+	}
+	return errors.New("unknown game mode")
+}
+
+func checkResource(v string) error {
+	if !resourceRegex.MatchString(v) {
+		return errors.New("expected a resource location")
+	}
+	return nil
+}
+
+func checkUnquoted(v string) error {
+	if !unquotedValueRegex.MatchString(v) {
+		return errors.New("invalid characters")
+	}
+	return nil
+}
+
+func checkString(v string) error {
+	if strings.HasPrefix(v, `"`) {
+		if !quotedRegex.MatchString(v) {
+			return errors.New("invalid quoted string")
+		}
+		return nil
+	}
+	return checkUnquoted(v)
+}
+
+func checkBraces(v string) error {
+	if !strings.HasPrefix(v, "{") || !strings.HasSuffix(v, "}") {
+		return errors.New("expected a compound like '{...}'")
+	}
+	return nil
+}
+
+func checkScores(v string) error {
+	if err := checkBraces(v); err != nil {
+		return err
+	}
+	scores, err := splitSelectorArgs(v[1 : len(v)-1])
+	if err != nil {
+		return err
+	}
+	for _, score := range scores {
+		i := strings.Index(score, "=")
+		if i < 0 {
+			return fmt.Errorf("expected a range for objective '%s'", score)
+		}
+		objective := strings.TrimSpace(score[:i])
+		if objective == "" || checkUnquoted(objective) != nil {
+			return fmt.Errorf("invalid objective '%s'", objective)
+		}
+		if err := checkRange(true, false)(strings.TrimSpace(score[i+1:])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
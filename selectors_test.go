@@ -0,0 +1,145 @@
+package wrapper
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSelectorString(t *testing.T) {
+	tests := []struct {
+		s        Selector
+		expected string
+	}{
+		{AllPlayers(), "@a"},
+		{NearestPlayer().GameMode(Survival).Distance(AtMost(10)), "@p[gamemode=survival,distance=..10]"},
+		{AllEntities().Type("minecraft:zombie").NotTag("boss").Limit(5).Sort(SortNearest), "@e[type=minecraft:zombie,tag=!boss,limit=5,sort=nearest]"},
+		{AllPlayers().Score("kills", AtLeast(10)).Tag("vip").Score("deaths", Exactly(0)), "@a[scores={kills=10..,deaths=0},tag=vip]"},
+		{AllEntities().At(0, 64, -10.5).Volume(10, 5, 10).NotType("#minecraft:skeletons"), "@e[x=0,y=64,z=-10.5,dx=10,dy=5,dz=10,type=!#minecraft:skeletons]"},
+		{RandomPlayer().Level(Between(5, 10)).NotGameMode(Spectator), "@r[level=5..10,gamemode=!spectator]"},
+		{AllEntities().Name(`Bob "the" builder`), `@e[name="Bob \"the\" builder"]`},
+	}
+	for _, tt := range tests {
+		if actual := tt.s.String(); actual != tt.expected {
+			t.Errorf("wrong selector: actual=%s, expected=%s", actual, tt.expected)
+		}
+		if err := tt.s.Validate(); err != nil {
+			t.Errorf("%s should be valid: %s", tt.expected, err)
+		}
+	}
+
+	base := AllPlayers().Tag("a")
+	if base.Tag("b").String() == base.Tag("c").String() || base.String() != "@a[tag=a]" {
+		t.Error("selectors should be copied on change")
+	}
+}
+
+func TestValidateTarget(t *testing.T) {
+	valid := []string{
+		"player1",
+		"069a79f4-44e9-4726-a5be-fca90e38aaf5",
+		"@s",
+		"@a[]",
+		"@e[type=minecraft:cow,nbt={OnGround:1b,Tags:[\"a\",\"b\"]},tag=x,tag=y]",
+		"@a[name=!player1,name=!player2]",
+		"@a[ distance = 1.5..3 , x_rotation=-90..0]",
+		"@a[advancements={minecraft:story/mine_stone=true}]",
+		"@p[team=]",
+	}
+	for _, target := range valid {
+		if err := validateTarget(target); err != nil {
+			t.Errorf("%s should be valid: %s", target, err)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"player 1",
+		"@x",
+		"@pa",
+		"@",
+		"@a[",
+		"@a[tag=vip",
+		"@a[limit=0]",
+		"@a[limit=2,limit=3]",
+		"@a[sort=closest]",
+		"@a[gamemode=hardcore]",
+		"@a[type=minecraft:cow]",
+		"@s[limit=1]",
+		"@e[distance=-1..]",
+		"@e[distance=5..1]",
+		"@e[level=1.5]",
+		"@e[x=east]",
+		"@e[color=red]",
+		"@e[tag]",
+		"@e[tag=a b]",
+		"@e[scores={kills=a}]",
+		"@e[scores=kills]",
+		"@e[name=\"unterminated]",
+		"@e[nbt={a:1}}]",
+		AllEntities().Limit(-1).String(),
+	}
+	for _, target := range invalid {
+		if err := validateTarget(target); !errors.Is(err, ErrInvalidTarget) {
+			t.Errorf("%s should be invalid, got %v", target, err)
+		}
+	}
+}
+
+func TestCheckTarget(t *testing.T) {
+	valid := []Target{
+		PlayerName("player1"),
+		AllPlayers().Tag("vip"),
+		Self(),
+		IPAddress("203.0.113.7"),
+		IPAddress("2001:db8::1"),
+	}
+	for _, target := range valid {
+		if err := checkTarget(target); err != nil {
+			t.Errorf("%s should be valid: %s", target, err)
+		}
+	}
+
+	invalid := []Target{
+		nil,
+		PlayerName(""),
+		PlayerName("player 1"),
+		PlayerName("@a"),
+		Selector{},
+		AllPlayers().Limit(0),
+		IPAddress("203.0.113"),
+		IPAddress("player1"),
+	}
+	for _, target := range invalid {
+		if err := checkTarget(target); !errors.Is(err, ErrInvalidTarget) {
+			t.Errorf("%v should be invalid, got %v", target, err)
+		}
+	}
+}
+
+func TestPosition(t *testing.T) {
+	tests := []struct {
+		p        Position
+		expected string
+		err      error
+	}{
+		{AbsolutePosition(10, 64, -3.5), "10 64 -3.5", nil},
+		{RelativePosition(0, 1, 0), "~ ~1 ~", nil},
+		{LocalPosition(0, 0, 2), "^ ^ ^2", nil},
+		{Position{Absolute(100), Relative(0), Absolute(-20)}, "100 ~ -20", nil},
+		{Position{Local(1), Relative(0), Local(1)}, "^1 ~ ^1", ErrInvalidPosition},
+	}
+	for _, tt := range tests {
+		if actual := tt.p.String(); actual != tt.expected {
+			t.Errorf("wrong position: actual=%s, expected=%s", actual, tt.expected)
+		}
+		if err := tt.p.Validate(); !errors.Is(err, tt.err) {
+			t.Errorf("%s: wrong error: actual=%v, expected=%v", tt.expected, err, tt.err)
+		}
+	}
+	if err := AbsolutePosition(0.5, 64, 0).validateBlock(); !errors.Is(err, ErrInvalidPosition) {
+		t.Errorf("block positions should be integers, got %v", err)
+	}
+	if err := RelativePosition(0.5, 0, 0).validateBlock(); err != nil {
+		t.Errorf("relative block positions can be fractional, got %v", err)
+	}
+}
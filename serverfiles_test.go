@@ -0,0 +1,48 @@
+package wrapper
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testDir returns a temporary directory, removed once the test is done.
+func testDir(t *testing.T) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "wrapper")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+func TestWriteJSONFileMode(t *testing.T) {
+	dir := testDir(t)
+	path := filepath.Join(dir, "whitelist.json")
+
+	if err := writeJSONFile(path, []string{}); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Stat(path); err != nil {
+		t.Fatal(err)
+	} else if fi.Mode().Perm() != 0644 {
+		t.Errorf("new file should be 0644, got %s", fi.Mode())
+	}
+
+	if err := os.Chmod(path, 0640); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeJSONFile(path, []string{"player1"}); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Stat(path); err != nil {
+		t.Fatal(err)
+	} else if fi.Mode().Perm() != 0640 {
+		t.Errorf("file should keep its mode, got %s", fi.Mode())
+	}
+	if entries, _ := ioutil.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("temporary files should be removed, got %d entries", len(entries))
+	}
+}
@@ -0,0 +1,320 @@
+package wrapper
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/wlwanpan/minecraft-wrapper/events"
+)
+
+// Session is a play session of a player, from the time it joined to the
+// time it left the server.
+type Session struct {
+	UUID      string
+	Name      string
+	JoinTime  time.Time
+	JoinTick  int
+	LeaveTime time.Time
+	LeaveTick int
+}
+
+// Online returns whether the session is still going on.
+func (s Session) Online() bool {
+	return s.LeaveTime.IsZero()
+}
+
+// PlayerStats holds the playtime accounting of a player across sessions.
+type PlayerStats struct {
+	UUID string `json:"uuid"`
+	// Name is the last name the player joined with.
+	Name string `json:"name"`
+	// Playtime is the cumulated length of the player ended sessions.
+	Playtime time.Duration `json:"playtime"`
+	// PlaytimeTicks is the cumulated game ticks of the player ended sessions.
+	PlaytimeTicks int       `json:"playtime_ticks"`
+	Sessions      int       `json:"sessions"`
+	FirstSeen     time.Time `json:"first_seen"`
+	LastSeen      time.Time `json:"last_seen"`
+}
+
+// SessionStore persists the player stats of a SessionTracker.
+type SessionStore interface {
+	Load() ([]PlayerStats, error)
+	Save([]PlayerStats) error
+}
+
+// JSONFileStore is the default SessionStore, saving the player stats to
+// a JSON file.
+type JSONFileStore struct {
+	path string
+}
+
+// NewJSONFileStore returns a SessionStore reading and writing the player
+// stats to the JSON file at the given path.
+func NewJSONFileStore(path string) *JSONFileStore {
+	return &JSONFileStore{path: path}
+}
+
+// Load returns the player stats saved, or none if the file does not exist.
+func (s *JSONFileStore) Load() ([]PlayerStats, error) {
+	stats := []PlayerStats{}
+	if err := readJSONFile(s.path, &stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// Save replaces the file with the given player stats.
+func (s *JSONFileStore) Save(stats []PlayerStats) error {
+	return writeJSONFile(s.path, stats)
+}
+
+// SessionTracker records the play sessions of the players from the game
+// events, and accounts for their playtime. Players are keyed by UUID so
+// their stats survive name changes, the name being the key only for the
+// players joining with no UUID logged (offline mode servers).
+type SessionTracker struct {
+	mu     sync.Mutex
+	store  SessionStore
+	stats  map[string]*PlayerStats
+	online map[string]*Session
+	// uuids maps the player names to the UUID logged before joining.
+	uuids map[string]string
+	now   func() time.Time
+	// onSaveError is called with the save errors not returned to a caller.
+	onSaveError func(error)
+}
+
+// NewSessionTracker returns a SessionTracker loading and persisting the
+// player stats to the given store.
+func NewSessionTracker(store SessionStore) (*SessionTracker, error) {
+	loaded, err := store.Load()
+	if err != nil {
+		return nil, err
+	}
+	st := &SessionTracker{
+		store:  store,
+		stats:  map[string]*PlayerStats{},
+		online: map[string]*Session{},
+		uuids:  map[string]string{},
+		now:    time.Now,
+	}
+	for i := range loaded {
+		ps := loaded[i]
+		st.stats[ps.UUID] = &ps
+	}
+	return st, nil
+}
+
+// OnSaveError sets the function called when the stats fail to be saved while
+// tracking the events of a wrapper, the error being otherwise returned by
+// Track and EndAll. The stats are kept in memory, the save being retried with
+// the next session ending.
+func (st *SessionTracker) OnSaveError(fn func(error)) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.onSaveError = fn
+}
+
+// saveFailed reports a save error of the events tracked by a wrapper.
+func (st *SessionTracker) saveFailed(err error) {
+	if err == nil {
+		return
+	}
+	st.mu.Lock()
+	fn := st.onSaveError
+	st.mu.Unlock()
+	if fn != nil {
+		fn(err)
+	}
+}
+
+// Track updates the sessions from a game event, the player stats being
+// persisted to the store when a session ends.
+func (st *SessionTracker) Track(ev events.GameEvent) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	t := ev.Time
+	if t.IsZero() {
+		t = st.now()
+	}
+	name := ev.Data["player_name"]
+	switch {
+	case ev.Is(events.PlayerUUIDEvent):
+		st.uuids[name] = ev.Data["player_uuid"]
+	case ev.Is(events.PlayerRejectedEvent):
+		delete(st.uuids, name)
+	case ev.Is(events.PlayerJoinedEvent):
+		uuid, ok := st.uuids[name]
+		if !ok {
+			uuid = name
+		}
+		st.online[uuid] = &Session{
+			UUID:     uuid,
+			Name:     name,
+			JoinTime: t,
+			JoinTick: ev.Tick,
+		}
+		ps := st.playerStats(uuid)
+		ps.Name = name
+		if ps.FirstSeen.IsZero() {
+			ps.FirstSeen = t
+		}
+		ps.LastSeen = t
+	case ev.Is(events.PlayerLeftEvent):
+		uuid, ok := st.uuids[name]
+		if !ok {
+			uuid = name
+		}
+		delete(st.uuids, name)
+		if st.endSession(uuid, t, ev.Tick) {
+			return st.save()
+		}
+	}
+	return nil
+}
+
+// EndAll ends all the ongoing sessions, when the server stops.
+func (st *SessionTracker) EndAll() error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if len(st.online) == 0 {
+		return nil
+	}
+	t := st.now()
+	for uuid, s := range st.online {
+		// No tick is logged on stop, the session ticks are accounted
+		// from its duration.
+		tick := s.JoinTick + int(t.Sub(s.JoinTime).Seconds())*GameTickPerSecond
+		st.endSession(uuid, t, tick)
+	}
+	st.uuids = map[string]string{}
+	return st.save()
+}
+
+func (st *SessionTracker) playerStats(uuid string) *PlayerStats {
+	ps, ok := st.stats[uuid]
+	if !ok {
+		ps = &PlayerStats{UUID: uuid}
+		st.stats[uuid] = ps
+	}
+	return ps
+}
+
+func (st *SessionTracker) endSession(uuid string, t time.Time, tick int) bool {
+	s, ok := st.online[uuid]
+	if !ok {
+		return false
+	}
+	delete(st.online, uuid)
+
+	ps := st.playerStats(uuid)
+	ps.Sessions++
+	ps.LastSeen = t
+	if d := t.Sub(s.JoinTime); d > 0 {
+		ps.Playtime += d
+	}
+	if tick > s.JoinTick {
+		ps.PlaytimeTicks += tick - s.JoinTick
+	}
+	return true
+}
+
+func (st *SessionTracker) save() error {
+	stats := make([]PlayerStats, 0, len(st.stats))
+	for _, ps := range st.stats {
+		stats = append(stats, *ps)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].UUID < stats[j].UUID
+	})
+	return st.store.Save(stats)
+}
+
+// Session returns the ongoing session of a player.
+func (st *SessionTracker) Session(uuid string) (Session, bool) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	s, ok := st.online[uuid]
+	if !ok {
+		return Session{}, false
+	}
+	return *s, true
+}
+
+// SessionLength returns the length of the ongoing session of a player, or
+// 0 if the player is not online.
+func (st *SessionTracker) SessionLength(uuid string) time.Duration {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	s, ok := st.online[uuid]
+	if !ok {
+		return 0
+	}
+	return st.now().Sub(s.JoinTime)
+}
+
+// Playtime returns the cumulative playtime of a player, including its
+// ongoing session.
+func (st *SessionTracker) Playtime(uuid string) time.Duration {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	return st.playtime(uuid)
+}
+
+func (st *SessionTracker) playtime(uuid string) time.Duration {
+	var d time.Duration
+	if ps, ok := st.stats[uuid]; ok {
+		d = ps.Playtime
+	}
+	if s, ok := st.online[uuid]; ok {
+		d += st.now().Sub(s.JoinTime)
+	}
+	return d
+}
+
+// TopPlaytime returns the stats of the n players with the most playtime,
+// ongoing sessions included.
+func (st *SessionTracker) TopPlaytime(n int) []PlayerStats {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	top := make([]PlayerStats, 0, len(st.stats))
+	for uuid, ps := range st.stats {
+		s := *ps
+		s.Playtime = st.playtime(uuid)
+		top = append(top, s)
+	}
+	sort.SliceStable(top, func(i, j int) bool {
+		if top[i].Playtime == top[j].Playtime {
+			return top[i].UUID < top[j].UUID
+		}
+		return top[i].Playtime > top[j].Playtime
+	})
+	if n >= 0 && n < len(top) {
+		top = top[:n]
+	}
+	return top
+}
+
+// LastSeen returns the last time a player was seen on the server, which is
+// now for online players.
+func (st *SessionTracker) LastSeen(uuid string) (time.Time, bool) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if _, ok := st.online[uuid]; ok {
+		return st.now(), true
+	}
+	ps, ok := st.stats[uuid]
+	if !ok {
+		return time.Time{}, false
+	}
+	return ps.LastSeen, true
+}
@@ -0,0 +1,93 @@
+package wrapper
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/wlwanpan/minecraft-wrapper/events"
+)
+
+func newTestSessionTracker(t *testing.T) (*SessionTracker, string) {
+	path := filepath.Join(testDir(t), "sessions.json")
+	st, err := NewSessionTracker(NewJSONFileStore(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return st, path
+}
+
+func sessionEvent(name string, data map[string]string, at time.Time, tick int) events.GameEvent {
+	ev := events.NewGameEvent(name)
+	ev.Data = data
+	ev.Time = at
+	ev.Tick = tick
+	return ev
+}
+
+func TestSessionTracker(t *testing.T) {
+	st, path := newTestSessionTracker(t)
+
+	start := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	now := start
+	st.now = func() time.Time { return now }
+
+	evs := []events.GameEvent{
+		sessionEvent(events.PlayerUUID, map[string]string{"player_name": "player1", "player_uuid": "uuid-1"}, start, 0),
+		sessionEvent(events.PlayerJoined, map[string]string{"player_name": "player1"}, start, 0),
+		sessionEvent(events.PlayerUUID, map[string]string{"player_name": "player2", "player_uuid": "uuid-2"}, start, 0),
+		sessionEvent(events.PlayerJoined, map[string]string{"player_name": "player2"}, start, 0),
+		sessionEvent(events.PlayerLeft, map[string]string{"player_name": "player1"}, start.Add(30*time.Minute), 36000),
+		// player1 renamed to player3.
+		sessionEvent(events.PlayerUUID, map[string]string{"player_name": "player3", "player_uuid": "uuid-1"}, start.Add(40*time.Minute), 48000),
+		sessionEvent(events.PlayerJoined, map[string]string{"player_name": "player3"}, start.Add(40*time.Minute), 48000),
+	}
+	for _, ev := range evs {
+		if err := st.Track(ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	now = start.Add(60 * time.Minute)
+	if d := st.SessionLength("uuid-1"); d != 20*time.Minute {
+		t.Errorf("session length should be 20m, got %s", d)
+	}
+	if d := st.Playtime("uuid-1"); d != 50*time.Minute {
+		t.Errorf("playtime should be 50m, got %s", d)
+	}
+	if s, ok := st.Session("uuid-1"); !ok || s.Name != "player3" || !s.Online() {
+		t.Errorf("wrong ongoing session: %+v", s)
+	}
+
+	top := st.TopPlaytime(1)
+	if len(top) != 1 || top[0].UUID != "uuid-2" || top[0].Playtime != 60*time.Minute {
+		t.Errorf("wrong top playtime: %+v", top)
+	}
+
+	if err := st.EndAll(); err != nil {
+		t.Fatal(err)
+	}
+	now = start.Add(90 * time.Minute)
+	if seen, ok := st.LastSeen("uuid-2"); !ok || !seen.Equal(start.Add(60*time.Minute)) {
+		t.Errorf("wrong last seen time: %s", seen)
+	}
+	if _, ok := st.LastSeen("uuid-unknown"); ok {
+		t.Error("unknown player should not have been seen")
+	}
+
+	// The stats must be reloaded from the store.
+	reloaded, err := NewSessionTracker(NewJSONFileStore(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	top = reloaded.TopPlaytime(-1)
+	if len(top) != 2 {
+		t.Fatalf("wrong reloaded stats count: %d", len(top))
+	}
+	if top[0].UUID != "uuid-2" || top[1].UUID != "uuid-1" || top[1].Name != "player3" || top[1].Sessions != 2 {
+		t.Errorf("wrong reloaded stats: %+v", top)
+	}
+	if top[1].PlaytimeTicks != 36000+24000 {
+		t.Errorf("wrong playtime ticks: %d", top[1].PlaytimeTicks)
+	}
+}
@@ -0,0 +1,191 @@
+package wrapper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNotRunning is returned by a Supervisor asked about its server while
+	// it is not running.
+	ErrNotRunning = errors.New("server not running")
+	// ErrBackupDisabled is returned when backing up a server with no backup
+	// directory configured.
+	ErrBackupDisabled = errors.New("backup not configured")
+)
+
+// supervisorStopTimeout bounds the stop of the server once the Supervisor
+// is done, when the config sets no stop timeout. It is killed past it.
+const supervisorStopTimeout = 1 * time.Minute
+
+// Supervisor runs the server of a config, restarting it following its
+// restart policy and backing it up on its schedule. A new Wrapper is run
+// on every restart, see 'Wrapper'.
+type Supervisor struct {
+	// Logf reports the restarts and the failed backups, if not nil.
+	Logf func(format string, args ...interface{})
+	// Output receives the console lines of the server, if not nil.
+	Output io.Writer
+
+	cfg        ServerConfig
+	newWrapper func(ServerConfig) (*Wrapper, error)
+	mu         sync.Mutex
+	wrapper    *Wrapper
+	// backupMu serializes the scheduled backups and the ones requested.
+	backupMu sync.Mutex
+}
+
+// NewSupervisor returns a Supervisor of the server of the config.
+func NewSupervisor(cfg ServerConfig) *Supervisor {
+	return &Supervisor{cfg: cfg, newWrapper: NewConfiguredWrapper}
+}
+
+// Config returns the config of the server.
+func (s *Supervisor) Config() ServerConfig {
+	return s.cfg
+}
+
+// Wrapper returns the wrapper of the server being run, or nil before the
+// first start.
+func (s *Supervisor) Wrapper() *Wrapper {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.wrapper
+}
+
+// Backup backs up the world of the server to its backup directory.
+func (s *Supervisor) Backup(ctx context.Context) (string, error) {
+	if s.cfg.Backup.Dir == "" {
+		return "", ErrBackupDisabled
+	}
+	w := s.Wrapper()
+	if w == nil {
+		return "", ErrNotRunning
+	}
+	s.backupMu.Lock()
+	defer s.backupMu.Unlock()
+	return w.Backup(ctx, s.cfg.Dir, s.cfg.Backup)
+}
+
+// Run starts the server and supervises it until the context is done, the
+// server being stopped then, or until it is not restarted anymore. It
+// returns the error the server last stopped on, nil on a clean stop.
+func (s *Supervisor) Run(ctx context.Context) error {
+	if s.cfg.Backup.Interval > 0 {
+		backupCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		go s.scheduleBackups(backupCtx)
+	}
+
+	restarts := 0
+	for {
+		loaded, err := s.runOnce(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if loaded {
+			restarts = 0
+		}
+		switch s.cfg.Restart.Mode {
+		case RestartAlways:
+		case RestartOnFailure:
+			if err == nil {
+				return nil
+			}
+		default:
+			return err
+		}
+		if s.cfg.Restart.MaxRestarts > 0 && restarts >= s.cfg.Restart.MaxRestarts {
+			return fmt.Errorf("giving up after %d restarts: %w", restarts, err)
+		}
+		restarts++
+		s.logf("restarting server (%d): %v", restarts, err)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(s.cfg.Restart.Delay):
+		}
+	}
+}
+
+// runOnce runs the server until it stops, or until the context is done,
+// returning whether the server loaded.
+func (s *Supervisor) runOnce(ctx context.Context) (bool, error) {
+	w, err := s.newWrapper(s.cfg)
+	if err != nil {
+		return false, err
+	}
+	s.mu.Lock()
+	s.wrapper = w
+	s.mu.Unlock()
+	if s.Output != nil {
+		lines, cancel := w.lines.subscribe()
+		defer cancel()
+		go func() {
+			for line := range lines {
+				fmt.Fprintln(s.Output, line)
+			}
+		}()
+	}
+
+	startCtx, cancel := withTimeout(ctx, s.cfg.Timeouts.Start)
+	err = startServer(startCtx, w)
+	cancel()
+	if err != nil {
+		if ctx.Err() == nil {
+			err = fmt.Errorf("start: %w", err)
+		}
+		s.stop(w)
+		return false, err
+	}
+
+	select {
+	case err := <-w.Exited():
+		return true, err
+	case <-ctx.Done():
+		s.stop(w)
+		return true, nil
+	}
+}
+
+// stop stops the server, killing it past the stop timeout.
+func (s *Supervisor) stop(w *Wrapper) {
+	timeout := s.cfg.Timeouts.Stop
+	if timeout <= 0 {
+		timeout = supervisorStopTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := stopServer(ctx, w); err != nil {
+		s.logf("killing server: %v", err)
+		w.Kill()
+	}
+}
+
+func (s *Supervisor) scheduleBackups(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.Backup.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if w := s.Wrapper(); w == nil || w.State() != WrapperOnline {
+				continue
+			}
+			if _, err := s.Backup(ctx); err != nil {
+				s.logf("backup: %v", err)
+			}
+		}
+	}
+}
+
+func (s *Supervisor) logf(format string, args ...interface{}) {
+	if s.Logf != nil {
+		s.Logf(format, args...)
+	}
+}
@@ -0,0 +1,155 @@
+package wrapper
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// crash ends the console output without the server logging its stop.
+func (tc *managerTestConsole) crash() {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	tc.stopped = true
+	close(tc.lines)
+}
+
+type supervisedServer struct {
+	wrapper *Wrapper
+	console *managerTestConsole
+	// lines are the console lines read by the wrapper since its creation.
+	lines <-chan string
+}
+
+// newTestSupervisor returns a Supervisor running its servers on test
+// consoles, sent to the returned channel as they are created.
+func newTestSupervisor(policy RestartPolicy) (*Supervisor, chan supervisedServer) {
+	servers := make(chan supervisedServer, 8)
+	s := NewSupervisor(ServerConfig{Restart: policy})
+	s.newWrapper = func(ServerConfig) (*Wrapper, error) {
+		tc := newManagerTestConsole(newStartGauge(0))
+		w := NewWrapper(tc, NewLogParser(VanillaLogProfile))
+		lines, _ := w.lines.subscribe()
+		servers <- supervisedServer{wrapper: w, console: tc, lines: lines}
+		return w, nil
+	}
+	return s, servers
+}
+
+// waitOnline waits for a wrapper to read the end of the server load.
+func waitOnline(t *testing.T, lines <-chan string) {
+	for {
+		select {
+		case line := <-lines:
+			if strings.Contains(line, "Done (") {
+				return
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("timeout waiting for the server to be online")
+		}
+	}
+}
+
+func TestSupervisorRestart(t *testing.T) {
+	s, servers := newTestSupervisor(RestartPolicy{Mode: RestartOnFailure, MaxRestarts: 1})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Run(ctx)
+	}()
+
+	// The restarts are counted in a row, the server loading in between.
+	for i := 0; i < 2; i++ {
+		srv := <-servers
+		waitOnline(t, srv.lines)
+		srv.console.crash()
+	}
+	srv := <-servers
+	waitOnline(t, srv.lines)
+	if s.Wrapper() != srv.wrapper {
+		t.Error("supervisor should return the wrapper of the last start")
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("supervisor should stop the server cleanly, got %v", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("timeout waiting for the supervisor to stop")
+	}
+	if cmds := srv.console.written(); len(cmds) != 1 || cmds[0] != "stop" {
+		t.Errorf("server should be stopped, got %v", cmds)
+	}
+	if len(servers) != 0 {
+		t.Errorf("server should not be restarted once stopped, %d more starts", len(servers))
+	}
+}
+
+func TestSupervisorGiveUp(t *testing.T) {
+	s := NewSupervisor(ServerConfig{Restart: RestartPolicy{Mode: RestartAlways, MaxRestarts: 2}})
+	starts := 0
+	failure := errors.New("java not found")
+	s.newWrapper = func(ServerConfig) (*Wrapper, error) {
+		starts++
+		return nil, failure
+	}
+	if err := s.Run(context.Background()); !errors.Is(err, failure) {
+		t.Errorf("supervisor should give up on the start failure, got %v", err)
+	}
+	if starts != 3 {
+		t.Errorf("server should be restarted twice, got %d starts", starts)
+	}
+}
+
+func TestSupervisorNoRestart(t *testing.T) {
+	s, servers := newTestSupervisor(RestartPolicy{Mode: RestartOnFailure})
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Run(context.Background())
+	}()
+	srv := <-servers
+	waitOnline(t, srv.lines)
+	// Stopped from the console, not by the supervisor.
+	srv.console.WriteCmd("stop")
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("clean stop should not be an error, got %v", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("supervisor should not restart a server stopped cleanly")
+	}
+	if _, err := s.Backup(context.Background()); !errors.Is(err, ErrBackupDisabled) {
+		t.Errorf("backup with no backup dir should fail, got %v", err)
+	}
+}
+
+func TestSupervisorStartExited(t *testing.T) {
+	s := NewSupervisor(ServerConfig{Restart: RestartPolicy{Mode: RestartOnFailure, MaxRestarts: 1}})
+	starts := 0
+	s.newWrapper = func(ServerConfig) (*Wrapper, error) {
+		starts++
+		tc := exitingTestConsole{newManagerTestConsole(nil)}
+		return NewWrapper(tc, NewLogParser(VanillaLogProfile)), nil
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Run(context.Background())
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrServerExited) {
+			t.Errorf("supervisor should give up on the server exit, got %v", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("supervisor should not hang on a server exiting while starting")
+	}
+	if starts != 2 {
+		t.Errorf("server should be restarted once, got %d starts", starts)
+	}
+}
@@ -0,0 +1,373 @@
+package text
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+)
+
+var (
+	// ErrInvalidColor is returned when serializing a component colored with
+	// neither a named color nor a "#RRGGBB" hex color.
+	ErrInvalidColor = errors.New("invalid text color")
+	// ErrInvalidURL is returned when serializing a component opening an url
+	// that is not http(s), which the game refuses to open.
+	ErrInvalidURL = errors.New("invalid click url")
+	// ErrHexColor is returned when serializing a component colored with a
+	// hex color for a server prior to 1.16, which only knows the named ones.
+	ErrHexColor = errors.New("hex colors are only supported since 1.16")
+)
+
+// Color is the color of a text component, either named or a "#RRGGBB" hex
+// color (1.16+).
+type Color string
+
+const (
+	Black       Color = "black"
+	DarkBlue    Color = "dark_blue"
+	DarkGreen   Color = "dark_green"
+	DarkAqua    Color = "dark_aqua"
+	DarkRed     Color = "dark_red"
+	DarkPurple  Color = "dark_purple"
+	Gold        Color = "gold"
+	Gray        Color = "gray"
+	DarkGray    Color = "dark_gray"
+	Blue        Color = "blue"
+	Green       Color = "green"
+	Aqua        Color = "aqua"
+	Red         Color = "red"
+	LightPurple Color = "light_purple"
+	Yellow      Color = "yellow"
+	White       Color = "white"
+	// Reset resets the color inherited from the parent component.
+	Reset Color = "reset"
+)
+
+var hexColorRegex = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// Hex returns the color of the given red, green and blue values.
+func Hex(r, g, b uint8) Color {
+	return Color(fmt.Sprintf("#%02x%02x%02x", r, g, b))
+}
+
+func (c Color) valid() bool {
+	switch c {
+	case Black, DarkBlue, DarkGreen, DarkAqua, DarkRed, DarkPurple, Gold, Gray,
+		DarkGray, Blue, Green, Aqua, Red, LightPurple, Yellow, White, Reset:
+		return true
+	}
+	return hexColorRegex.MatchString(string(c))
+}
+
+// ClickAction is the action run when a player clicks a text component.
+type ClickAction string
+
+const (
+	RunCommand      ClickAction = "run_command"
+	SuggestCommand  ClickAction = "suggest_command"
+	OpenURL         ClickAction = "open_url"
+	CopyToClipboard ClickAction = "copy_to_clipboard"
+	ChangePage      ClickAction = "change_page"
+)
+
+// Component is a Minecraft JSON text component, see:
+// https://minecraft.gamepedia.com/Raw_JSON_text_format. Components are built
+// by chaining their methods, each returning a modified copy:
+//
+//	text.Text("Vote for the next map").Color(text.Gold).Bold().RunCommand("/trigger vote")
+type Component struct {
+	text      *string
+	translate string
+	with      []Component
+	selector  string
+	score     *score
+	keybind   string
+
+	color         Color
+	bold          *bool
+	italic        *bool
+	underlined    *bool
+	strikethrough *bool
+	obfuscated    *bool
+	insertion     string
+	click         *clickEvent
+	hover         *Component
+	extra         []Component
+}
+
+type score struct {
+	Name      string `json:"name"`
+	Objective string `json:"objective"`
+}
+
+type clickEvent struct {
+	Action ClickAction `json:"action"`
+	Value  string      `json:"value"`
+}
+
+// hoverEvent holds the hovered component in 'contents' since 1.16, and in
+// 'value' before.
+type hoverEvent struct {
+	Action   string          `json:"action"`
+	Contents json.RawMessage `json:"contents,omitempty"`
+	Value    json.RawMessage `json:"value,omitempty"`
+}
+
+// jsonComponent is the serialized form of a Component.
+type jsonComponent struct {
+	Text          *string           `json:"text,omitempty"`
+	Translate     string            `json:"translate,omitempty"`
+	With          []json.RawMessage `json:"with,omitempty"`
+	Selector      string            `json:"selector,omitempty"`
+	Score         *score            `json:"score,omitempty"`
+	Keybind       string            `json:"keybind,omitempty"`
+	Color         Color             `json:"color,omitempty"`
+	Bold          *bool             `json:"bold,omitempty"`
+	Italic        *bool             `json:"italic,omitempty"`
+	Underlined    *bool             `json:"underlined,omitempty"`
+	Strikethrough *bool             `json:"strikethrough,omitempty"`
+	Obfuscated    *bool             `json:"obfuscated,omitempty"`
+	Insertion     string            `json:"insertion,omitempty"`
+	ClickEvent    *clickEvent       `json:"clickEvent,omitempty"`
+	HoverEvent    *hoverEvent       `json:"hoverEvent,omitempty"`
+	Extra         []json.RawMessage `json:"extra,omitempty"`
+}
+
+// Text returns a component of plain text.
+func Text(s string) Component {
+	return Component{text: &s}
+}
+
+// Join returns a component made of the given components, which inherit
+// its formatting.
+func Join(cs ...Component) Component {
+	return Text("").Extra(cs...)
+}
+
+// Translate returns a component of the translation of the given key, like
+// "chat.type.announcement", formatted with the given components.
+func Translate(key string, with ...Component) Component {
+	return Component{translate: key, with: with}
+}
+
+// Selector returns a component of the names of the entities matching the
+// given selector, like "@p".
+func Selector(selector string) Component {
+	return Component{selector: selector}
+}
+
+// Score returns a component of the score of an entity in an objective, the
+// name being a player name or a selector matching a single entity.
+func Score(name, objective string) Component {
+	return Component{score: &score{Name: name, Objective: objective}}
+}
+
+// Keybind returns a component of the key bound to an action on the player
+// client, like "key.jump".
+func Keybind(key string) Component {
+	return Component{keybind: key}
+}
+
+// Color sets the color of the component.
+func (c Component) Color(color Color) Component {
+	c.color = color
+	return c
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+// Bold makes the component bold.
+func (c Component) Bold() Component {
+	c.bold = boolPtr(true)
+	return c
+}
+
+// Italic makes the component italic.
+func (c Component) Italic() Component {
+	c.italic = boolPtr(true)
+	return c
+}
+
+// Underlined makes the component underlined.
+func (c Component) Underlined() Component {
+	c.underlined = boolPtr(true)
+	return c
+}
+
+// Strikethrough makes the component struck through.
+func (c Component) Strikethrough() Component {
+	c.strikethrough = boolPtr(true)
+	return c
+}
+
+// Obfuscated makes the component obfuscated.
+func (c Component) Obfuscated() Component {
+	c.obfuscated = boolPtr(true)
+	return c
+}
+
+// Plain removes all the formatting inherited from the parent component.
+func (c Component) Plain() Component {
+	c.color = Reset
+	c.bold = boolPtr(false)
+	c.italic = boolPtr(false)
+	c.underlined = boolPtr(false)
+	c.strikethrough = boolPtr(false)
+	c.obfuscated = boolPtr(false)
+	return c
+}
+
+// Insertion sets the text inserted in the player chat input when the
+// component is shift-clicked.
+func (c Component) Insertion(s string) Component {
+	c.insertion = s
+	return c
+}
+
+// OnClick sets the action run when the component is clicked.
+func (c Component) OnClick(action ClickAction, value string) Component {
+	c.click = &clickEvent{Action: action, Value: value}
+	return c
+}
+
+// RunCommand makes the player run the command when clicking the component.
+func (c Component) RunCommand(cmd string) Component {
+	return c.OnClick(RunCommand, cmd)
+}
+
+// SuggestCommand fills the player chat input with the command when clicking
+// the component.
+func (c Component) SuggestCommand(cmd string) Component {
+	return c.OnClick(SuggestCommand, cmd)
+}
+
+// OpenURL opens the url when the player clicks the component.
+func (c Component) OpenURL(u string) Component {
+	return c.OnClick(OpenURL, u)
+}
+
+// OnHover sets the component shown when the player hovers the component.
+func (c Component) OnHover(hover Component) Component {
+	c.hover = &hover
+	return c
+}
+
+// Extra appends components to the component, which inherit its formatting.
+func (c Component) Extra(cs ...Component) Component {
+	c.extra = append(c.extra[:len(c.extra):len(c.extra)], cs...)
+	return c
+}
+
+// Validate returns an error if the component, or any of its children, can
+// not be displayed by the game.
+func (c Component) Validate() error {
+	if c.color != "" && !c.color.valid() {
+		return fmt.Errorf("%w: %s", ErrInvalidColor, c.color)
+	}
+	if c.click != nil && c.click.Action == OpenURL {
+		u, err := url.Parse(c.click.Value)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return fmt.Errorf("%w: %s", ErrInvalidURL, c.click.Value)
+		}
+	}
+	children := append(append([]Component{}, c.with...), c.extra...)
+	if c.hover != nil {
+		children = append(children, *c.hover)
+	}
+	for _, child := range children {
+		if err := child.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MarshalJSON serializes the component to the raw JSON text format.
+func (c Component) MarshalJSON() ([]byte, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c.marshal(false)
+}
+
+// MarshalLegacyJSON serializes the component to the raw JSON text format of
+// the servers prior to 1.16, which hold the hovered component in 'value' and
+// return ErrHexColor for the hex colors.
+func (c Component) MarshalLegacyJSON() ([]byte, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c.marshal(true)
+}
+
+// marshal serializes a validated component and its children.
+func (c Component) marshal(legacy bool) ([]byte, error) {
+	if legacy && hexColorRegex.MatchString(string(c.color)) {
+		return nil, fmt.Errorf("%w: %s", ErrHexColor, c.color)
+	}
+	marshalAll := func(cs []Component) ([]json.RawMessage, error) {
+		var raws []json.RawMessage
+		for _, child := range cs {
+			b, err := child.marshal(legacy)
+			if err != nil {
+				return nil, err
+			}
+			raws = append(raws, b)
+		}
+		return raws, nil
+	}
+	with, err := marshalAll(c.with)
+	if err != nil {
+		return nil, err
+	}
+	extra, err := marshalAll(c.extra)
+	if err != nil {
+		return nil, err
+	}
+	jc := jsonComponent{
+		Text:          c.text,
+		Translate:     c.translate,
+		With:          with,
+		Selector:      c.selector,
+		Score:         c.score,
+		Keybind:       c.keybind,
+		Color:         c.color,
+		Bold:          c.bold,
+		Italic:        c.italic,
+		Underlined:    c.underlined,
+		Strikethrough: c.strikethrough,
+		Obfuscated:    c.obfuscated,
+		Insertion:     c.insertion,
+		ClickEvent:    c.click,
+		Extra:         extra,
+	}
+	if c.text == nil && c.translate == "" && c.selector == "" && c.score == nil && c.keybind == "" {
+		// A component must hold some content, even if only made of extras.
+		empty := ""
+		jc.Text = &empty
+	}
+	if c.hover != nil {
+		hover, err := c.hover.marshal(legacy)
+		if err != nil {
+			return nil, err
+		}
+		jc.HoverEvent = &hoverEvent{Action: "show_text", Contents: hover}
+		if legacy {
+			jc.HoverEvent = &hoverEvent{Action: "show_text", Value: hover}
+		}
+	}
+	return json.Marshal(jc)
+}
+
+// String returns the JSON of the component, or an empty string if invalid.
+func (c Component) String() string {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
@@ -0,0 +1,100 @@
+package text
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestComponentJSON(t *testing.T) {
+	tests := []struct {
+		c        Component
+		expected string
+	}{
+		{Text("hello"), `{"text":"hello"}`},
+		{Text(""), `{"text":""}`},
+		{
+			Text("Vote").Color(Gold).Bold().Underlined().RunCommand("/trigger vote"),
+			`{"text":"Vote","color":"gold","bold":true,"underlined":true,"clickEvent":{"action":"run_command","value":"/trigger vote"}}`,
+		},
+		{
+			Text("wiki").Italic().OpenURL("https://minecraft.gamepedia.com").OnHover(Text("Open the wiki").Color(Gray)),
+			`{"text":"wiki","italic":true,"clickEvent":{"action":"open_url","value":"https://minecraft.gamepedia.com"},"hoverEvent":{"action":"show_text","contents":{"text":"Open the wiki","color":"gray"}}}`,
+		},
+		{
+			Join(Text("[Server] ").Color(Red), Text("Restarting").Plain()).Bold(),
+			`{"text":"","bold":true,"extra":[{"text":"[Server] ","color":"red"},{"text":"Restarting","color":"reset","bold":false,"italic":false,"underlined":false,"strikethrough":false,"obfuscated":false}]}`,
+		},
+		{
+			Translate("chat.type.announcement", Selector("@p"), Text("hi")).SuggestCommand("/msg "),
+			`{"translate":"chat.type.announcement","with":[{"selector":"@p"},{"text":"hi"}],"clickEvent":{"action":"suggest_command","value":"/msg "}}`,
+		},
+		{Score("@s", "kills").Color(Hex(255, 128, 0)), `{"score":{"name":"@s","objective":"kills"},"color":"#ff8000"}`},
+		{Keybind("key.jump").Strikethrough().Obfuscated().Insertion("jump"), `{"keybind":"key.jump","strikethrough":true,"obfuscated":true,"insertion":"jump"}`},
+	}
+	for _, tt := range tests {
+		b, err := json.Marshal(tt.c)
+		if err != nil {
+			t.Errorf("%s: %s", tt.expected, err)
+			continue
+		}
+		if string(b) != tt.expected {
+			t.Errorf("wrong component json:\nactual  : %s\nexpected: %s", b, tt.expected)
+		}
+	}
+}
+
+func TestComponentLegacyJSON(t *testing.T) {
+	c := Join(Text("wiki").OnHover(Text("Open the wiki").Color(Gray)))
+	b, err := c.MarshalLegacyJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"text":"","extra":[{"text":"wiki","hoverEvent":{"action":"show_text","value":{"text":"Open the wiki","color":"gray"}}}]}`
+	if string(b) != expected {
+		t.Errorf("wrong legacy component json:\nactual  : %s\nexpected: %s", b, expected)
+	}
+
+	hex := []Component{
+		Text("a").Color(Hex(255, 128, 0)),
+		Translate("chat.type.text", Text("a").Color("#00ff00")),
+		Text("a").OnHover(Text("b").Color("#00ff00")),
+	}
+	for _, c := range hex {
+		if _, err := c.MarshalLegacyJSON(); !errors.Is(err, ErrHexColor) {
+			t.Errorf("hex color should not be serialized for legacy servers, got %v", err)
+		}
+	}
+}
+
+func TestComponentCopy(t *testing.T) {
+	base := Join(Text("a"))
+	b1 := base.Extra(Text("b"))
+	b2 := base.Extra(Text("c"))
+	if b1.String() == b2.String() || base.String() != `{"text":"","extra":[{"text":"a"}]}` {
+		t.Errorf("components should be copied on change: base=%s b1=%s b2=%s", base, b1, b2)
+	}
+}
+
+func TestComponentValidate(t *testing.T) {
+	tests := []struct {
+		c   Component
+		err error
+	}{
+		{Text("a").Color("pink"), ErrInvalidColor},
+		{Text("a").Color("#12345"), ErrInvalidColor},
+		{Join(Text("a").Color("#GGGGGG")), ErrInvalidColor},
+		{Text("a").OpenURL("file:///etc/passwd"), ErrInvalidURL},
+		{Text("a").OnHover(Text("b").OpenURL("javascript:alert(1)")), ErrInvalidURL},
+		{Text("a").Color(DarkPurple).OpenURL("http://example.com"), nil},
+	}
+	for _, tt := range tests {
+		err := tt.c.Validate()
+		if !errors.Is(err, tt.err) {
+			t.Errorf("wrong validation error: actual=%v, expected=%v", err, tt.err)
+		}
+		if _, err := json.Marshal(tt.c); tt.err != nil && err == nil {
+			t.Errorf("invalid component should fail to marshal")
+		}
+	}
+}
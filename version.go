@@ -0,0 +1,99 @@
+package wrapper
+
+import (
+	"strconv"
+	"strings"
+)
+
+// gameVersion is a parsed minecraft release version, for example: 1.16.4.
+// The zero value represents an unknown version, which is considered to be
+// the latest release.
+type gameVersion struct {
+	major int
+	minor int
+	patch int
+}
+
+// parseGameVersion parses the version logged by the server on start. Only
+// the leading release number is kept from pre-releases and release candidates
+// ("1.17-pre1", "1.17 Release Candidate 1"), while snapshots like "21w15a"
+// can not be mapped to a release and are reported as unknown.
+func parseGameVersion(v string) (gameVersion, bool) {
+	if i := strings.IndexAny(v, " -"); i >= 0 {
+		v = v[:i]
+	}
+	parts := strings.Split(v, ".")
+	if len(parts) < 2 || len(parts) > 3 {
+		return gameVersion{}, false
+	}
+	nums := [3]int{}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return gameVersion{}, false
+		}
+		nums[i] = n
+	}
+	return gameVersion{major: nums[0], minor: nums[1], patch: nums[2]}, true
+}
+
+func mustParseGameVersion(v string) gameVersion {
+	gv, ok := parseGameVersion(v)
+	if !ok {
+		panic("invalid game version: " + v)
+	}
+	return gv
+}
+
+func (v gameVersion) known() bool {
+	return v != gameVersion{}
+}
+
+func (v gameVersion) less(o gameVersion) bool {
+	if v.major != o.major {
+		return v.major < o.major
+	}
+	if v.minor != o.minor {
+		return v.minor < o.minor
+	}
+	return v.patch < o.patch
+}
+
+// within returns whether the version is in the range [since, until), where
+// unknown bounds are left open. An unknown version is only within ranges
+// left open on the upper bound.
+func (v gameVersion) within(since, until gameVersion) bool {
+	if !v.known() {
+		return !until.known()
+	}
+	if since.known() && v.less(since) {
+		return false
+	}
+	if until.known() && !v.less(until) {
+		return false
+	}
+	return true
+}
+
+func (v gameVersion) String() string {
+	s := strconv.Itoa(v.major) + "." + strconv.Itoa(v.minor)
+	if v.patch > 0 {
+		s += "." + strconv.Itoa(v.patch)
+	}
+	return s
+}
+
+// Versions from which the command syntax and output changed.
+var (
+	// versionFlattening (1.13) rewrote the command system, most commands
+	// outputs were reworded and commands like 'data' or 'experience' added.
+	versionFlattening = mustParseGameVersion("1.13")
+	// versionForceLoad (1.14) added the 'forceload' command.
+	versionForceLoad = mustParseGameVersion("1.14")
+	// versionHexColors (1.16) added the hex colors to the text components,
+	// whose hovered component moved from 'value' to 'contents'.
+	versionHexColors = mustParseGameVersion("1.16")
+	// versionSecureChat (1.19.1) prefixes unsigned chat messages with
+	// "[Not Secure]" in the server logs.
+	versionSecureChat = mustParseGameVersion("1.19.1")
+)
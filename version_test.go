@@ -0,0 +1,64 @@
+package wrapper
+
+import (
+	"testing"
+)
+
+func TestParseGameVersion(t *testing.T) {
+	tests := []struct {
+		version  string
+		expected gameVersion
+		ok       bool
+	}{
+		{"1.16.4", gameVersion{1, 16, 4}, true},
+		{"1.12", gameVersion{1, 12, 0}, true},
+		{"1.17-pre1", gameVersion{1, 17, 0}, true},
+		{"1.17 Release Candidate 1", gameVersion{1, 17, 0}, true},
+		{"21w15a", gameVersion{}, false},
+		{"", gameVersion{}, false},
+	}
+	for _, tt := range tests {
+		v, ok := parseGameVersion(tt.version)
+		if ok != tt.ok || v != tt.expected {
+			t.Errorf("failed to parse version %s: got %v (%t)", tt.version, v, ok)
+		}
+	}
+}
+
+func TestGameVersionWithin(t *testing.T) {
+	v1_12 := mustParseGameVersion("1.12.2")
+	v1_16 := mustParseGameVersion("1.16.5")
+	unknown := gameVersion{}
+
+	if v1_12.within(versionFlattening, gameVersion{}) {
+		t.Error("1.12.2 should not be within [1.13, latest)")
+	}
+	if !v1_12.within(gameVersion{}, versionFlattening) {
+		t.Error("1.12.2 should be within [oldest, 1.13)")
+	}
+	if !v1_16.within(versionFlattening, gameVersion{}) {
+		t.Error("1.16.5 should be within [1.13, latest)")
+	}
+	if !unknown.within(versionSecureChat, gameVersion{}) {
+		t.Error("an unknown version should be considered the latest")
+	}
+	if unknown.within(gameVersion{}, versionFlattening) {
+		t.Error("an unknown version should not be within legacy ranges")
+	}
+}
+
+func TestWrapperRequireVersion(t *testing.T) {
+	wpr := NewWrapper(&testConsole{}, logParserFunc)
+	wpr.setVersion("1.12.2")
+	if _, err := wpr.DataGet("entity", "player1"); err != ErrUnsupportedVersion {
+		t.Errorf("DataGet should not be supported on 1.12.2, got %v", err)
+	}
+	if err := wpr.ForceLoadRemoveAll(); err != ErrUnsupportedVersion {
+		t.Errorf("ForceLoadRemoveAll should not be supported on 1.12.2, got %v", err)
+	}
+
+	wpr.setVersion("1.16.5")
+	if _, err := wpr.DataGet("entity", "player1"); err != ErrWrapperNotOnline {
+		t.Errorf("DataGet should be supported on 1.16.5, got %v", err)
+	}
+}
@@ -0,0 +1,127 @@
+package wrapper
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ErrInvalidEntry is returned when reading or writing a server list file,
+// like 'whitelist.json', holding an entry the server would not load.
+var ErrInvalidEntry = errors.New("invalid list entry")
+
+var (
+	playerNameRegex = regexp.MustCompile(`^[A-Za-z0-9_]{1,16}$`)
+	uuidRegex       = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+)
+
+// validateProfile returns ErrInvalidEntry if the player name or UUID of a
+// server list entry is malformed.
+func validateProfile(uuid, name string) error {
+	if !uuidRegex.MatchString(uuid) {
+		return fmt.Errorf("%w: invalid uuid %q for %s", ErrInvalidEntry, uuid, name)
+	}
+	if !playerNameRegex.MatchString(name) {
+		return fmt.Errorf("%w: invalid player name %q", ErrInvalidEntry, name)
+	}
+	return nil
+}
+
+// WhitelistEntry is a player allowed to join the server when the whitelist
+// is on, as saved in 'whitelist.json'.
+type WhitelistEntry struct {
+	UUID string `json:"uuid"`
+	Name string `json:"name"`
+}
+
+func validateWhitelist(entries []WhitelistEntry) error {
+	seen := map[string]bool{}
+	for _, e := range entries {
+		if err := validateProfile(e.UUID, e.Name); err != nil {
+			return err
+		}
+		uuid := strings.ToLower(e.UUID)
+		if seen[uuid] {
+			return fmt.Errorf("%w: duplicated uuid %s", ErrInvalidEntry, e.UUID)
+		}
+		seen[uuid] = true
+	}
+	return nil
+}
+
+// ReadWhitelistFile returns the entries of a 'whitelist.json' file, or none
+// if it does not exist.
+func ReadWhitelistFile(path string) ([]WhitelistEntry, error) {
+	entries := []WhitelistEntry{}
+	if err := readJSONFile(path, &entries); err != nil {
+		return nil, err
+	}
+	if err := validateWhitelist(entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// WriteWhitelistFile validates and writes the entries to a 'whitelist.json'
+// file. The server only reads the file on start or on 'WhitelistReload',
+// and overwrites it on any whitelist command: it should be written while
+// the server is offline.
+func WriteWhitelistFile(path string, entries []WhitelistEntry) error {
+	if err := validateWhitelist(entries); err != nil {
+		return err
+	}
+	if entries == nil {
+		entries = []WhitelistEntry{}
+	}
+	return writeJSONFile(path, entries)
+}
+
+// ReconcileWhitelist brings the server whitelist in line with the given
+// players, adding the missing ones and removing the others, and returns the
+// players added and removed. Players are compared case-insensitively, as the
+// server does. It stops on the first command failing.
+func (w *Wrapper) ReconcileWhitelist(players []string) (added, removed []string, err error) {
+	current, err := w.WhitelistList()
+	if err != nil {
+		return nil, nil, err
+	}
+	toAdd, toRemove := diffNames(players, current)
+	for _, p := range toAdd {
+		if err := w.WhitelistAdd(PlayerName(p)); err != nil {
+			return added, removed, fmt.Errorf("whitelist add %s: %w", p, err)
+		}
+		added = append(added, p)
+	}
+	for _, p := range toRemove {
+		if err := w.WhitelistRemove(PlayerName(p)); err != nil {
+			return added, removed, fmt.Errorf("whitelist remove %s: %w", p, err)
+		}
+		removed = append(removed, p)
+	}
+	return added, removed, nil
+}
+
+// diffNames returns the names desired but not current, and those current
+// but not desired, compared case-insensitively.
+func diffNames(desired, current []string) (missing, extra []string) {
+	want := map[string]bool{}
+	for _, n := range desired {
+		want[strings.ToLower(n)] = true
+	}
+	have := map[string]bool{}
+	for _, n := range current {
+		have[strings.ToLower(n)] = true
+		if !want[strings.ToLower(n)] {
+			extra = append(extra, n)
+		}
+	}
+	for _, n := range desired {
+		if !have[strings.ToLower(n)] {
+			missing = append(missing, n)
+			// Skip the names desired twice.
+			have[strings.ToLower(n)] = true
+		}
+	}
+	return missing, extra
+}
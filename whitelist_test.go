@@ -0,0 +1,129 @@
+package wrapper
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWhitelistFile(t *testing.T) {
+	entries, err := ReadWhitelistFile("testdata/server_files/whitelist.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []WhitelistEntry{
+		{UUID: "7d3b5c1e-2f6a-4f8e-9b0a-1c2d3e4f5a6b", Name: "player1"},
+		{UUID: "0c1d2e3f-4a5b-4c6d-8e7f-9a0b1c2d3e4f", Name: "player2"},
+	}
+	if !reflect.DeepEqual(entries, expected) {
+		t.Fatalf("wrong entries: %+v", entries)
+	}
+
+	dir := testDir(t)
+	path := filepath.Join(dir, "whitelist.json")
+
+	if entries, err := ReadWhitelistFile(path); err != nil || len(entries) != 0 {
+		t.Errorf("missing file should have no entries, got %v, %v", entries, err)
+	}
+	entries = append(entries, WhitelistEntry{UUID: "069a79f4-44e9-4726-a5be-fca90e38aaf5", Name: "player3"})
+	if err := WriteWhitelistFile(path, entries); err != nil {
+		t.Fatal(err)
+	}
+	if read, err := ReadWhitelistFile(path); err != nil || !reflect.DeepEqual(read, entries) {
+		t.Errorf("wrong entries read back: %+v, %v", read, err)
+	}
+
+	invalid := [][]WhitelistEntry{
+		{{UUID: "7d3b5c1e", Name: "player1"}},
+		{{UUID: "7d3b5c1e-2f6a-4f8e-9b0a-1c2d3e4f5a6b", Name: "player 1"}},
+		{{UUID: "7d3b5c1e-2f6a-4f8e-9b0a-1c2d3e4f5a6b", Name: "player1"}, {UUID: "7D3B5C1E-2F6A-4F8E-9B0A-1C2D3E4F5A6B", Name: "player2"}},
+	}
+	for _, entries := range invalid {
+		if err := WriteWhitelistFile(path, entries); !errors.Is(err, ErrInvalidEntry) {
+			t.Errorf("%+v should be invalid, got %v", entries, err)
+		}
+	}
+}
+
+func TestDiffNames(t *testing.T) {
+	missing, extra := diffNames([]string{"player1", "Player2", "player4", "player4"}, []string{"player1", "player2", "player3"})
+	if !reflect.DeepEqual(missing, []string{"player4"}) || !reflect.DeepEqual(extra, []string{"player3"}) {
+		t.Errorf("wrong diff: missing=%v, extra=%v", missing, extra)
+	}
+}
+
+func TestWrapperWhitelist(t *testing.T) {
+	tests := []struct {
+		version   string
+		responses map[string][]string
+	}{
+		{"1.16.5", map[string][]string{
+			"whitelist on":             {"[12:01:00] [Server thread/INFO]: Whitelist is now turned on"},
+			"whitelist off":            {"[12:01:00] [Server thread/INFO]: Whitelist is already turned off"},
+			"whitelist add player1":    {"[12:01:00] [Server thread/INFO]: Added player1 to the whitelist"},
+			"whitelist add player9":    {"[12:01:00] [Server thread/INFO]: That player does not exist"},
+			"whitelist remove player2": {"[12:01:00] [Server thread/INFO]: Player is not whitelisted"},
+			"whitelist reload":         {"[12:01:00] [Server thread/INFO]: Reloaded the whitelist"},
+		}},
+		{"1.12.2", map[string][]string{
+			"whitelist on":             {"[12:01:00] [Server thread/INFO]: Turned on the whitelist"},
+			"whitelist add player1":    {"[12:01:00] [Server thread/INFO]: Added player1 to the whitelist"},
+			"whitelist remove player2": {"[12:01:00] [Server thread/INFO]: Could not remove player2 from the whitelist"},
+			"whitelist reload":         {"[12:01:00] [Server thread/INFO]: Reloaded the whitelist"},
+		}},
+	}
+	for _, tt := range tests {
+		wpr, _ := newCmdTestWrapper(t, tt.version, tt.responses)
+		if err := wpr.WhitelistOn(); err != nil {
+			t.Errorf("%s: %s", tt.version, err)
+		}
+		if err := wpr.WhitelistAdd(PlayerName("player1")); err != nil {
+			t.Errorf("%s: %s", tt.version, err)
+		}
+		if err := wpr.WhitelistRemove(PlayerName("player2")); err == nil {
+			t.Errorf("%s: removing a player not whitelisted should fail", tt.version)
+		}
+		if err := wpr.WhitelistReload(); err != nil {
+			t.Errorf("%s: %s", tt.version, err)
+		}
+		if tt.version == "1.12.2" {
+			if _, err := wpr.WhitelistList(); err != ErrUnsupportedVersion {
+				t.Errorf("%s: expected ErrUnsupportedVersion, got %v", tt.version, err)
+			}
+			continue
+		}
+		if err := wpr.WhitelistOff(); err == nil || err.Error() != "Whitelist is already turned off" {
+			t.Errorf("%s: wrong error turning off the whitelist: %v", tt.version, err)
+		}
+		if err := wpr.WhitelistAdd(PlayerName("player9")); err != ErrPlayerNotFound {
+			t.Errorf("%s: expected ErrPlayerNotFound, got %v", tt.version, err)
+		}
+	}
+}
+
+func TestWrapperReconcileWhitelist(t *testing.T) {
+	wpr, tc := newCmdTestWrapper(t, "1.16.5", map[string][]string{
+		"whitelist list":           {"[12:01:00] [Server thread/INFO]: There are 3 whitelisted players: player1, player2, player3"},
+		"whitelist add player4":    {"[12:01:00] [Server thread/INFO]: Added player4 to the whitelist"},
+		"whitelist remove player3": {"[12:01:00] [Server thread/INFO]: Removed player3 from the whitelist"},
+	})
+	added, removed, err := wpr.ReconcileWhitelist([]string{"Player1", "player2", "player4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(added, []string{"player4"}) || !reflect.DeepEqual(removed, []string{"player3"}) {
+		t.Errorf("wrong reconcile: added=%v, removed=%v", added, removed)
+	}
+	expected := []string{"whitelist list", "whitelist add player4", "whitelist remove player3"}
+	if cmds := tc.written(); !reflect.DeepEqual(cmds, expected) {
+		t.Errorf("wrong commands written:\nactual  : %v\nexpected: %v", cmds, expected)
+	}
+
+	tc.responses["whitelist list"] = []string{"[12:01:00] [Server thread/INFO]: There are no whitelisted players"}
+	tc.responses["whitelist add player1"] = []string{"[12:01:00] [Server thread/INFO]: That player does not exist"}
+	added, _, err = wpr.ReconcileWhitelist([]string{"player1"})
+	if !errors.Is(err, ErrPlayerNotFound) || len(added) != 0 {
+		t.Errorf("reconcile should stop on the failing command, got %v, %v", added, err)
+	}
+}
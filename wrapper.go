@@ -4,15 +4,19 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"io"
+	"math"
 	"reflect"
+	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/looplab/fsm"
 	"github.com/wlwanpan/minecraft-wrapper/events"
 	"github.com/wlwanpan/minecraft-wrapper/snbt"
+	"github.com/wlwanpan/minecraft-wrapper/text"
 )
 
 const (
@@ -38,6 +42,21 @@ var (
 	// ErrUnknownItem is returned when an item operation is called with an
 	// invalid item type or structure.
 	ErrUnknownItem = errors.New("unknown item")
+	// ErrUnknownAdvancement is returned when an advancement command is
+	// called with an advancement id not loaded by the server.
+	ErrUnknownAdvancement = errors.New("unknown advancement")
+	// ErrUnsupportedVersion is returned when a command does not exist in
+	// the minecraft version being wrapped.
+	ErrUnsupportedVersion = errors.New("unsupported in this version")
+	// ErrUnknownGameRule is returned when querying or setting a game rule
+	// that does not exist in the running version.
+	ErrUnknownGameRule = errors.New("unknown game rule")
+	// ErrTeleportNotVerified is returned when the position read back from a
+	// teleported player does not match the one it was teleported to.
+	ErrTeleportNotVerified = errors.New("teleport not verified")
+	// ErrServerExited is signaled by 'Exited' when the server process ended
+	// without stopping, like on a crash.
+	ErrServerExited = errors.New("server exited unexpectedly")
 )
 
 var wrapperFsmEvents = fsm.Events{
@@ -80,16 +99,31 @@ type Wrapper struct {
 	// Version is the minecraft server version being wrapped.
 	// The Version is detected and set from the log line:
 	// "Starting minecraft server version [X.X.X]""
+	//
+	// Deprecated: Version is set from the log goroutine, read it with
+	// GameVersion instead.
 	Version        string
 	machine        *fsm.FSM
 	console        Console
 	parser         LogParser
 	clock          *clock
 	eq             *eventsQueue
-	playerList     map[string]string
+	players        *playerList
+	sessions       *SessionTracker
+	moderation     *Moderation
 	ctxCancelFunc  context.CancelFunc
 	gameEventsChan chan (events.GameEvent)
 	loadedChan     chan bool
+	exitedChan     chan error
+	lines          *lineFeed
+	events         *eventFeed
+	states         *stateFeed
+
+	// cmdMu serializes the commands waiting on their response, the logged
+	// responses not telling which command they answer.
+	cmdMu sync.Mutex
+	// version is the Version, read from the other goroutines.
+	version atomic.Value
 }
 
 // NewDefaultWrapper returns a new instance of the Wrapper. This is
@@ -98,7 +132,7 @@ type Wrapper struct {
 func NewDefaultWrapper(server string, initial, max int) *Wrapper {
 	cmd := javaExecCmd(server, initial, max)
 	console := newConsole(cmd)
-	return NewWrapper(console, logParserFunc)
+	return NewWrapper(console, NewLogParser(AutoLogProfile))
 }
 
 func NewWrapper(c Console, p LogParser) *Wrapper {
@@ -107,10 +141,14 @@ func NewWrapper(c Console, p LogParser) *Wrapper {
 		parser:         p,
 		clock:          newClock(),
 		eq:             newEventsQueue(),
-		playerList:     map[string]string{},
+		players:        newPlayerList(),
 		ctxCancelFunc:  func() {},
 		gameEventsChan: make(chan events.GameEvent, 10),
 		loadedChan:     make(chan bool, 1),
+		exitedChan:     make(chan error, 1),
+		lines:          newLineFeed(),
+		events:         newEventFeed(),
+		states:         newStateFeed(),
 	}
 	wpr.newFSM()
 	return wpr
@@ -131,6 +169,7 @@ func (w *Wrapper) newFSM() {
 			},
 			"enter_offline": func(ev *fsm.Event) {
 				w.ctxCancelFunc()
+				w.reportEndSessions()
 			},
 		},
 	)
@@ -143,27 +182,57 @@ func (w *Wrapper) processLogEvents(ctx context.Context) {
 			return
 		default:
 			line, err := w.console.ReadLine()
-			if err == io.EOF {
-				w.updateState(events.StoppedEvent)
+			if err != nil {
+				// Any read error means the java process stdout is gone,
+				// io.EOF being the expected one once the server stopped.
+				w.exit(w.updateState(events.StoppedEvent))
 				return
 			}
 
 			ev, t := w.parseLineToEvent(line)
 			switch t {
 			case events.TypeState:
-				w.updateState(ev.(events.StateEvent))
+				if sev, ok := ev.(events.StateEvent); ok {
+					w.updateState(sev)
+					w.states.publish(sev)
+				}
 			case events.TypeCmd:
-				w.handleCmdEvent(ev.(events.GameEvent))
+				if gev, ok := ev.(events.GameEvent); ok {
+					w.handleCmdEvent(gev)
+				}
 			case events.TypeGame:
-				w.handleGameEvent(ev.(events.GameEvent))
+				if gev, ok := ev.(events.GameEvent); ok {
+					w.handleGameEvent(gev)
+				}
 			default:
 			}
+			// Lines are fed once handled, so that the state is up to date
+			// for their readers.
+			w.lines.publish(strings.TrimRight(line, "\r\n"))
 		}
 	}
 }
 
+// exit signals the end of the server process, given the error of the
+// 'stopped' state transition.
+func (w *Wrapper) exit(stopErr error) {
+	var err error
+	if stopErr != nil && !w.machine.Is(WrapperOffline) {
+		// The server went down without logging its stop, hard reset the
+		// wrapper as 'Kill' does.
+		w.machine.SetState(WrapperOffline)
+		w.ctxCancelFunc()
+		w.reportEndSessions()
+		err = ErrServerExited
+	}
+	select {
+	case w.exitedChan <- err:
+	default:
+	}
+}
+
 func (w *Wrapper) parseLineToEvent(line string) (events.Event, events.EventType) {
-	return w.parser(line, w.clock.Tick)
+	return w.parser(line, w.clock.tick())
 }
 
 func (w *Wrapper) updateState(ev events.StateEvent) error {
@@ -176,25 +245,102 @@ func (w *Wrapper) handleCmdEvent(ev events.GameEvent) {
 		return
 	}
 	if ev.Is(events.VersionEvent) {
-		w.Version = ev.Data["version"]
+		w.setVersion(ev.Data["version"])
 		return
 	}
 	w.eq.push(ev)
 }
 
 func (w *Wrapper) handleGameEvent(ev events.GameEvent) {
-	if ev.Is(events.PlayerLeftEvent) {
-		delete(w.playerList, ev.Data["player_name"])
+	if ev.Is(events.PlayerLeftEvent) || ev.Is(events.PlayerRejectedEvent) {
+		// A rejected login already got its UUID, but never joins.
+		w.players.remove(ev.Data["player_name"])
 	}
 	if ev.Is(events.PlayerUUIDEvent) {
-		w.playerList[ev.Data["player_name"]] = ev.Data["player_uuid"]
+		w.players.update(ev.Data["player_name"], func(p *Player) {
+			p.UUID = ev.Data["player_uuid"]
+		})
+	}
+	if ev.Is(events.PlayerLoginEvent) {
+		w.players.update(ev.Data["player_name"], func(p *Player) {
+			p.IP = ev.Data["ip"]
+			p.LoginTime = ev.Time
+			if p.LoginTime.IsZero() {
+				p.LoginTime = time.Now()
+			}
+			p.Position = parsePosition(ev.Data["x"], ev.Data["y"], ev.Data["z"])
+		})
+	}
+	if w.sessions != nil {
+		// A failed save is retried with the next session ending, the
+		// tracker keeping the stats in memory.
+		w.sessions.saveFailed(w.sessions.Track(ev))
+	}
+	if w.moderation != nil && ev.Name == events.PlayerSay {
+		// A failed save is retried with the next change, the moderation
+		// keeping its state in memory.
+		action, _ := w.moderation.offence(ev.Data["player_name"])
+		if action != muteIgnore {
+			ev.Data["muted"] = "true"
+			go w.moderateChat(ev.Data["player_name"], action)
+		}
+	}
+	if w.moderation != nil && ev.Name == events.Banned && ev.Data["player_name"] != "" {
+		// A failed save is retried with the next change, the moderation
+		// keeping its state in memory.
+		if expires, ok, _ := w.moderation.banned(ev.Data["player_name"]); ok {
+			ev.Data["expires"] = expires.Format(time.RFC3339)
+		}
 	}
+	if ev.Is(events.PlayerDiedEvent) && ev.Data["killer"] != "" {
+		// The log can't tell a player killer from a named entity, the online
+		// players can.
+		ev.Data["killer_type"] = "entity"
+		if _, ok := w.players.get(ev.Data["killer"]); ok {
+			ev.Data["killer_type"] = "player"
+			if ev.Data["death_cause"] == "mob_attack" {
+				ev.Data["death_cause"] = "player_attack"
+			}
+		}
+	}
+	w.events.publish(ev)
 	select {
 	case w.gameEventsChan <- ev:
 	default:
 	}
 }
 
+func (w *Wrapper) endSessions() error {
+	if w.sessions == nil {
+		return nil
+	}
+	return w.sessions.EndAll()
+}
+
+// reportEndSessions ends the sessions when no caller gets the save error.
+func (w *Wrapper) reportEndSessions() {
+	if w.sessions != nil {
+		w.sessions.saveFailed(w.sessions.EndAll())
+	}
+}
+
+// listEntryRegex matches a player listed by 'list uuids': "player1 (uuid)".
+var listEntryRegex = regexp.MustCompile(`^(\S+) \(([0-9a-fA-F-]+)\)$`)
+
+// parsePosition returns the x, y, z position logged, or nil if any of the
+// coordinates is malformed.
+func parsePosition(coords ...string) []float64 {
+	pos := make([]float64, len(coords))
+	for i, c := range coords {
+		f, err := strconv.ParseFloat(c, 64)
+		if err != nil {
+			return nil
+		}
+		pos[i] = f
+	}
+	return pos
+}
+
 func (w *Wrapper) writeToConsole(cmd string) error {
 	if !w.machine.Is(WrapperOnline) {
 		return ErrWrapperNotOnline
@@ -202,6 +348,42 @@ func (w *Wrapper) writeToConsole(cmd string) error {
 	return w.console.WriteCmd(cmd)
 }
 
+// GameVersion returns the minecraft server version being wrapped, detected
+// from the log line "Starting minecraft server version [X.X.X]", or an empty
+// string until it is logged.
+func (w *Wrapper) GameVersion() string {
+	v, _ := w.version.Load().(string)
+	return v
+}
+
+func (w *Wrapper) setVersion(v string) {
+	w.Version = v
+	w.version.Store(v)
+}
+
+// requireVersion returns ErrUnsupportedVersion if the wrapped server is known
+// to run a version older than the one that introduced a command.
+func (w *Wrapper) requireVersion(since gameVersion) error {
+	v, ok := parseGameVersion(w.GameVersion())
+	if ok && v.less(since) {
+		return ErrUnsupportedVersion
+	}
+	return nil
+}
+
+// componentJSON serializes the text component in the format of the wrapped
+// server, returning ErrUnsupportedVersion for the hex colors prior to 1.16.
+func (w *Wrapper) componentJSON(c text.Component) ([]byte, error) {
+	if w.requireVersion(versionHexColors) == nil {
+		return c.MarshalJSON()
+	}
+	b, err := c.MarshalLegacyJSON()
+	if errors.Is(err, text.ErrHexColor) {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedVersion, err)
+	}
+	return b, err
+}
+
 func (w *Wrapper) processClock(ctx context.Context) {
 	w.clock.start(ctx)
 	for {
@@ -216,23 +398,34 @@ func (w *Wrapper) processClock(ctx context.Context) {
 }
 
 func (w *Wrapper) processCmdToEvent(cmd string, timeout time.Duration, evs ...string) (events.GameEvent, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return w.processCmdToEventContext(ctx, cmd, evs...)
+}
+
+// processCmdToEventContext is processCmdToEvent, waiting on the first of the
+// given events until the context is done.
+func (w *Wrapper) processCmdToEventContext(ctx context.Context, cmd string, evs ...string) (events.GameEvent, error) {
+	w.cmdMu.Lock()
+	defer w.cmdMu.Unlock()
+
 	gchns := make([]<-chan events.GameEvent, len(evs))
 	for i, ev := range evs {
 		registerGameEvent(ev)
 		gchns[i] = w.eq.get(ev)
 	}
 
-	timeoutCaseIdx := len(evs)
-	cases := make([]reflect.SelectCase, timeoutCaseIdx+1)
+	doneCaseIdx := len(evs)
+	cases := make([]reflect.SelectCase, doneCaseIdx+1)
 	for i, ch := range gchns {
 		cases[i] = reflect.SelectCase{
 			Dir:  reflect.SelectRecv,
 			Chan: reflect.ValueOf(ch),
 		}
 	}
-	cases[timeoutCaseIdx] = reflect.SelectCase{
+	cases[doneCaseIdx] = reflect.SelectCase{
 		Dir:  reflect.SelectRecv,
-		Chan: reflect.ValueOf(time.After(timeout)),
+		Chan: reflect.ValueOf(ctx.Done()),
 	}
 
 	if err := w.writeToConsole(cmd); err != nil {
@@ -240,8 +433,8 @@ func (w *Wrapper) processCmdToEvent(cmd string, timeout time.Duration, evs ...st
 	}
 
 	chosen, value, _ := reflect.Select(cases)
-	if chosen == timeoutCaseIdx {
-		return events.NilGameEvent, ErrWrapperResponseTimeout
+	if chosen == doneCaseIdx {
+		return events.NilGameEvent, contextErr(ctx)
 	}
 
 	ev := value.Interface().(events.GameEvent)
@@ -254,7 +447,19 @@ func (w *Wrapper) processCmdToEvent(cmd string, timeout time.Duration, evs ...st
 	return ev, nil
 }
 
+// contextErr returns the error of a done context, a deadline exceeded
+// being reported as ErrWrapperResponseTimeout.
+func contextErr(ctx context.Context) error {
+	if ctx.Err() == context.DeadlineExceeded {
+		return ErrWrapperResponseTimeout
+	}
+	return ctx.Err()
+}
+
 func (w *Wrapper) processCmdToEventArr(cmd string, timeout time.Duration, ev string) ([]events.GameEvent, error) {
+	w.cmdMu.Lock()
+	defer w.cmdMu.Unlock()
+
 	registerGameEvent(ev)
 	evChan := w.eq.get(ev)
 	if err := w.writeToConsole(cmd); err != nil {
@@ -286,39 +491,124 @@ func (w *Wrapper) processCmdToEventArr(cmd string, timeout time.Duration, ev str
 	}
 }
 
-func (w *Wrapper) Ban(player, reason string) error {
-	cmd := strings.Join([]string{"ban", player, reason}, " ")
-	return w.writeToConsole(cmd)
+// Actionbar displays the text component above the hotbar of the target
+// player(s).
+func (w *Wrapper) Actionbar(target Target, c text.Component) error {
+	return w.title(target, "actionbar", c)
+}
+
+// Advancement grants or revokes advancements to the target player(s) and
+// returns the number of advancements changed. The advancement id, like
+// 'minecraft:story/mine_stone', is ignored in the 'everything' mode.
+func (w *Wrapper) Advancement(action AdvancementAction, target Target, mode AdvancementMode, advancement string) (int, error) {
+	if err := checkTarget(target); err != nil {
+		return 0, err
+	}
+	cmd := fmt.Sprintf("advancement %s %s %s", action, target, mode)
+	if mode != AdvancementEverything {
+		cmd += " " + advancement
+	}
+	ev, err := w.processCmdToEvent(cmd, 1*time.Second, events.Advancement, events.NoPlayerFound, events.UnknownAdvancement)
+	if err != nil {
+		return 0, err
+	}
+	if ev.Is(events.NoPlayerFoundEvent) {
+		return 0, ErrPlayerNotFound
+	}
+	if ev.Is(events.UnknownAdvancementEvent) {
+		return 0, ErrUnknownAdvancement
+	}
+	return strconv.Atoi(ev.Data["count"])
 }
 
-// BanIP adds the input IP address to the servers blacklisted IPs list.
-func (w *Wrapper) BanIP(ip, reason string) error {
-	cmd := strings.Join([]string{"ban-ip", ip, reason}, " ")
+// Ban adds the target player(s) to the server ban list, with an optional
+// reason. The ban is permanent, replacing the temporary ban of the player.
+func (w *Wrapper) Ban(target Target, reason string) error {
+	if err := w.ban("ban", target, reason); err != nil {
+		return err
+	}
+	if p, ok := target.(PlayerName); ok && w.moderation != nil {
+		// The players banned by a selector are dropped on their Banned
+		// event.
+		return w.moderation.removeBan(string(p))
+	}
+	return nil
+}
+
+// BanIP adds an IPAddress, or the IP address of the given online player, to
+// the server banned IPs list, with an optional reason.
+func (w *Wrapper) BanIP(target Target, reason string) error {
+	return w.ban("ban-ip", target, reason)
+}
+
+func (w *Wrapper) ban(action string, target Target, reason string) error {
+	if err := checkTarget(target); err != nil {
+		return err
+	}
+	cmd := fmt.Sprintf("%s %s", action, target)
+	if reason != "" {
+		cmd += " " + reason
+	}
 	return w.writeToConsole(cmd)
 }
 
+// BanList returns the names of the players or the IPs banned from the
+// server, see BanListEntries for the details of the bans.
 func (w *Wrapper) BanList(t BanListType) ([]string, error) {
+	entries, err := w.BanListEntries(t)
+	if err != nil {
+		return nil, err
+	}
+	banList := make([]string, len(entries))
+	for i, e := range entries {
+		banList[i] = e.Target
+	}
+	return banList, nil
+}
+
+// BanListEntries returns the bans of the players or the IPs banned from the
+// server. Prior to 1.13, only the names of the banned players or IPs are
+// logged, read 'banned-players.json' and 'banned-ips.json' with
+// ReadBannedPlayersFile and ReadBannedIPsFile for the other details.
+func (w *Wrapper) BanListEntries(t BanListType) ([]BanEntry, error) {
 	cmd := fmt.Sprintf("banlist %s", t)
 	evs, err := w.processCmdToEventArr(cmd, 3*time.Second, events.BanList)
 	if err != nil {
 		return nil, err
 	}
 
-	banList := []string{}
+	entries := []BanEntry{}
 	for _, ev := range evs {
-		banList = append(banList, ev.Data["entry_name"])
+		if names, ok := ev.Data["entry_names"]; ok {
+			for _, name := range strings.Split(names, ", ") {
+				entries = append(entries, BanEntry{Target: name, Type: t})
+			}
+			continue
+		}
+		entries = append(entries, BanEntry{
+			Target: ev.Data["entry_name"],
+			Type:   t,
+			Source: ev.Data["source"],
+			Reason: ev.Data["reason"],
+		})
 	}
-	return banList, nil
+	return entries, nil
 }
 
 // DataGet returns the Go struct representation of an 'entity' or 'block' or 'storage'.
 // The data is originally stored in a NBT format.
 func (w *Wrapper) DataGet(t, id string) (*DataGetOutput, error) {
+	if err := w.requireVersion(versionFlattening); err != nil {
+		return nil, err
+	}
 	cmd := fmt.Sprintf("data get %s %s", t, id)
-	ev, err := w.processCmdToEvent(cmd, 3*time.Second, events.DataGet)
+	ev, err := w.processCmdToEvent(cmd, 3*time.Second, events.DataGet, events.NoEntityFound)
 	if err != nil {
 		return nil, err
 	}
+	if ev.Is(events.NoEntityFoundEvent) {
+		return nil, ErrPlayerNotFound
+	}
 	rawData := []byte(ev.Data["data_raw"])
 	resp := &DataGetOutput{}
 	if err = snbt.Decode(rawData, resp); err != nil {
@@ -333,9 +623,9 @@ func (w *Wrapper) DefaultGameMode(mode GameMode) error {
 	return w.writeToConsole(cmd)
 }
 
-// DeOp removes a given player from the operator list.
-func (w *Wrapper) DeOp(player string) error {
-	return w.writeToConsole("deop " + player)
+// DeOp removes the target player(s) from the operator list.
+func (w *Wrapper) DeOp(target Target) error {
+	return w.operator("deop", target)
 }
 
 // Difficulty changes the game difficulty level of the world.
@@ -345,11 +635,23 @@ func (w *Wrapper) Difficulty(d GameDifficulty) error {
 	return err
 }
 
+// Exited returns a channel receiving nil once the server process ended
+// after stopping, or ErrServerExited if it went down on its own.
+func (w *Wrapper) Exited() <-chan error {
+	return w.exitedChan
+}
+
 // ExperienceAdd adds a given amount of experience either:
 // - levels or
 // - points
 // to the provided player.
-func (w *Wrapper) ExperienceAdd(target string, xp int32, xpType ExperienceType) error {
+func (w *Wrapper) ExperienceAdd(target Target, xp int32, xpType ExperienceType) error {
+	if err := checkTarget(target); err != nil {
+		return err
+	}
+	if err := w.requireVersion(versionFlattening); err != nil {
+		return err
+	}
 	cmd := fmt.Sprintf("experience add %s %d %s", target, xp, xpType)
 	ev, err := w.processCmdToEvent(cmd, 1*time.Second, events.ExperienceAdd, events.NoPlayerFound)
 	if err != nil {
@@ -363,7 +665,13 @@ func (w *Wrapper) ExperienceAdd(target string, xp int32, xpType ExperienceType)
 
 // ExperienceQuery returns the amount of experience of the provided player.
 // The 'target' arg should be a single target, multi-targets query might fail.
-func (w *Wrapper) ExperienceQuery(target string, xpType ExperienceType) (int, error) {
+func (w *Wrapper) ExperienceQuery(target Target, xpType ExperienceType) (int, error) {
+	if err := checkTarget(target); err != nil {
+		return 0, err
+	}
+	if err := w.requireVersion(versionFlattening); err != nil {
+		return 0, err
+	}
 	cmd := fmt.Sprintf("experience query %s %s", target, xpType)
 	ev, err := w.processCmdToEvent(cmd, 1*time.Second, events.ExperienceQuery, events.NoPlayerFound)
 	if err != nil {
@@ -377,6 +685,9 @@ func (w *Wrapper) ExperienceQuery(target string, xpType ExperienceType) (int, er
 
 // ForceLoadAll removes the constant force loads on all chunks in the dimension.
 func (w *Wrapper) ForceLoadRemoveAll() error {
+	if err := w.requireVersion(versionForceLoad); err != nil {
+		return err
+	}
 	return w.writeToConsole("forceload remove all")
 }
 
@@ -388,8 +699,40 @@ func (w *Wrapper) GameEvents() <-chan events.GameEvent {
 	return w.gameEventsChan
 }
 
+// GameRuleBool returns the value of a boolean game rule.
+func (w *Wrapper) GameRuleBool(rule BoolGameRule) (bool, error) {
+	v, err := w.gameRule("gamerule " + string(rule))
+	if err != nil {
+		return false, err
+	}
+	return strconv.ParseBool(v)
+}
+
+// GameRuleInt returns the value of an integer game rule.
+func (w *Wrapper) GameRuleInt(rule IntGameRule) (int, error) {
+	v, err := w.gameRule("gamerule " + string(rule))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(v)
+}
+
+func (w *Wrapper) gameRule(cmd string) (string, error) {
+	ev, err := w.processCmdToEvent(cmd, 1*time.Second, events.GameRule, events.UnknownGameRule)
+	if err != nil {
+		return "", err
+	}
+	if ev.Is(events.UnknownGameRuleEvent) {
+		return "", ErrUnknownGameRule
+	}
+	return ev.Data["value"], nil
+}
+
 // Give give a target player entity some given items.
-func (w *Wrapper) Give(target, item string, count int) error {
+func (w *Wrapper) Give(target Target, item string, count int) error {
+	if err := checkTarget(target); err != nil {
+		return err
+	}
 	cmd := fmt.Sprintf("give %s %s %d", target, item, count)
 	ev, err := w.processCmdToEvent(cmd, 1*time.Second, events.Give, events.NoPlayerFound, events.UnknownItem)
 	if err != nil {
@@ -416,13 +759,16 @@ func (w *Wrapper) Kill() error {
 	// Manually trigger the context cancellation since 'SetState'
 	// does not trigger any callbacks on the fsm.
 	w.ctxCancelFunc()
-	return nil
+	return w.endSessions()
 }
 
 // Kick kicks the provided player from the server. If a reason is provided,
 // the message will display on the players screen when disconnected.
-func (w *Wrapper) Kick(target, reason string) error {
-	cmd := strings.Join([]string{"kick", target, reason}, " ")
+func (w *Wrapper) Kick(target Target, reason string) error {
+	if err := checkTarget(target); err != nil {
+		return err
+	}
+	cmd := strings.Join([]string{"kick", target.String(), reason}, " ")
 	ev, err := w.processCmdToEvent(cmd, 1*time.Second, events.Kicked, events.NoPlayerFound)
 	if err != nil {
 		return err
@@ -435,20 +781,84 @@ func (w *Wrapper) Kick(target, reason string) error {
 
 // List returns a list of connected players on the server.
 func (w *Wrapper) List() []Player {
+	return w.players.list()
+}
+
+// ListPlayers returns the players online, as reported by the 'list uuids'
+// command, and resyncs the player list returned by 'List' with them. The
+// context bounds the wait for the response, a default timeout is used if
+// it has no deadline.
+func (w *Wrapper) ListPlayers(ctx context.Context) ([]Player, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 3*time.Second)
+		defer cancel()
+	}
+	ev, err := w.processCmdToEventContext(ctx, "list uuids", events.List)
+	if err != nil {
+		return nil, err
+	}
 	players := []Player{}
-	for name, uuid := range w.playerList {
-		players = append(players, Player{
-			Name: name,
-			UUID: uuid,
-		})
+	for _, entry := range strings.Split(ev.Data["players"], ", ") {
+		m := listEntryRegex.FindStringSubmatch(entry)
+		if m == nil {
+			continue
+		}
+		players = append(players, Player{Name: m[1], UUID: m[2]})
 	}
-	return players
+	return w.players.resync(players), nil
 }
 
 func (w *Wrapper) Loaded() <-chan bool {
 	return w.loadedChan
 }
 
+// Op adds the target player(s) to the operator list, with the permission
+// level set by 'op-permission-level' in the server properties.
+func (w *Wrapper) Op(target Target) error {
+	return w.operator("op", target)
+}
+
+func (w *Wrapper) operator(action string, target Target) error {
+	if err := checkTarget(target); err != nil {
+		return err
+	}
+	cmd := fmt.Sprintf("%s %s", action, target)
+	ev, err := w.processCmdToEvent(cmd, 3*time.Second, events.Operator, events.NoPlayerFound)
+	if err != nil {
+		return err
+	}
+	if ev.Is(events.NoPlayerFoundEvent) {
+		return ErrPlayerNotFound
+	}
+	return nil
+}
+
+// Pardon removes a player from the ban list.
+func (w *Wrapper) Pardon(player PlayerName) error {
+	if err := w.pardon("pardon", player); err != nil {
+		return err
+	}
+	if w.moderation != nil {
+		return w.moderation.removeBan(string(player))
+	}
+	return nil
+}
+
+// PardonIP removes an IP address from the ban list.
+func (w *Wrapper) PardonIP(ip IPAddress) error {
+	return w.pardon("pardon-ip", ip)
+}
+
+func (w *Wrapper) pardon(action string, target Target) error {
+	if err := checkTarget(target); err != nil {
+		return err
+	}
+	cmd := fmt.Sprintf("%s %s", action, target)
+	_, err := w.processCmdToEvent(cmd, 1*time.Second, events.Pardon)
+	return err
+}
+
 // Reload reloads the server datapack.
 func (w *Wrapper) Reload() error {
 	return w.writeToConsole("reload")
@@ -486,9 +896,48 @@ func (w *Wrapper) Seed() (int, error) {
 		return 0, err
 	}
 	rawData := []byte(ev.Data["data_raw"])
+	if !strings.HasPrefix(ev.Data["data_raw"], "[") {
+		// Prior to 1.13, the seed is logged as a plain number.
+		return strconv.Atoi(ev.Data["data_raw"])
+	}
 	resp := []int{}
-	err = snbt.Decode(rawData, &resp)
-	return resp[0], err
+	if err = snbt.Decode(rawData, &resp); err != nil {
+		return 0, err
+	}
+	if len(resp) == 0 {
+		return 0, fmt.Errorf("failed to decode seed: %s", ev.Data["data_raw"])
+	}
+	return resp[0], nil
+}
+
+// SetGameMode sets the game mode of the target player(s). The game logs no
+// confirmation for the players already in that game mode, in which case
+// ErrWrapperResponseTimeout is returned.
+func (w *Wrapper) SetGameMode(target Target, mode GameMode) error {
+	if err := checkTarget(target); err != nil {
+		return err
+	}
+	cmd := fmt.Sprintf("gamemode %s %s", mode, target)
+	ev, err := w.processCmdToEvent(cmd, 1*time.Second, events.GameMode, events.NoPlayerFound)
+	if err != nil {
+		return err
+	}
+	if ev.Is(events.NoPlayerFoundEvent) {
+		return ErrPlayerNotFound
+	}
+	return nil
+}
+
+// SetGameRuleBool sets the value of a boolean game rule.
+func (w *Wrapper) SetGameRuleBool(rule BoolGameRule, value bool) error {
+	_, err := w.gameRule(fmt.Sprintf("gamerule %s %t", rule, value))
+	return err
+}
+
+// SetGameRuleInt sets the value of an integer game rule.
+func (w *Wrapper) SetGameRuleInt(rule IntGameRule, value int) error {
+	_, err := w.gameRule(fmt.Sprintf("gamerule %s %d", rule, value))
+	return err
 }
 
 // SetIdleTimeout sets the default timeout in minutes after which idle players
@@ -497,16 +946,49 @@ func (w *Wrapper) SetIdleTimeout(minutes uint32) error {
 	return w.writeToConsole(fmt.Sprintf("setidletimeout %d", minutes))
 }
 
+// SetWorldSpawn sets the world spawn to the given block position.
+func (w *Wrapper) SetWorldSpawn(pos Position) error {
+	if err := pos.validateBlock(); err != nil {
+		return err
+	}
+	return w.writeToConsole("setworldspawn " + pos.String())
+}
+
+// SpawnPoint sets the spawn point of the target player(s) to the given
+// block position.
+func (w *Wrapper) SpawnPoint(target Target, pos Position) error {
+	if err := checkTarget(target); err != nil {
+		return err
+	}
+	if err := pos.validateBlock(); err != nil {
+		return err
+	}
+	return w.writeToConsole(fmt.Sprintf("spawnpoint %s %s", target, pos))
+}
+
 // Start will initialize the minecraft java process and start
 // orchestrating the wrapper machine.
 func (w *Wrapper) Start() error {
 	if !w.machine.Is(WrapperOffline) {
 		return fmt.Errorf("cannot Start when wrapper is in %s state", w.State())
 	}
+	// Drop the signals of the previous run left unread, which would be
+	// taken for the ones of this run.
+	select {
+	case <-w.loadedChan:
+	default:
+	}
+	select {
+	case <-w.exitedChan:
+	default:
+	}
 	ctx, cancel := context.WithCancel(context.Background())
 	w.ctxCancelFunc = cancel
 	go w.processLogEvents(ctx)
 	go w.processClock(ctx)
+	if w.moderation != nil {
+		go w.processModeration(ctx)
+	}
 	return w.console.Start()
 }
 
@@ -524,8 +1006,127 @@ func (w *Wrapper) Stop() error {
 	return w.console.WriteCmd("stop")
 }
 
+// TrackSessions records the play sessions and playtime of the players
+// with the given tracker. It should be set before starting the wrapper. The
+// stats failing to be saved are reported with 'SessionTracker.OnSaveError'.
+func (w *Wrapper) TrackSessions(st *SessionTracker) {
+	w.sessions = st
+}
+
+// Subtitle sets the text component displayed under the title of the target
+// player(s), shown with the next 'Title'.
+func (w *Wrapper) Subtitle(target Target, c text.Component) error {
+	return w.title(target, "subtitle", c)
+}
+
+// Teleport teleports the target entities to a position, facing the given
+// rotation if not nil.
+func (w *Wrapper) Teleport(target Target, pos Position, rot *Rotation) error {
+	_, err := w.teleportToPosition(target, "", pos, rot)
+	return err
+}
+
+// TeleportToDimension teleports the target entities to a position in the
+// given dimension, facing the given rotation if not nil.
+func (w *Wrapper) TeleportToDimension(target Target, dim Dimension, pos Position, rot *Rotation) error {
+	_, err := w.teleportToPosition(target, dim, pos, rot)
+	return err
+}
+
+// TeleportToPlayer teleports the target entities to the destination player,
+// or to the entity matched by a single entity selector.
+func (w *Wrapper) TeleportToPlayer(target, destination Target) error {
+	if err := checkTarget(target); err != nil {
+		return err
+	}
+	if err := checkTarget(destination); err != nil {
+		return err
+	}
+	_, err := w.teleport(fmt.Sprintf("tp %s %s", target, destination))
+	return err
+}
+
+// TeleportVerified teleports a player to a position, in the given dimension
+// unless empty, and reads its position back with 'DataGet' to verify that
+// it was moved. It returns ErrTeleportNotVerified if the player is not
+// found where the game reported teleporting it.
+func (w *Wrapper) TeleportVerified(player PlayerName, dim Dimension, pos Position, rot *Rotation) error {
+	// 'data get' is needed to read the position back.
+	if err := w.requireVersion(versionFlattening); err != nil {
+		return err
+	}
+	ev, err := w.teleportToPosition(player, dim, pos, rot)
+	if err != nil {
+		return err
+	}
+	expected := parsePosition(ev.Data["x"], ev.Data["y"], ev.Data["z"])
+	if expected == nil {
+		return fmt.Errorf("%w: no position reported for %s", ErrTeleportNotVerified, player)
+	}
+	out, err := w.DataGet("entity", player.String())
+	if err != nil {
+		return err
+	}
+	if len(out.Pos) != 3 {
+		return fmt.Errorf("%w: no position read back for %s", ErrTeleportNotVerified, player)
+	}
+	for i := range expected {
+		// The reported coordinates are rounded to 6 decimals.
+		if math.Abs(out.Pos[i]-expected[i]) > 1e-3 {
+			return fmt.Errorf("%w: %s is at %v instead of %v", ErrTeleportNotVerified, player, out.Pos, expected)
+		}
+	}
+	if dim != "" && out.Dimension != string(dim) {
+		return fmt.Errorf("%w: %s is in %s instead of %s", ErrTeleportNotVerified, player, out.Dimension, dim)
+	}
+	return nil
+}
+
+func (w *Wrapper) teleportToPosition(target Target, dim Dimension, pos Position, rot *Rotation) (events.GameEvent, error) {
+	if err := checkTarget(target); err != nil {
+		return events.NilGameEvent, err
+	}
+	if err := pos.Validate(); err != nil {
+		return events.NilGameEvent, err
+	}
+	cmd := fmt.Sprintf("tp %s %s", target, pos)
+	if rot != nil {
+		if err := rot.Validate(); err != nil {
+			return events.NilGameEvent, err
+		}
+		cmd += " " + rot.String()
+	}
+	if dim != "" {
+		if err := w.requireVersion(versionFlattening); err != nil {
+			return events.NilGameEvent, err
+		}
+		if strings.HasPrefix(string(dim), "#") || !resourceRegex.MatchString(string(dim)) {
+			return events.NilGameEvent, fmt.Errorf("invalid dimension: %s", dim)
+		}
+		cmd = fmt.Sprintf("execute in %s run %s", dim, cmd)
+	}
+	return w.teleport(cmd)
+}
+
+func (w *Wrapper) teleport(cmd string) (events.GameEvent, error) {
+	ev, err := w.processCmdToEvent(cmd, 1*time.Second, events.Teleport, events.NoPlayerFound, events.NoEntityFound, events.InvalidPosition)
+	if err != nil {
+		return events.NilGameEvent, err
+	}
+	if ev.Is(events.NoPlayerFoundEvent) || ev.Is(events.NoEntityFoundEvent) {
+		return events.NilGameEvent, ErrPlayerNotFound
+	}
+	if ev.Is(events.InvalidPositionEvent) {
+		return events.NilGameEvent, ErrInvalidPosition
+	}
+	return ev, nil
+}
+
 // Tell sends a message to a specific target in the server.
-func (w *Wrapper) Tell(target, msg string) error {
+func (w *Wrapper) Tell(target Target, msg string) error {
+	if err := checkTarget(target); err != nil {
+		return err
+	}
 	cmd := fmt.Sprintf("tell %s %s", target, msg)
 	ev, err := w.processCmdToEvent(cmd, 3*time.Second, events.WhisperTo, events.NoPlayerFound)
 	if err != nil {
@@ -537,8 +1138,112 @@ func (w *Wrapper) Tell(target, msg string) error {
 	return nil
 }
 
+// Tellraw sends a JSON text component message to the target player(s).
+func (w *Wrapper) Tellraw(target Target, c text.Component) error {
+	if err := checkTarget(target); err != nil {
+		return err
+	}
+	b, err := w.componentJSON(c)
+	if err != nil {
+		return err
+	}
+	return w.writeToConsole(fmt.Sprintf("tellraw %s %s", target, b))
+}
+
 // Tick returns the current minecraft game tick, which runs at a fixed rate
 // of 20 ticks per second, src: https://minecraft.gamepedia.com/Tick.
 func (w *Wrapper) Tick() int {
-	return w.clock.Tick
+	return w.clock.tick()
+}
+
+// Title displays the text component as a title on the screen of the target
+// player(s).
+func (w *Wrapper) Title(target Target, c text.Component) error {
+	return w.title(target, "title", c)
+}
+
+// TitleTimes sets the fade in, stay and fade out durations of the titles
+// displayed to the target player(s), rounded to the game ticks.
+func (w *Wrapper) TitleTimes(target Target, fadeIn, stay, fadeOut time.Duration) error {
+	if err := checkTarget(target); err != nil {
+		return err
+	}
+	ticks := func(d time.Duration) int {
+		return int(d / (time.Second / time.Duration(GameTickPerSecond)))
+	}
+	cmd := fmt.Sprintf("title %s times %d %d %d", target, ticks(fadeIn), ticks(stay), ticks(fadeOut))
+	return w.writeToConsole(cmd)
+}
+
+func (w *Wrapper) title(target Target, kind string, c text.Component) error {
+	if err := checkTarget(target); err != nil {
+		return err
+	}
+	b, err := w.componentJSON(c)
+	if err != nil {
+		return err
+	}
+	return w.writeToConsole(fmt.Sprintf("title %s %s %s", target, kind, b))
+}
+
+// WhitelistAdd adds the target player(s) to the whitelist.
+func (w *Wrapper) WhitelistAdd(target Target) error {
+	return w.whitelistPlayer("add", target)
+}
+
+// WhitelistList returns the whitelisted players. Prior to 1.13, the players
+// are logged on a line that can not be told apart from other lists, read
+// 'whitelist.json' with ReadWhitelistFile instead.
+func (w *Wrapper) WhitelistList() ([]string, error) {
+	if err := w.requireVersion(versionFlattening); err != nil {
+		return nil, err
+	}
+	ev, err := w.processCmdToEvent("whitelist list", 1*time.Second, events.Whitelist)
+	if err != nil {
+		return nil, err
+	}
+	players := []string{}
+	if ev.Data["players"] != "" {
+		players = strings.Split(ev.Data["players"], ", ")
+	}
+	return players, nil
+}
+
+// WhitelistOff turns off the whitelist, letting any player join.
+func (w *Wrapper) WhitelistOff() error {
+	_, err := w.processCmdToEvent("whitelist off", 1*time.Second, events.Whitelist)
+	return err
+}
+
+// WhitelistOn turns on the whitelist, only letting the whitelisted players
+// join.
+func (w *Wrapper) WhitelistOn() error {
+	_, err := w.processCmdToEvent("whitelist on", 1*time.Second, events.Whitelist)
+	return err
+}
+
+// WhitelistReload reloads the whitelist from 'whitelist.json'.
+func (w *Wrapper) WhitelistReload() error {
+	_, err := w.processCmdToEvent("whitelist reload", 1*time.Second, events.Whitelist)
+	return err
+}
+
+// WhitelistRemove removes the target player(s) from the whitelist.
+func (w *Wrapper) WhitelistRemove(target Target) error {
+	return w.whitelistPlayer("remove", target)
+}
+
+func (w *Wrapper) whitelistPlayer(action string, target Target) error {
+	if err := checkTarget(target); err != nil {
+		return err
+	}
+	cmd := fmt.Sprintf("whitelist %s %s", action, target)
+	ev, err := w.processCmdToEvent(cmd, 3*time.Second, events.Whitelist, events.NoPlayerFound)
+	if err != nil {
+		return err
+	}
+	if ev.Is(events.NoPlayerFoundEvent) {
+		return ErrPlayerNotFound
+	}
+	return nil
 }
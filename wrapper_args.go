@@ -31,3 +31,96 @@ const (
 	Levels ExperienceType = "levels"
 	Points ExperienceType = "points"
 )
+
+type AdvancementAction string
+
+const (
+	AdvancementGrant  AdvancementAction = "grant"
+	AdvancementRevoke AdvancementAction = "revoke"
+)
+
+// AdvancementMode selects the advancements targeted by an 'advancement'
+// command, relative to the given advancement in its tree.
+type AdvancementMode string
+
+const (
+	// AdvancementOnly targets the given advancement only.
+	AdvancementOnly AdvancementMode = "only"
+	// AdvancementFrom targets the given advancement and all its children.
+	AdvancementFrom AdvancementMode = "from"
+	// AdvancementThrough targets the given advancement, its parents and its children.
+	AdvancementThrough AdvancementMode = "through"
+	// AdvancementUntil targets the given advancement and all its parents.
+	AdvancementUntil AdvancementMode = "until"
+	// AdvancementEverything targets all the loaded advancements.
+	AdvancementEverything AdvancementMode = "everything"
+)
+
+// BoolGameRule is a game rule holding a boolean value, see:
+// https://minecraft.gamepedia.com/Game_rule.
+type BoolGameRule string
+
+const (
+	AnnounceAdvancements       BoolGameRule = "announceAdvancements"
+	BlockExplosionDropDecay    BoolGameRule = "blockExplosionDropDecay"
+	CommandBlockOutput         BoolGameRule = "commandBlockOutput"
+	DisableElytraMovementCheck BoolGameRule = "disableElytraMovementCheck"
+	DisableRaids               BoolGameRule = "disableRaids"
+	DoDaylightCycle            BoolGameRule = "doDaylightCycle"
+	DoEntityDrops              BoolGameRule = "doEntityDrops"
+	DoFireTick                 BoolGameRule = "doFireTick"
+	DoImmediateRespawn         BoolGameRule = "doImmediateRespawn"
+	DoInsomnia                 BoolGameRule = "doInsomnia"
+	DoLimitedCrafting          BoolGameRule = "doLimitedCrafting"
+	DoMobLoot                  BoolGameRule = "doMobLoot"
+	DoMobSpawning              BoolGameRule = "doMobSpawning"
+	DoPatrolSpawning           BoolGameRule = "doPatrolSpawning"
+	DoTileDrops                BoolGameRule = "doTileDrops"
+	DoTraderSpawning           BoolGameRule = "doTraderSpawning"
+	DoVinesSpread              BoolGameRule = "doVinesSpread"
+	DoWardenSpawning           BoolGameRule = "doWardenSpawning"
+	DoWeatherCycle             BoolGameRule = "doWeatherCycle"
+	DrowningDamage             BoolGameRule = "drowningDamage"
+	FallDamage                 BoolGameRule = "fallDamage"
+	FireDamage                 BoolGameRule = "fireDamage"
+	ForgiveDeadPlayers         BoolGameRule = "forgiveDeadPlayers"
+	FreezeDamage               BoolGameRule = "freezeDamage"
+	GlobalSoundEvents          BoolGameRule = "globalSoundEvents"
+	KeepInventory              BoolGameRule = "keepInventory"
+	LavaSourceConversion       BoolGameRule = "lavaSourceConversion"
+	LogAdminCommands           BoolGameRule = "logAdminCommands"
+	MobExplosionDropDecay      BoolGameRule = "mobExplosionDropDecay"
+	MobGriefing                BoolGameRule = "mobGriefing"
+	NaturalRegeneration        BoolGameRule = "naturalRegeneration"
+	ReducedDebugInfo           BoolGameRule = "reducedDebugInfo"
+	SendCommandFeedback        BoolGameRule = "sendCommandFeedback"
+	ShowDeathMessages          BoolGameRule = "showDeathMessages"
+	SpectatorsGenerateChunks   BoolGameRule = "spectatorsGenerateChunks"
+	TNTExplosionDropDecay      BoolGameRule = "tntExplosionDropDecay"
+	UniversalAnger             BoolGameRule = "universalAnger"
+	WaterSourceConversion      BoolGameRule = "waterSourceConversion"
+)
+
+// IntGameRule is a game rule holding an integer value, see:
+// https://minecraft.gamepedia.com/Game_rule.
+type IntGameRule string
+
+const (
+	CommandModificationBlockLimit IntGameRule = "commandModificationBlockLimit"
+	MaxCommandChainLength         IntGameRule = "maxCommandChainLength"
+	MaxEntityCramming             IntGameRule = "maxEntityCramming"
+	PlayersSleepingPercentage     IntGameRule = "playersSleepingPercentage"
+	RandomTickSpeed               IntGameRule = "randomTickSpeed"
+	SnowAccumulationHeight        IntGameRule = "snowAccumulationHeight"
+	SpawnRadius                   IntGameRule = "spawnRadius"
+)
+
+// Dimension is the resource location of a dimension, the vanilla ones or
+// those added by datapacks and mods.
+type Dimension string
+
+const (
+	Overworld Dimension = "minecraft:overworld"
+	TheNether Dimension = "minecraft:the_nether"
+	TheEnd    Dimension = "minecraft:the_end"
+)
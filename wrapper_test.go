@@ -1,16 +1,24 @@
 package wrapper
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
 	"testing"
 	"time"
+
+	"github.com/wlwanpan/minecraft-wrapper/events"
+	"github.com/wlwanpan/minecraft-wrapper/text"
 )
 
 func TestWrapperStart(t *testing.T) {
 	c, err := newTestConsole("testdata/server_start_log")
 	if err != nil {
-		t.Errorf("failed to load test file: %w", err)
+		t.Errorf("failed to load test file: %s", err)
 		return
 	}
+	c.idle = true
 
 	wpr := NewWrapper(c, logParserFunc)
 	if wpr.State() != WrapperOffline {
@@ -32,15 +40,15 @@ func TestWrapperStart(t *testing.T) {
 	}
 
 	expectedDetectedVersion := "1.16.4"
-	if wpr.Version != expectedDetectedVersion {
-		t.Errorf("wrapper version be %s, got %s", expectedDetectedVersion, wpr.Version)
+	if wpr.GameVersion() != expectedDetectedVersion {
+		t.Errorf("wrapper version be %s, got %s", expectedDetectedVersion, wpr.GameVersion())
 	}
 }
 
 func TestWrapperOffline(t *testing.T) {
 	c, err := newTestConsole("testdata/server_start_log")
 	if err != nil {
-		t.Errorf("failed to load test file: %w", err)
+		t.Errorf("failed to load test file: %s", err)
 		return
 	}
 
@@ -50,7 +58,7 @@ func TestWrapperOffline(t *testing.T) {
 	}
 
 	// test simple entry command (no output expected).
-	if err := wpr.Ban("player-1", "reason-1"); err == nil {
+	if err := wpr.Ban(PlayerName("player-1"), "reason-1"); err == nil {
 		t.Error("wrapper.Ban should error when 'offline'")
 	}
 
@@ -66,3 +74,482 @@ func TestWrapperOffline(t *testing.T) {
 		t.Error("wrapper.BanList should error when 'offline'")
 	}
 }
+
+func TestWrapperExited(t *testing.T) {
+	for _, crash := range []bool{false, true} {
+		tc := newManagerTestConsole(newStartGauge(0))
+		wpr := NewWrapper(tc, NewLogParser(VanillaLogProfile))
+		lines, cancel := wpr.lines.subscribe()
+		if err := wpr.Start(); err != nil {
+			t.Fatal(err)
+		}
+		waitOnline(t, lines)
+		cancel()
+		expected := error(nil)
+		if crash {
+			tc.crash()
+			expected = ErrServerExited
+		} else {
+			wpr.Stop()
+		}
+		select {
+		case err := <-wpr.Exited():
+			if err != expected {
+				t.Errorf("crash=%t: wrong exit error: actual=%v, expected=%v", crash, err, expected)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("crash=%t: timeout waiting for the exit", crash)
+		}
+		if wpr.State() != WrapperOffline {
+			t.Errorf("crash=%t: wrapper should be 'offline', got %s", crash, wpr.State())
+		}
+	}
+}
+
+func TestWrapperLogProfiles(t *testing.T) {
+	for _, tl := range profileTestLogs {
+		c, err := newTestConsole(tl.filename)
+		if err != nil {
+			t.Errorf("failed to load test file: %s", err)
+			return
+		}
+
+		wpr := NewWrapper(c, NewLogParser(tl.profile))
+		if err := wpr.Start(); err != nil {
+			t.Error(err)
+			return
+		}
+		select {
+		case <-wpr.Loaded():
+		case <-time.After(1 * time.Second):
+			t.Errorf("%s: wrapper timeout, failed to start", tl.profile.Name)
+			continue
+		}
+
+		select {
+		case <-wpr.Exited():
+		case <-time.After(1 * time.Second):
+			t.Errorf("%s: wrapper timeout, failed to exit", tl.profile.Name)
+			continue
+		}
+		if wpr.State() != WrapperOffline {
+			t.Errorf("%s: wrapper should be 'offline', got %s", tl.profile.Name, wpr.State())
+		}
+		if wpr.GameVersion() != tl.version {
+			t.Errorf("%s: wrapper version be %s, got %s", tl.profile.Name, tl.version, wpr.GameVersion())
+		}
+	}
+}
+
+func TestWrapperPlayerLogin(t *testing.T) {
+	wpr := NewWrapper(&testConsole{}, logParserFunc)
+	lines := []string{
+		"[14:13:15] [User Authenticator #1/INFO]: UUID of player player1 is player-1-uuid",
+		"[14:13:15] [Server thread/INFO]: player1[/192.168.1.77:48490] logged in with entity id 246 at (181.5, 79.0, -122.5)",
+	}
+	for _, line := range lines {
+		ev, _ := logParserFunc(line, 0)
+		wpr.handleGameEvent(ev.(events.GameEvent))
+	}
+
+	players := wpr.List()
+	if len(players) != 1 {
+		t.Fatalf("wrapper should list 1 player, got %d", len(players))
+	}
+	p := players[0]
+	if p.Name != "player1" || p.UUID != "player-1-uuid" || p.IP != "192.168.1.77" {
+		t.Errorf("wrong player details: %+v", p)
+	}
+	if p.LoginTime.IsZero() {
+		t.Error("player login time should be set")
+	}
+	if !reflect.DeepEqual(p.Position, []float64{181.5, 79.0, -122.5}) {
+		t.Errorf("wrong player position: %v", p.Position)
+	}
+}
+
+func TestWrapperPlayerRejected(t *testing.T) {
+	st, _ := newTestSessionTracker(t)
+	wpr := NewWrapper(&testConsole{}, logParserFunc)
+	wpr.TrackSessions(st)
+	lines := []string{
+		"[18:13:05] [User Authenticator #2/INFO]: UUID of player player3 is player-3-uuid",
+		"[18:13:05] [Server thread/INFO]: com.mojang.authlib.GameProfile@7c2b1a3e[id=player-3-uuid,name=player3,properties={},legacy=false] (/192.168.1.80:51334) lost connection: You are not white-listed on this server!",
+	}
+	for _, line := range lines {
+		ev, _ := logParserFunc(line, 0)
+		wpr.handleGameEvent(ev.(events.GameEvent))
+	}
+
+	if players := wpr.List(); len(players) != 0 {
+		t.Errorf("rejected player should not be listed, got %+v", players)
+	}
+	if uuid, ok := st.uuids["player3"]; ok {
+		t.Errorf("rejected player uuid should be forgotten, got %s", uuid)
+	}
+}
+
+// failingSessionStore loads no stats and fails to save them.
+type failingSessionStore struct{}
+
+func (failingSessionStore) Load() ([]PlayerStats, error) { return nil, nil }
+
+func (failingSessionStore) Save([]PlayerStats) error { return errors.New("disk full") }
+
+func TestWrapperSessionSaveError(t *testing.T) {
+	st, err := NewSessionTracker(failingSessionStore{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saveErrs []error
+	st.OnSaveError(func(err error) { saveErrs = append(saveErrs, err) })
+	wpr := NewWrapper(&testConsole{}, logParserFunc)
+	wpr.TrackSessions(st)
+	lines := []string{
+		"[14:13:15] [Server thread/INFO]: player1 joined the game",
+		"[14:20:15] [Server thread/INFO]: player1 left the game",
+	}
+	for _, line := range lines {
+		ev, _ := logParserFunc(line, 0)
+		wpr.handleGameEvent(ev.(events.GameEvent))
+	}
+
+	if len(saveErrs) != 1 || saveErrs[0].Error() != "disk full" {
+		t.Errorf("the failed save should be reported once, got %v", saveErrs)
+	}
+}
+
+func TestWrapperListPlayers(t *testing.T) {
+	tests := []struct {
+		version  string
+		response []string
+	}{
+		{"1.16.5", []string{"[12:01:00] [Server thread/INFO]: There are 2 of a max of 20 players online: player1 (7d3b5c1e-2f6a-4f8e-9b0a-1c2d3e4f5a6b), player4 (0c1d2e3f-4a5b-4c6d-8e7f-9a0b1c2d3e4f)"}},
+		{"1.12.2", []string{
+			"[12:01:00] [Server thread/INFO]: There are 2/20 players online:",
+			"[12:01:00] [Server thread/INFO]: player1 (7d3b5c1e-2f6a-4f8e-9b0a-1c2d3e4f5a6b), player4 (0c1d2e3f-4a5b-4c6d-8e7f-9a0b1c2d3e4f)",
+		}},
+	}
+	for _, tt := range tests {
+		wpr, _ := newCmdTestWrapper(t, tt.version, map[string][]string{"list uuids": tt.response})
+		// player1 was seen logging in, player2 left without being noticed
+		// and player4 logged in before the wrapper started.
+		wpr.players.update("player1", func(p *Player) { p.IP = "192.168.1.77" })
+		wpr.players.update("player2", func(p *Player) {})
+
+		players, err := wpr.ListPlayers(context.Background())
+		if err != nil {
+			t.Errorf("%s: %s", tt.version, err)
+			continue
+		}
+		if len(players) != 2 || players[0].Name != "player1" || players[1].Name != "player4" {
+			t.Errorf("%s: wrong players listed: %+v", tt.version, players)
+			continue
+		}
+		if players[0].IP != "192.168.1.77" || players[1].UUID != "0c1d2e3f-4a5b-4c6d-8e7f-9a0b1c2d3e4f" {
+			t.Errorf("%s: wrong players details: %+v", tt.version, players)
+		}
+		if _, ok := wpr.players.get("player2"); ok {
+			t.Errorf("%s: player2 should be removed from the player list", tt.version)
+		}
+	}
+}
+
+func TestWrapperListPlayersEmpty(t *testing.T) {
+	wpr, _ := newCmdTestWrapper(t, "1.16.5", map[string][]string{
+		"list uuids": {"[12:01:00] [Server thread/INFO]: There are 0 of a max of 20 players online: "},
+	})
+	players, err := wpr.ListPlayers(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(players) != 0 {
+		t.Errorf("no player should be listed, got %+v", players)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	wpr.console.(*cmdTestConsole).responses = nil
+	if _, err := wpr.ListPlayers(ctx); err != ErrWrapperResponseTimeout {
+		t.Errorf("unanswered list should time out, got %v", err)
+	}
+}
+
+func TestWrapperTellraw(t *testing.T) {
+	wpr, tc := newCmdTestWrapper(t, "1.16.5", nil)
+	msg := text.Text("Restarting in 5 minutes").Color(text.Red).Bold()
+	if err := wpr.Tellraw(AllPlayers(), msg); err != nil {
+		t.Fatal(err)
+	}
+	if err := wpr.Title(PlayerName("player1"), text.Text("Welcome")); err != nil {
+		t.Fatal(err)
+	}
+	if err := wpr.Subtitle(PlayerName("player1"), text.Text("to the server")); err != nil {
+		t.Fatal(err)
+	}
+	if err := wpr.Actionbar(PlayerName("player1"), text.Text("Low health").Color(text.Red)); err != nil {
+		t.Fatal(err)
+	}
+	if err := wpr.TitleTimes(PlayerName("player1"), 500*time.Millisecond, 3*time.Second, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err := wpr.Tellraw(AllPlayers(), text.Text("x").Color("pink")); err == nil {
+		t.Error("invalid component should not be sent")
+	}
+
+	expected := []string{
+		`tellraw @a {"text":"Restarting in 5 minutes","color":"red","bold":true}`,
+		`title player1 title {"text":"Welcome"}`,
+		`title player1 subtitle {"text":"to the server"}`,
+		`title player1 actionbar {"text":"Low health","color":"red"}`,
+		`title player1 times 10 60 20`,
+	}
+	if cmds := tc.written(); !reflect.DeepEqual(cmds, expected) {
+		t.Errorf("wrong commands written:\nactual  : %v\nexpected: %v", cmds, expected)
+	}
+}
+
+func TestWrapperTellrawLegacy(t *testing.T) {
+	wpr, tc := newCmdTestWrapper(t, "1.15.2", nil)
+	msg := text.Text("Rules").OnHover(text.Text("No griefing"))
+	if err := wpr.Tellraw(AllPlayers(), msg); err != nil {
+		t.Fatal(err)
+	}
+	if err := wpr.Title(AllPlayers(), text.Text("Welcome").Color(text.Hex(255, 128, 0))); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("hex color should be unsupported prior to 1.16, got %v", err)
+	}
+
+	expected := []string{
+		`tellraw @a {"text":"Rules","hoverEvent":{"action":"show_text","value":{"text":"No griefing"}}}`,
+	}
+	if cmds := tc.written(); !reflect.DeepEqual(cmds, expected) {
+		t.Errorf("wrong commands written:\nactual  : %v\nexpected: %v", cmds, expected)
+	}
+}
+
+func TestWrapperTargets(t *testing.T) {
+	wpr, tc := newCmdTestWrapper(t, "1.16.5", nil)
+	vips := AllPlayers().Tag("vip").Distance(AtMost(20))
+	if err := wpr.SpawnPoint(vips, AbsolutePosition(100, 64, -20)); err != nil {
+		t.Fatal(err)
+	}
+	if err := wpr.SetWorldSpawn(RelativePosition(0, 0, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if err := wpr.Tellraw(AllPlayers().Limit(0), text.Text("hi")); !errors.Is(err, ErrInvalidTarget) {
+		t.Errorf("invalid selector should not be sent, got %v", err)
+	}
+	if err := wpr.Give(PlayerName("player 1"), "minecraft:diamond", 1); !errors.Is(err, ErrInvalidTarget) {
+		t.Errorf("invalid player name should not be sent, got %v", err)
+	}
+	if err := wpr.SpawnPoint(PlayerName("player1"), AbsolutePosition(0.5, 64, 0)); !errors.Is(err, ErrInvalidPosition) {
+		t.Errorf("invalid position should not be sent, got %v", err)
+	}
+	if err := wpr.Ban(PlayerName("player 1"), "Griefing"); !errors.Is(err, ErrInvalidTarget) {
+		t.Errorf("invalid ban target should not be sent, got %v", err)
+	}
+	if err := wpr.BanIP(IPAddress("203.0.113"), ""); !errors.Is(err, ErrInvalidTarget) {
+		t.Errorf("invalid IP address should not be sent, got %v", err)
+	}
+	if err := wpr.Ban(AllPlayers().Tag("griefer"), "Griefing"); err != nil {
+		t.Fatal(err)
+	}
+	if err := wpr.BanIP(IPAddress("203.0.113.7"), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"spawnpoint @a[tag=vip,distance=..20] 100 64 -20",
+		"setworldspawn ~ ~ ~",
+		"ban @a[tag=griefer] Griefing",
+		"ban-ip 203.0.113.7",
+	}
+	if cmds := tc.written(); !reflect.DeepEqual(cmds, expected) {
+		t.Errorf("wrong commands written:\nactual  : %v\nexpected: %v", cmds, expected)
+	}
+}
+
+func TestWrapperSetGameMode(t *testing.T) {
+	wpr, _ := newCmdTestWrapper(t, "1.16.5", map[string][]string{
+		"gamemode creative player1": {"[12:01:00] [Server thread/INFO]: Set player1's game mode to Creative Mode"},
+		"gamemode survival player2": {"[12:01:00] [Server thread/INFO]: No player was found"},
+	})
+	if err := wpr.SetGameMode(PlayerName("player1"), Creative); err != nil {
+		t.Error(err)
+	}
+	if err := wpr.SetGameMode(PlayerName("player2"), Survival); err != ErrPlayerNotFound {
+		t.Errorf("expected ErrPlayerNotFound, got %v", err)
+	}
+	if err := wpr.SetGameMode(PlayerName("player 3"), Survival); !errors.Is(err, ErrInvalidTarget) {
+		t.Errorf("expected ErrInvalidTarget, got %v", err)
+	}
+}
+
+func TestWrapperConcurrentCommands(t *testing.T) {
+	wpr, _ := newCmdTestWrapper(t, "1.16.5", map[string][]string{
+		"banlist players": {
+			"[12:01:00] [Server thread/INFO]: There are 2 bans:",
+			"[12:01:00] [Server thread/INFO]: griefer1 was banned by Server: Banned by an operator.",
+			"[12:01:00] [Server thread/INFO]: griefer2 was banned by admin: Griefing: spawn",
+		},
+	})
+	// Each command reads its own response, instead of discarding the
+	// entries left to read by the others.
+	errs := make(chan error, 5)
+	for i := 0; i < cap(errs); i++ {
+		go func() {
+			banned, err := wpr.BanList(BanPlayers)
+			if err == nil && len(banned) != 2 {
+				err = fmt.Errorf("wrong ban list %v", banned)
+			}
+			errs <- err
+		}()
+	}
+	for i := 0; i < cap(errs); i++ {
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestWrapperAdvancement(t *testing.T) {
+	wpr, _ := newCmdTestWrapper(t, "1.16.5", map[string][]string{
+		"advancement grant player2 only minecraft:story/mine_stone": {"[12:01:00] [Server thread/INFO]: Granted the advancement [Stone Age] to player2"},
+		"advancement revoke player1 everything":                     {"[12:01:00] [Server thread/INFO]: Revoked 12 advancements from player1"},
+		"advancement grant player1 only minecraft:story/mine_stone": {"[12:01:00] [Server thread/INFO]: Couldn't grant advancement [Stone Age] to player1 as they already have it"},
+		"advancement grant player3 only minecraft:story/mine_stone": {"[12:01:00] [Server thread/INFO]: No player was found"},
+	})
+	count, err := wpr.Advancement(AdvancementGrant, PlayerName("player2"), AdvancementOnly, "minecraft:story/mine_stone")
+	if err != nil || count != 1 {
+		t.Errorf("expected 1 advancement granted, got %d, %v", count, err)
+	}
+	count, err = wpr.Advancement(AdvancementRevoke, PlayerName("player1"), AdvancementEverything, "ignored")
+	if err != nil || count != 12 {
+		t.Errorf("expected 12 advancements revoked, got %d, %v", count, err)
+	}
+	_, err = wpr.Advancement(AdvancementGrant, PlayerName("player1"), AdvancementOnly, "minecraft:story/mine_stone")
+	if err == nil || err.Error() != "Couldn't grant advancement [Stone Age] to player1 as they already have it" {
+		t.Errorf("expected the grant error, got %v", err)
+	}
+	if _, err := wpr.Advancement(AdvancementGrant, PlayerName("player3"), AdvancementOnly, "minecraft:story/mine_stone"); err != ErrPlayerNotFound {
+		t.Errorf("expected ErrPlayerNotFound, got %v", err)
+	}
+}
+
+func TestWrapperGameRule(t *testing.T) {
+	tests := []struct {
+		version   string
+		responses map[string][]string
+	}{
+		{"1.16.5", map[string][]string{
+			"gamerule keepInventory":      {"[12:01:00] [Server thread/INFO]: Gamerule keepInventory is currently set to: false"},
+			"gamerule keepInventory true": {"[12:01:00] [Server thread/INFO]: Gamerule keepInventory is now set to: true"},
+			"gamerule randomTickSpeed":    {"[12:01:00] [Server thread/INFO]: Gamerule randomTickSpeed is currently set to: 3"},
+			"gamerule randomTickSpeed 10": {"[12:01:00] [Server thread/INFO]: Gamerule randomTickSpeed is now set to: 10"},
+			"gamerule doWardenSpawning":   {"[12:01:00] [Server thread/INFO]: Unknown or incomplete command, see below for error", "[12:01:00] [Server thread/INFO]: gamerule doWardenSpawning<--[HERE]"},
+		}},
+		{"1.12.2", map[string][]string{
+			"gamerule keepInventory":      {"[12:01:00] [Server thread/INFO]: keepInventory = false"},
+			"gamerule keepInventory true": {"[12:01:00] [Server thread/INFO]: Game rule keepInventory has been updated to true"},
+			"gamerule randomTickSpeed":    {"[12:01:00] [Server thread/INFO]: randomTickSpeed = 3"},
+			"gamerule randomTickSpeed 10": {"[12:01:00] [Server thread/INFO]: Game rule randomTickSpeed has been updated to 10"},
+			"gamerule doWardenSpawning":   {"[12:01:00] [Server thread/INFO]: No game rule called 'doWardenSpawning' is available"},
+		}},
+	}
+	for _, tt := range tests {
+		wpr, _ := newCmdTestWrapper(t, tt.version, tt.responses)
+		if v, err := wpr.GameRuleBool(KeepInventory); err != nil || v {
+			t.Errorf("%s: wrong keepInventory: %t, %v", tt.version, v, err)
+		}
+		if err := wpr.SetGameRuleBool(KeepInventory, true); err != nil {
+			t.Errorf("%s: %s", tt.version, err)
+		}
+		if v, err := wpr.GameRuleInt(RandomTickSpeed); err != nil || v != 3 {
+			t.Errorf("%s: wrong randomTickSpeed: %d, %v", tt.version, v, err)
+		}
+		if err := wpr.SetGameRuleInt(RandomTickSpeed, 10); err != nil {
+			t.Errorf("%s: %s", tt.version, err)
+		}
+		if _, err := wpr.GameRuleBool(DoWardenSpawning); err != ErrUnknownGameRule {
+			t.Errorf("%s: expected ErrUnknownGameRule, got %v", tt.version, err)
+		}
+	}
+}
+
+func TestWrapperTeleport(t *testing.T) {
+	tests := []struct {
+		version   string
+		responses map[string][]string
+	}{
+		{"1.16.5", map[string][]string{
+			"tp player1 player2":                                 {"[12:01:00] [Server thread/INFO]: Teleported player1 to player2"},
+			"tp @a[tag=vip] 10.5 64 -3.5 90 ~":                   {"[12:01:00] [Server thread/INFO]: Teleported 3 entities to 10.500000, 64.000000, -3.500000"},
+			"tp player3 player1":                                 {"[12:01:00] [Server thread/INFO]: No entity was found"},
+			"tp player1 0 30000000 0":                            {"[12:01:00] [Server thread/INFO]: Invalid position for teleport"},
+			"tp @a[name=player4] ~ ~10 ~":                        {"[12:01:00] [Server thread/INFO]: No player was found"},
+			"execute in minecraft:the_end run tp player1 0 64 0": {"[12:01:00] [Server thread/INFO]: Teleported player1 to 0.000000, 64.000000, 0.000000"},
+		}},
+		{"1.12.2", map[string][]string{
+			"tp player1 player2":               {"[12:01:00] [Server thread/INFO]: Teleported player1 to player2"},
+			"tp @a[tag=vip] 10.5 64 -3.5 90 ~": {"[12:01:00] [Server thread/INFO]: Teleported player1 to 10.5, 64.0, -3.5"},
+			"tp player3 player1":               {"[12:01:00] [Server thread/INFO]: The entity UUID provided is in an invalid format"},
+			"tp player1 0 30000000 0":          {"[12:01:00] [Server thread/INFO]: The number you have entered (30000000) is too big, it must be at most 29999984"},
+			"tp @a[name=player4] ~ ~10 ~":      {"[12:01:00] [Server thread/INFO]: Entity '@a[name=player4]' cannot be found"},
+		}},
+	}
+	for _, tt := range tests {
+		wpr, _ := newCmdTestWrapper(t, tt.version, tt.responses)
+		if err := wpr.TeleportToPlayer(PlayerName("player1"), PlayerName("player2")); err != nil {
+			t.Errorf("%s: %s", tt.version, err)
+		}
+		rot := &Rotation{Yaw: Absolute(90), Pitch: Relative(0)}
+		if err := wpr.Teleport(AllPlayers().Tag("vip"), AbsolutePosition(10.5, 64, -3.5), rot); err != nil {
+			t.Errorf("%s: %s", tt.version, err)
+		}
+		if err := wpr.TeleportToPlayer(PlayerName("player3"), PlayerName("player1")); err != ErrPlayerNotFound {
+			t.Errorf("%s: expected ErrPlayerNotFound, got %v", tt.version, err)
+		}
+		if err := wpr.Teleport(PlayerName("player1"), AbsolutePosition(0, 30000000, 0), nil); err != ErrInvalidPosition {
+			t.Errorf("%s: expected ErrInvalidPosition, got %v", tt.version, err)
+		}
+		if err := wpr.Teleport(AllPlayers().Name("player4"), RelativePosition(0, 10, 0), nil); err != ErrPlayerNotFound {
+			t.Errorf("%s: expected ErrPlayerNotFound, got %v", tt.version, err)
+		}
+		if err := wpr.Teleport(PlayerName("player1"), Position{Local(0), Absolute(64), Absolute(0)}, nil); !errors.Is(err, ErrInvalidPosition) {
+			t.Errorf("%s: mixed local position should not be sent, got %v", tt.version, err)
+		}
+		err := wpr.TeleportToDimension(PlayerName("player1"), TheEnd, AbsolutePosition(0, 64, 0), nil)
+		if tt.version == "1.12.2" && err != ErrUnsupportedVersion {
+			t.Errorf("%s: expected ErrUnsupportedVersion, got %v", tt.version, err)
+		}
+		if tt.version != "1.12.2" && err != nil {
+			t.Errorf("%s: %s", tt.version, err)
+		}
+	}
+}
+
+func TestWrapperTeleportVerified(t *testing.T) {
+	wpr, _ := newCmdTestWrapper(t, "1.16.5", map[string][]string{
+		"execute in minecraft:the_nether run tp player1 ~ ~ ~5": {"[12:01:00] [Server thread/INFO]: Teleported player1 to 10.500000, 64.000000, 1.500000"},
+		"data get entity player1":                               {`[12:01:00] [Server thread/INFO]: player1 has the following entity data: {Dimension: "minecraft:the_nether", Pos: [10.5d, 64.0d, 1.5d]}`},
+		"tp player2 0 64 0":                                     {"[12:01:00] [Server thread/INFO]: Teleported player2 to 0.000000, 64.000000, 0.000000"},
+		"data get entity player2":                               {`[12:01:00] [Server thread/INFO]: player2 has the following entity data: {Dimension: "minecraft:overworld", Pos: [120.5d, 70.0d, -8.5d]}`},
+	})
+	if err := wpr.TeleportVerified(PlayerName("player1"), TheNether, RelativePosition(0, 0, 5), nil); err != nil {
+		t.Error(err)
+	}
+	if err := wpr.TeleportVerified(PlayerName("player2"), "", AbsolutePosition(0, 64, 0), nil); !errors.Is(err, ErrTeleportNotVerified) {
+		t.Errorf("expected ErrTeleportNotVerified, got %v", err)
+	}
+	if err := wpr.TeleportVerified(PlayerName("@p"), "", AbsolutePosition(0, 64, 0), nil); !errors.Is(err, ErrInvalidTarget) {
+		t.Errorf("expected ErrInvalidTarget, got %v", err)
+	}
+
+	legacy, tc := newCmdTestWrapper(t, "1.12.2", nil)
+	if err := legacy.TeleportVerified(PlayerName("player1"), "", AbsolutePosition(0, 64, 0), nil); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("expected ErrUnsupportedVersion, got %v", err)
+	}
+	if cmds := tc.written(); len(cmds) != 0 {
+		t.Errorf("no command should be sent, got %v", cmds)
+	}
+}